@@ -13,6 +13,10 @@
 // @in header
 // @name Authorization
 // @description Enter the token with the `Bearer: ` prefix, e.g. "Bearer abcde12345".
+// @securityDefinitions.apikey ApiKey
+// @in header
+// @name X-API-Key
+// @description API key of a machine client, e.g. "egp_1a2b3c4d_...".
 
 package main
 
@@ -37,6 +41,7 @@ import (
 	"example-go-project/pkg/config"
 	"example-go-project/pkg/database"
 	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/oidc"
 	"example-go-project/pkg/utils"
 )
 
@@ -58,7 +63,7 @@ func setupMongoDB(cfg *config.Config) (*mongo.Client, error) {
 	return client, nil
 }
 
-func setupServer(cfg *config.Config) (*routers.Application, error) {
+func setupServer(ctx context.Context, cfg *config.Config) (*routers.Application, error) {
 	// Set Gin mode to release
 	gin.SetMode(gin.DebugMode)
 
@@ -80,12 +85,15 @@ func setupServer(cfg *config.Config) (*routers.Application, error) {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "X-Org-ID", "X-Request-ID", "If-Match", "X-Cart-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID", "ETag"},
 		AllowCredentials: allowCredentials,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Tag each request with an id, echoed in X-Request-ID and kept in the audit log
+	router.Use(middleware.RequestID())
+
 	// Setup MongoDB
 	mongoClient, err := setupMongoDB(cfg)
 	if err != nil {
@@ -98,36 +106,174 @@ func setupServer(cfg *config.Config) (*routers.Application, error) {
 		return nil, err
 	}
 
+	// Setup JWT signing keys, a replaced key keeps verifying for one access token lifetime
+	accessExpiry, err := time.ParseDuration(cfg.JWTExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+	keyManager, err := utils.NewKeyManager(cfg.JWTKeysDir, cfg.JWTSigningAlg, accessExpiry)
+	if err != nil {
+		return nil, err
+	}
+	go keyManager.StartRotation(ctx, cfg.JWTKeyRotation)
+	authHandler := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+
 	// Initialize repositories
 	db := mongoClient.Database(cfg.MongoDBDatabase)
 	userRepo := repository.NewUserRepository(db)
+	if err := userRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
 	productRepo := repository.NewProductRepository(db)
+	if err := productRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	categoryRepo := repository.NewCategoryRepository(db)
+	if err := categoryRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	stockMovementRepo := repository.NewStockMovementRepository(db)
+	if err := stockMovementRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	stockReservationRepo := repository.NewStockReservationRepository(db)
+	if err := stockReservationRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	orderRepo := repository.NewOrderRepository(db)
+	if err := orderRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	cartRepo := repository.NewCartRepository(db)
+	if err := cartRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
 	fileRepo := repository.NewLocalFileRepository(db, cfg)
+	auditRepo := repository.NewAuditRepository(db)
+	if err := auditRepo.EnsureIndexes(ctx, cfg.AuditRetention); err != nil {
+		return nil, err
+	}
+	roleRepo := repository.NewRoleRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	if err := apiKeyRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	invitationRepo := repository.NewInvitationRepository(db)
+	if err := invitationRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	orgRepo := repository.NewOrganizationRepository(db)
+	membershipRepo := repository.NewMembershipRepository(db)
+	if err := membershipRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	orgInvitationRepo := repository.NewOrgInvitationRepository(db)
+	if err := orgInvitationRepo.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
 
 	// Initialize services
-	fileService := service.NewFileService(fileRepo)
+	auditService := service.NewAuditService(auditRepo)
+	fileService := service.NewFileService(fileRepo, auditService)
 	httpService := service.NewHttpService()
-	productService := service.NewProductService(productRepo)
-	userService := service.NewUserService(userRepo, redisClient, cfg)
+	inventoryService := service.NewInventoryService(productRepo, stockMovementRepo, stockReservationRepo, cfg)
+	go inventoryService.StartExpiry(ctx)
+	categoryService := service.NewCategoryService(categoryRepo, productRepo, auditService)
+	productService := service.NewProductService(productRepo, inventoryService, categoryService, auditService)
+	paymentProvider, err := service.NewPaymentProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cartService := service.NewCartService(cartRepo, productRepo, redisClient, cfg)
+	orderService := service.NewOrderService(orderRepo, productRepo, inventoryService, repository.NewTransactor(db), paymentProvider, auditService)
+	tokenService := service.NewTokenService(redisClient, authHandler, auditService, cfg)
+	userService := service.NewUserService(userRepo, redisClient, tokenService, auditService, cfg)
+	loginThrottle := service.NewLoginThrottleService(redisClient, auditService, cfg)
+	twoFactorService := service.NewTwoFactorService(userRepo, redisClient, tokenService, loginThrottle, auditService, cfg)
+	mailService := service.NewMailService(cfg)
+	passwordService := service.NewPasswordService(userRepo, redisClient, tokenService, mailService, auditService, cfg)
+	verificationService := service.NewEmailVerificationService(userRepo, redisClient, mailService, cfg)
+	emailChangeService := service.NewEmailChangeService(userRepo, redisClient, tokenService, auditService, mailService, cfg)
+	permissionService := service.NewPermissionService(roleRepo, userRepo, redisClient, auditService)
+	if err := permissionService.EnsureDefaults(ctx); err != nil {
+		return nil, err
+	}
+	go permissionService.Subscribe(ctx)
+	roleService := service.NewRoleService(userRepo, permissionService, auditService)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, permissionService, auditService)
+	if err := roleService.EnsureAdmin(ctx, cfg.AdminEmail); err != nil {
+		return nil, err
+	}
+	var oidcProviders []*oidc.Provider
+	for _, provider := range cfg.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(oidc.Config(provider), nil))
+	}
+	impersonationService := service.NewImpersonationService(userRepo, tokenService, permissionService, auditService, cfg)
+	invitationService := service.NewInvitationService(invitationRepo, userRepo, userService, permissionService, mailService, auditService, cfg)
+	orgService := service.NewOrgService(orgRepo, membershipRepo, orgInvitationRepo, userRepo, productRepo, fileRepo, tokenService, mailService, auditService, cfg)
+	magicLinkService := service.NewMagicLinkService(userRepo, redisClient, tokenService, twoFactorService, mailService, cfg)
+	privacyService := service.NewPrivacyService(userRepo, productRepo, fileRepo, membershipRepo, orgRepo, apiKeyRepo, orderRepo, cartRepo, tokenService, auditService, mailService, cfg)
+	go privacyService.StartCleanup(ctx)
+	oidcService := service.NewOIDCService(oidcProviders, userRepo, redisClient, tokenService, twoFactorService, auditService, cfg)
 
 	// Initialize handlers
-	userHandler := handlers.NewUserHandler(userService)
-	productHandler := handlers.NewProductHandler(productService, userService)
+	userHandler := handlers.NewUserHandler(userService, twoFactorService, verificationService, loginThrottle)
+	productHandler := handlers.NewProductHandler(productService, categoryService, userService, permissionService)
 	pingHandler := handlers.NewPingHandler(httpService)
-	uploadHandler := handlers.NewUploadHandler(fileService, userService)
+	uploadHandler := handlers.NewUploadHandler(fileService, userService, permissionService)
+	sessionHandler := handlers.NewSessionHandler(tokenService, userService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	passwordHandler := handlers.NewPasswordHandler(passwordService)
+	verificationHandler := handlers.NewEmailVerificationHandler(verificationService)
+	roleHandler := handlers.NewRoleHandler(roleService, permissionService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, cfg)
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationService)
+	orgHandler := handlers.NewOrgHandler(orgService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
+	magicLinkHandler := handlers.NewMagicLinkHandler(magicLinkService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+	emailChangeHandler := handlers.NewEmailChangeHandler(emailChangeService)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService, productService, permissionService)
+	orderHandler := handlers.NewOrderHandler(orderService, permissionService)
+	cartHandler := handlers.NewCartHandler(cartService, productService, permissionService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	jwksHandler := handlers.NewJWKSHandler(keyManager)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(userService, cfg)
+	authMiddleware := middleware.NewAuthMiddleware(userService, tokenService, twoFactorService, permissionService, apiKeyService, impersonationService, cfg)
+	orgMiddleware := middleware.NewOrgMiddleware(orgService)
 
 	// Create application instance with all dependencies
 	application := &routers.Application{
-		Router:         router,
-		UserHandler:    userHandler,
-		ProductHandler: productHandler,
-		PingHandler:    pingHandler,
-		UploadHandler:  uploadHandler,
-		AuthMiddleware: authMiddleware,
-		Config:         cfg,
+		Router:                   router,
+		UserHandler:              userHandler,
+		ProductHandler:           productHandler,
+		PingHandler:              pingHandler,
+		UploadHandler:            uploadHandler,
+		SessionHandler:           sessionHandler,
+		TwoFactorHandler:         twoFactorHandler,
+		PasswordHandler:          passwordHandler,
+		EmailVerificationHandler: verificationHandler,
+		RoleHandler:              roleHandler,
+		APIKeyHandler:            apiKeyHandler,
+		OIDCHandler:              oidcHandler,
+		ImpersonationHandler:     impersonationHandler,
+		OrgHandler:               orgHandler,
+		InvitationHandler:        invitationHandler,
+		MagicLinkHandler:         magicLinkHandler,
+		AuditHandler:             auditHandler,
+		PrivacyHandler:           privacyHandler,
+		EmailChangeHandler:       emailChangeHandler,
+		InventoryHandler:         inventoryHandler,
+		OrderHandler:             orderHandler,
+		CartHandler:              cartHandler,
+		CategoryHandler:          categoryHandler,
+		JWKSHandler:              jwksHandler,
+		AuthMiddleware:           authMiddleware,
+		OrgMiddleware:            orgMiddleware,
+		Config:                   cfg,
 	}
 
 	// Setup routes
@@ -140,8 +286,12 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
 	// Setup server with all dependencies
-	application, err := setupServer(cfg)
+	application, err := setupServer(jobsCtx, cfg)
 	if err != nil {
 		log.Fatal("Failed to setup server:", err)
 	}
@@ -166,6 +316,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Give the server 5 seconds to finish current requests
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,8 @@
+package dto
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,min=3,max=50"`
+	Type          string   `json:"type" binding:"omitempty,oneof=personal service"`
+	Scopes        []string `json:"scopes" binding:"omitempty"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
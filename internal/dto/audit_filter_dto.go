@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type AuditFilter struct {
+	ActorID  string     `form:"actor_id"`
+	Action   string     `form:"action"`
+	TargetID string     `form:"target_id"`
+	From     *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
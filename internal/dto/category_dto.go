@@ -0,0 +1,13 @@
+package dto
+
+// CategoryRequest creates or replaces a category, it sits at the top of the
+// tree without a parent.
+type CategoryRequest struct {
+	Name     string `json:"name" binding:"required,min=1,max=50"`
+	ParentID string `json:"parent_id"`
+}
+
+// Products of the deleted category move to ReassignTo
+type DeleteCategoryQuery struct {
+	ReassignTo string `form:"reassign_to"`
+}
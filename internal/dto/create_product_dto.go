@@ -3,5 +3,18 @@ package dto
 type CreateProductRequest struct {
 	Name  string  `json:"name" binding:"required,min=3,max=30"`
 	Price float64 `json:"price" binding:"required"`
-	Stock int     `json:"stock" binding:"required"`
+	Stock *int    `json:"stock" binding:"required,gte=0"`
+	// CategoryID is optional, tags are stored lower case without repeats
+	CategoryID string   `json:"category_id"`
+	Tags       []string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=30"`
+}
+
+// UpdateProductRequest replaces the editable fields of a product, a JSON Merge
+// Patch is applied to it before validation. Stock only changes through stock
+// movements. Leaving out the category or tags removes them.
+type UpdateProductRequest struct {
+	Name       string   `json:"name" binding:"required,min=3,max=30"`
+	Price      *float64 `json:"price" binding:"required,gte=0"`
+	CategoryID *string  `json:"category_id"`
+	Tags       []string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=30"`
 }
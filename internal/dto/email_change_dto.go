@@ -0,0 +1,11 @@
+package dto
+
+// Accounts without a password, created through OIDC, leave it empty
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
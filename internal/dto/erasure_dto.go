@@ -0,0 +1,6 @@
+package dto
+
+// Accounts without a password, created through OIDC, send an empty object
+type RequestErasureRequest struct {
+	Password string `json:"password"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+type InviteUserRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,max=30"`
+}
+
+type AcceptInvitationRequest struct {
+	Token           string `json:"token" binding:"required"`
+	Name            string `json:"name" binding:"required,min=3,max=30"`
+	Password        string `json:"password" binding:"required,min=6,password_validator"`
+	ConfirmPassword string `json:"confirm_password" binding:"required,eqfield=Password"`
+}
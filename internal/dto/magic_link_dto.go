@@ -0,0 +1,11 @@
+package dto
+
+type MagicLinkRequest struct {
+	Email      string `json:"email" binding:"required,email"`
+	DeviceName string `json:"device_name" binding:"omitempty,max=100"`
+}
+
+type VerifyMagicLinkRequest struct {
+	Token     string `json:"token" binding:"required"`
+	RequestID string `json:"request_id" binding:"required"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+// OIDCCallbackRequest is the query the provider redirects back with. On a
+// failed or cancelled login it sends error instead of code.
+type OIDCCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+type OrderItemRequest struct {
+	ProductID string `json:"product_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,gte=1,lte=1000"`
+}
+
+type CreateOrderRequest struct {
+	Items []OrderItemRequest `json:"items" binding:"required,min=1,max=50,dive"`
+}
+
+// Source is what the payment provider charges, the fake provider declines
+// "decline" and accepts anything else
+type PayOrderRequest struct {
+	Source string `json:"source" binding:"required"`
+}
+
+// Orders become paid through payment only
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=shipped cancelled refunded"`
+}
+
+type OrderFilter struct {
+	Status string `form:"status"`
+	// UserID filters by customer for holders of order:manage
+	UserID string `form:"user_id"`
+}
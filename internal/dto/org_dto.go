@@ -0,0 +1,24 @@
+package dto
+
+type OrgRequest struct {
+	Name string `json:"name" binding:"required,min=2,max=100"`
+}
+
+type OrgMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+type OrgInvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+type AcceptOrgInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type SwitchOrgRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	// OrgID is the organization to switch to, empty for the personal space
+	OrgID string `json:"org_id" binding:"omitempty"`
+}
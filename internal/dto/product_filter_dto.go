@@ -5,4 +5,8 @@ type ProductFilter struct {
 	Price  *float64 `form:"price"`
 	Stock  *int     `form:"stock"`
 	UserId string   `form:"user_id"`
+	// Category matches its subcategories too, products match when they
+	// have every tag
+	Category string   `form:"category"`
+	Tags     []string `form:"tags"`
 }
@@ -0,0 +1,16 @@
+package dto
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required,max=30"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,min=2,max=30"`
+	Description string   `json:"description" binding:"omitempty,max=200"`
+	Permissions []string `json:"permissions" binding:"omitempty"`
+}
+
+type UpdateRoleRequest struct {
+	Description string   `json:"description" binding:"omitempty,max=200"`
+	Permissions []string `json:"permissions" binding:"omitempty"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// Quantity is positive for receipts, sales and returns, and signed for
+// adjustments
+type StockMovementRequest struct {
+	Type     string `json:"type" binding:"required,oneof=receipt sale adjustment return"`
+	Quantity int    `json:"quantity" binding:"required"`
+	Note     string `json:"note" binding:"max=200"`
+}
+
+type StockReservationRequest struct {
+	Quantity int `json:"quantity" binding:"required,gte=1"`
+}
+
+type StockHistoryFilter struct {
+	From *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To   *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	DeviceName     string `json:"device_name" binding:"omitempty,max=100"`
+}
+
+type TwoFactorPolicyRequest struct {
+	Roles []string `json:"roles" binding:"omitempty"`
+}
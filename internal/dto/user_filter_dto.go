@@ -4,4 +4,6 @@ type UserFilter struct {
 	Name  string   `form:"name"`
 	Email string   `form:"email"`
 	Role  []string `form:"role[]"`
+	// Deleted lists soft-deleted users instead of active ones
+	Deleted bool `form:"deleted"`
 }
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// APIKeyOwner resolves the creator of the key in the :id path parameter for
+// AuthMiddleware.OwnerOrPermission
+func (a *APIKeyHandler) APIKeyOwner(c *gin.Context) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	key, err := a.apiKeyService.FindByID(ctx, objID)
+	if errors.Is(err, service.ErrAPIKeyNotFound) {
+		return primitive.NilObjectID, mongo.ErrNoDocuments
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return key.UserID, nil
+}
+
+// @Summary Create API key endpoint
+// @Description Post a new API key, the key is only shown in this response. Personal keys act as the user narrowed by their scopes, service keys have exactly their scopes while their creator still holds them and apikey:manage. Keys with scopes only reach routes that need one of their scopes, like product:list for reading products; orders, organizations, the cart and stock reservations are closed to them
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.CreateAPIKeyRequest true "API key details"
+// @Router /user/api-keys [post]
+func (a *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	key, plain, err := a.apiKeyService.Create(ctx, principal, &req)
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotAllowed),
+		errors.Is(err, service.ErrServiceKeyForbidden),
+		errors.Is(err, service.ErrScopeNotHeld):
+		utils.SendError(c, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, service.ErrServiceKeyScopes),
+		errors.Is(err, service.ErrUnknownPermission):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     plain,
+	}, "API key created, store it now as it cannot be shown again")
+}
+
+// @Summary List API keys endpoint
+// @Description Get the API keys created by the current user
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /user/api-keys [get]
+func (a *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	keys, err := a.apiKeyService.List(ctx, user.ID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, keys)
+}
+
+// @Summary List all API keys endpoint
+// @Description Get the API keys of every user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /api-keys [get]
+func (a *APIKeyHandler) GetAllAPIKeys(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	keys, err := a.apiKeyService.List(ctx, primitive.NilObjectID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, keys)
+}
+
+// @Summary Revoke API key endpoint
+// @Description Delete an API key, only its creator or a user with apikey:manage may revoke it
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "API key ID"
+// @Router /user/api-keys/{id} [delete]
+func (a *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	err = a.apiKeyService.Revoke(ctx, user, objID)
+	if errors.Is(err, service.ErrAPIKeyNotFound) {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "API key revoked successfully")
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// @Summary Audit log endpoint
+// @Description Get the audit log, newest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number (default: 1)" default(1)
+// @Param pageSize query int false "Page size (default: 10)" default(10)
+// @Param actor_id query string false "Filter by actor ID"
+// @Param action query string false "Filter by action, e.g. user.deleted"
+// @Param target_id query string false "Filter by target ID"
+// @Param from query string false "Events at or after this RFC 3339 time"
+// @Param to query string false "Events before this RFC 3339 time"
+// @Router /audit [get]
+func (a *AuditHandler) GetEvents(c *gin.Context) {
+	page, pageSize := utils.PaginationParams(c)
+
+	var filter dto.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid filter parameters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	events, total, err := a.auditService.FindAll(ctx, filter, page, pageSize)
+	if errors.Is(err, service.ErrInvalidAuditFilter) {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, events)
+	utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// @Summary Audit log export endpoint
+// @Description Get the matching audit events as newline delimited JSON, oldest first
+// @Tags admin
+// @Produce application/x-ndjson
+// @Security Bearer
+// @Param actor_id query string false "Filter by actor ID"
+// @Param action query string false "Filter by action, e.g. user.deleted"
+// @Param target_id query string false "Filter by target ID"
+// @Param from query string false "Events at or after this RFC 3339 time"
+// @Param to query string false "Events before this RFC 3339 time"
+// @Router /audit/export [get]
+func (a *AuditHandler) Export(c *gin.Context) {
+	var filter dto.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid filter parameters")
+		return
+	}
+
+	// An export streams for as long as the client reads it
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	err := a.auditService.Export(ctx, filter, func(event *model.AuditEvent) error {
+		if written == 0 {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.ndjson"`, time.Now().Format("20060102-150405")))
+			c.Status(http.StatusOK)
+		}
+		written++
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		if written%100 == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+
+	switch {
+	case written > 0:
+		// The status is sent, a failure can only cut the stream short
+		if err != nil {
+			log.Printf("audit: export stopped after %d events: %v", written, err)
+		}
+	case errors.Is(err, service.ErrInvalidAuditFilter):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case err != nil:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	default:
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+}
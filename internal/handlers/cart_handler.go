@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Header carrying the ID of an anonymous cart
+const cartIDHeader = "X-Cart-ID"
+
+type CartHandler struct {
+	cartService       *service.CartService
+	productService    *service.ProductService
+	permissionService *service.PermissionService
+}
+
+func NewCartHandler(cartService *service.CartService, productService *service.ProductService, permissionService *service.PermissionService) *CartHandler {
+	return &CartHandler{
+		cartService:       cartService,
+		productService:    productService,
+		permissionService: permissionService,
+	}
+}
+
+// cartRef names the cart of the request. Signed in users who still send the
+// ID of the anonymous cart they filled before signing in get it merged into
+// their own cart first.
+func (h *CartHandler) cartRef(ctx context.Context, c *gin.Context) (service.CartRef, bool) {
+	cartID := c.GetHeader(cartIDHeader)
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		return service.CartRef{CartID: cartID}, true
+	}
+
+	if cartID != "" {
+		if err := h.cartService.Merge(ctx, principal.User.ID, cartID); err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			return service.CartRef{}, false
+		}
+	}
+	return service.CartRef{UserID: principal.User.ID}, true
+}
+
+// @Summary Get cart endpoint
+// @Description Get the cart priced at the current product prices. price_changed flags lines whose price changed since they were added, out_of_stock lines with less free stock than their quantity and unavailable lines whose product is gone, total leaves the last two out. Anonymous carts are named by X-Cart-ID, signed in users sending one get it merged into their cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param X-Cart-ID header string false "Anonymous cart ID"
+// @Param X-Org-ID header string false "Organization ID"
+// @Router /cart [get]
+func (h *CartHandler) GetCart(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	ref, ok := h.cartRef(ctx, c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.Get(ctx, ref)
+	if err != nil {
+		sendCartError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, cart)
+}
+
+// @Summary Add cart item endpoint
+// @Description Post a product of the organization in X-Org-ID, or a personal product without one, to the cart. Only products the caller may see in GET /product/{id} are added, 404 otherwise and 401 for anonymous requests, which see no products. Anonymous requests without X-Cart-ID start a new cart, its cart_id is sent as X-Cart-ID with later requests. 409 when not enough stock is free
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param X-Cart-ID header string false "Anonymous cart ID"
+// @Param X-Org-ID header string false "Organization ID"
+// @Param request body dto.AddCartItemRequest true "Product and quantity"
+// @Router /cart/items [post]
+func (h *CartHandler) AddItem(c *gin.Context) {
+	var req dto.AddCartItemRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	productID, err := primitive.ObjectIDFromHex(req.ProductID)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, ok := visibleProduct(ctx, c, h.productService, h.permissionService, productID); !ok {
+		return
+	}
+
+	ref, ok := h.cartRef(ctx, c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.AddItem(ctx, ref, productID, req.Quantity)
+	if err != nil {
+		sendCartError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, cart, "Item added to cart")
+}
+
+// @Summary Update cart item endpoint
+// @Description Put the quantity of a product in the cart, the line takes the current price
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param X-Cart-ID header string false "Anonymous cart ID"
+// @Param productId path string true "Product ID"
+// @Param request body dto.UpdateCartItemRequest true "Quantity"
+// @Router /cart/items/{productId} [put]
+func (h *CartHandler) UpdateItem(c *gin.Context) {
+	var req dto.UpdateCartItemRequest
+
+	productID, err := primitive.ObjectIDFromHex(c.Param("productId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	ref, ok := h.cartRef(ctx, c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.UpdateItem(ctx, ref, productID, req.Quantity)
+	if err != nil {
+		sendCartError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, cart, "Cart item updated")
+}
+
+// @Summary Remove cart item endpoint
+// @Description Delete a product from the cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param X-Cart-ID header string false "Anonymous cart ID"
+// @Param productId path string true "Product ID"
+// @Router /cart/items/{productId} [delete]
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	productID, err := primitive.ObjectIDFromHex(c.Param("productId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	ref, ok := h.cartRef(ctx, c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(ctx, ref, productID)
+	if err != nil {
+		sendCartError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, cart, "Item removed from cart")
+}
+
+func sendCartError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.SendError(c, http.StatusNotFound, "Product not found")
+	case errors.Is(err, service.ErrCartItemNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCartFull):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrInsufficientStock):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+	}
+}
+
+// optionalObjectID parses an optional ID, nil when it is empty.
+func optionalObjectID(id string) (*primitive.ObjectID, error) {
+	if id == "" {
+		return nil, nil
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return &objID, nil
+}
+
+// @Summary Get categories endpoint
+// @Description Get every category of the organization in X-Org-ID, or the personal categories without one. Each category lists the IDs of its ancestors from the top of the tree down
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Router /categories [get]
+func (h *CategoryHandler) GetCategories(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	categories, err := h.categoryService.FindAll(ctx)
+	if err != nil {
+		sendCategoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, categories)
+}
+
+// @Summary Get category endpoint
+// @Description Get a category of the organization in X-Org-ID, or a personal category without one
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Category ID"
+// @Router /categories/{id} [get]
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	category, err := h.categoryService.FindByID(ctx, id)
+	if err != nil {
+		sendCategoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, category)
+}
+
+// @Summary Create category endpoint
+// @Description Post a category under parent_id, at the top of the tree without one. Names are unique among siblings, 409 otherwise
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param request body dto.CategoryRequest true "Category details"
+// @Router /categories [post]
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req dto.CategoryRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	parentID, err := optionalObjectID(req.ParentID)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid parent ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	category, err := h.categoryService.Create(ctx, req.Name, parentID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		utils.SendError(c, http.StatusBadRequest, "Parent category not found")
+		return
+	}
+	if err != nil {
+		sendCategoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, category, "Category created successfully")
+}
+
+// @Summary Update category endpoint
+// @Description Put the name and parent of a category, moving it carries its subcategories along. 400 when the parent is the category or one of its subcategories
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Category ID"
+// @Param request body dto.CategoryRequest true "Category details"
+// @Router /categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	var req dto.CategoryRequest
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	parentID, err := optionalObjectID(req.ParentID)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid parent ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	category, err := h.categoryService.Update(ctx, id, req.Name, parentID)
+	if err != nil {
+		sendCategoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, category, "Category updated successfully")
+}
+
+// @Summary Delete category endpoint
+// @Description Delete a category without subcategories. A category with products is only deleted with reassign_to, the category its products move to, 409 otherwise
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Category ID"
+// @Param reassign_to query string false "Category ID the products move to"
+// @Router /categories/{id} [delete]
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var query dto.DeleteCategoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	reassignTo, err := optionalObjectID(query.ReassignTo)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid category ID in reassign_to")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.categoryService.Delete(ctx, id, reassignTo); err != nil {
+		sendCategoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Category deleted successfully")
+}
+
+func sendCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.SendError(c, http.StatusNotFound, "Category not found")
+	case errors.Is(err, service.ErrInvalidCategory):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrCategoryExists),
+		errors.Is(err, service.ErrCategoryHasChildren),
+		errors.Is(err, service.ErrCategoryInUse):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailChangeHandler struct {
+	emailChangeService *service.EmailChangeService
+}
+
+func NewEmailChangeHandler(emailChangeService *service.EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChangeService: emailChangeService,
+	}
+}
+
+// @Summary Change email endpoint
+// @Description Put a new email for the account. A confirmation link goes to the new address and a notice to the current one, the email changes once the link is opened. Accounts with a password must confirm it
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.ChangeEmailRequest true "New email and current password"
+// @Router /user/email [put]
+func (e *EmailChangeHandler) Request(c *gin.Context) {
+	var req dto.ChangeEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := e.emailChangeService.Request(ctx, user, req.Email, req.Password); err != nil {
+		sendEmailChangeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusAccepted, nil, "Confirmation link sent to the new email")
+}
+
+// @Summary Confirm email change endpoint
+// @Description Post the token from the confirmation link to move the account to the new email. Every session of the account is signed out
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ConfirmEmailChangeRequest true "Confirmation token"
+// @Router /auth/email-change/confirm [post]
+func (e *EmailChangeHandler) Confirm(c *gin.Context) {
+	var req dto.ConfirmEmailChangeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := e.emailChangeService.Confirm(ctx, req.Token); err != nil {
+		sendEmailChangeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Email changed successfully, sign in again with the new email")
+}
+
+func sendEmailChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		utils.SendError(c, http.StatusUnauthorized, "Invalid password")
+	case errors.Is(err, service.ErrEmailUnchanged), errors.Is(err, service.ErrInvalidEmailChange):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrEmailExists):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
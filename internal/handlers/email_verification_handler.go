@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailVerificationHandler struct {
+	verificationService *service.EmailVerificationService
+}
+
+func NewEmailVerificationHandler(verificationService *service.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		verificationService: verificationService,
+	}
+}
+
+// @Summary Verify email endpoint
+// @Description Post the token from the verification link to verify the account's email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "Verification token"
+// @Router /auth/verify-email [post]
+func (e *EmailVerificationHandler) Verify(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	err := e.verificationService.Verify(ctx, req.Token)
+	if errors.Is(err, service.ErrInvalidVerificationToken) {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Email verified successfully")
+}
+
+// @Summary Resend verification email endpoint
+// @Description Post the API's resend verification, mails a new link and invalidates the previous one
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /auth/verify-email/resend [post]
+func (e *EmailVerificationHandler) Resend(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	err := e.verificationService.SendVerification(ctx, user)
+	if errors.Is(err, service.ErrEmailAlreadyVerified) {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Verification email sent")
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ImpersonationHandler struct {
+	impersonationService *service.ImpersonationService
+}
+
+func NewImpersonationHandler(impersonationService *service.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+	}
+}
+
+// @Summary Impersonate user endpoint
+// @Description Post the API's impersonate, returns a short lived access token acting as the user. The token may only read, other methods get 403, and every request made with it is audited
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Router /user/{id}/impersonate [post]
+func (i *ImpersonationHandler) Impersonate(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var amr []string
+	if claims, ok := middleware.GetClaimsFromContext(c); ok {
+		amr = claims.AMR
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	token, err := i.impersonationService.Start(ctx, actor, objectID, amr, c.ClientIP())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		utils.SendError(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if errors.Is(err, service.ErrImpersonateSelf) {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrImpersonateNotCovered) {
+		utils.SendError(c, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, token, "Impersonation started")
+}
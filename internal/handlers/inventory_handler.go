@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InventoryHandler struct {
+	inventoryService  *service.InventoryService
+	productService    *service.ProductService
+	permissionService *service.PermissionService
+}
+
+func NewInventoryHandler(inventoryService *service.InventoryService, productService *service.ProductService, permissionService *service.PermissionService) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryService:  inventoryService,
+		productService:    productService,
+		permissionService: permissionService,
+	}
+}
+
+// @Summary Stock movement endpoint
+// @Description Post a receipt, sale, adjustment or return of a product's stock. Stock never goes below the part held by reservations, 409 otherwise
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Product ID"
+// @Param request body dto.StockMovementRequest true "Stock movement"
+// @Router /product/{id}/stock/movements [post]
+func (i *InventoryHandler) RecordMovement(c *gin.Context) {
+	var req dto.StockMovementRequest
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	movement, err := i.inventoryService.Record(ctx, id, req.Type, req.Quantity, req.Note, nil)
+	if err != nil {
+		sendInventoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, movement, "Stock movement recorded")
+}
+
+// @Summary Stock history endpoint
+// @Description Get the stock movements of a product, oldest first. stock_after of each movement is the stock level from then on
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Product ID"
+// @Param page query int false "Page number (default: 1)" default(1)
+// @Param pageSize query int false "Page size (default: 10)" default(10)
+// @Param from query string false "Movements at or after this RFC 3339 time"
+// @Param to query string false "Movements before this RFC 3339 time"
+// @Router /product/{id}/stock/history [get]
+func (i *InventoryHandler) GetHistory(c *gin.Context) {
+	page, pageSize := utils.PaginationParams(c)
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var filter dto.StockHistoryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid filter parameters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	product, ok := visibleProduct(ctx, c, i.productService, i.permissionService, id)
+	if !ok {
+		return
+	}
+
+	movements, total, err := i.inventoryService.History(ctx, product.ID, filter.From, filter.To, page, pageSize)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, movements)
+	utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// @Summary Reserve stock endpoint
+// @Description Post to hold stock of a product for STOCK_RESERVATION_TTL, after which it is released automatically. 409 when not enough stock is free, 429 when the caller's active reservations of the product would hold more than STOCK_RESERVATION_MAX_QUANTITY
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Product ID"
+// @Param request body dto.StockReservationRequest true "Quantity to reserve"
+// @Router /product/{id}/stock/reservations [post]
+func (i *InventoryHandler) Reserve(c *gin.Context) {
+	var req dto.StockReservationRequest
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	product, ok := visibleProduct(ctx, c, i.productService, i.permissionService, id)
+	if !ok {
+		return
+	}
+	principal, _ := middleware.GetPrincipalFromContext(c)
+
+	reservation, err := i.inventoryService.Reserve(ctx, product, principal.User.ID, req.Quantity)
+	if err != nil {
+		sendInventoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, reservation, "Stock reserved")
+}
+
+// @Summary Release reservation endpoint
+// @Description Delete a reservation before it expires and free its stock. Only the user who reserved and holders of product:update may release it
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Product ID"
+// @Param reservationId path string true "Reservation ID"
+// @Router /product/{id}/stock/reservations/{reservationId} [delete]
+func (i *InventoryHandler) Release(c *gin.Context) {
+	productID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+	reservationID, err := primitive.ObjectIDFromHex(c.Param("reservationId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	product, ok := visibleProduct(ctx, c, i.productService, i.permissionService, productID)
+	if !ok {
+		return
+	}
+	principal, _ := middleware.GetPrincipalFromContext(c)
+
+	reservation, err := i.inventoryService.FindReservation(ctx, product.ID, reservationID)
+	if err != nil {
+		sendInventoryError(c, err)
+		return
+	}
+
+	allowed, err := i.permissionService.IsOwnerOrPermitted(ctx, principal, reservation.UserID, utils.PermProductUpdate)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !allowed {
+		utils.SendError(c, http.StatusForbidden, "Insufficient permissions")
+		return
+	}
+
+	if err := i.inventoryService.Release(ctx, reservation.ID); err != nil {
+		sendInventoryError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Reservation released")
+}
+
+func sendInventoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInsufficientStock):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidStockMovement):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrReservationLimit):
+		utils.SendError(c, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, service.ErrReservationNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	default:
+		sendProductError(c, err)
+	}
+}
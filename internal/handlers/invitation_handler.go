@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InvitationHandler struct {
+	invitationService *service.InvitationService
+}
+
+func NewInvitationHandler(invitationService *service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+	}
+}
+
+// @Summary Invite user endpoint
+// @Description Post an invitation mailed to the address, accepting it creates an account with the role. The role may not grant permissions the inviter lacks
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.InviteUserRequest true "Invitation details"
+// @Router /invitations [post]
+func (i *InvitationHandler) Invite(c *gin.Context) {
+	var req dto.InviteUserRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	invitation, err := i.invitationService.Invite(ctx, actor, req.Email, req.Role, c.ClientIP())
+	if err != nil {
+		sendInvitationError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, invitation, "Invitation sent successfully")
+}
+
+// @Summary Get invitations endpoint
+// @Description Get the invitations that can still be accepted
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /invitations [get]
+func (i *InvitationHandler) GetInvitations(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	invitations, err := i.invitationService.Pending(ctx)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, invitations)
+}
+
+// @Summary Revoke invitation endpoint
+// @Description Delete a pending invitation, its link stops working
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Invitation ID"
+// @Router /invitations/{id} [delete]
+func (i *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := i.invitationService.Revoke(ctx, actor, objectID, c.ClientIP()); err != nil {
+		sendInvitationError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Invitation revoked successfully")
+}
+
+// @Summary Accept invitation endpoint
+// @Description Post the token of an invitation link with a name and password to create the invited account. The email and role come from the invitation
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.AcceptInvitationRequest true "Invitation token and account details"
+// @Router /auth/invitations/accept [post]
+func (i *InvitationHandler) Accept(c *gin.Context) {
+	var req dto.AcceptInvitationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := i.invitationService.Accept(ctx, &req, c.ClientIP())
+	if err != nil {
+		sendInvitationError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, user, "Account created successfully")
+}
+
+func sendInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInviteNotFound),
+		errors.Is(err, service.ErrRoleNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInviteRoleNotHeld):
+		utils.SendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrEmailExists):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidInvite):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"example-go-project/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keys *utils.KeyManager
+}
+
+func NewJWKSHandler(keys *utils.KeyManager) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// JWKS publishes the public signing keys so other services can verify our
+// access tokens without sharing a secret. It is served outside /api/v1 at the
+// well-known path, so it is not part of the swagger spec.
+func (j *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, j.keys.JWKS())
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *service.MagicLinkService
+}
+
+func NewMagicLinkHandler(magicLinkService *service.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+	}
+}
+
+// @Summary Magic link request endpoint
+// @Description Post an email to receive a one-time login link. The answer is the same whether the account exists or not and carries the request_id this device must send with the link. Too many requests for an email are answered with 429 and Retry-After
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MagicLinkRequest true "Account email"
+// @Router /auth/magic-link [post]
+func (m *MagicLinkHandler) Request(c *gin.Context) {
+	var req dto.MagicLinkRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	requestID, wait, err := m.magicLinkService.Request(ctx, req.Email, clientInfo(c, req.DeviceName))
+	if errors.Is(err, service.ErrMagicLinkThrottled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		utils.SendError(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to send login link")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"request_id": requestID}, "If the account exists, a login link has been sent")
+}
+
+// @Summary Magic link verify endpoint
+// @Description Post the token of a login link with the request_id from /auth/magic-link to get the token pair. Links work once, from the IP that asked for them. Users with two-factor authentication get a challenge token for /auth/login/2fa instead
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyMagicLinkRequest true "Link token and request id"
+// @Router /auth/magic-link/verify [post]
+func (m *MagicLinkHandler) Verify(c *gin.Context) {
+	var req dto.VerifyMagicLinkRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	login, err := m.magicLinkService.Verify(ctx, req.Token, req.RequestID, clientInfo(c, ""))
+	if errors.Is(err, service.ErrInvalidMagicLink) {
+		utils.SendError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if login.Challenge != nil {
+		utils.SendSuccess(c, http.StatusOK, login.Challenge, "Two-factor authentication required")
+		return
+	}
+	utils.SendSuccess(c, http.StatusOK, login.TokenPair, "Login successful")
+}
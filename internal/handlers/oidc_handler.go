@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/oidc"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The state is also kept in a cookie, so a callback only completes in the
+// browser that started the login
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+	config      *config.Config
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, config *config.Config) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		config:      config,
+	}
+}
+
+// @Summary OIDC login start endpoint
+// @Description Redirects to the identity provider to sign in with authorization code and PKCE
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name from OIDC_PROVIDERS"
+// @Param device_name query string false "Name of the device for the session list"
+// @Router /auth/oidc/{provider}/start [get]
+func (o *OIDCHandler) Start(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	provider := c.Param("provider")
+	state, authURL, err := o.oidcService.Start(ctx, provider, clientInfo(c, c.Query("device_name")).DeviceName)
+	if errors.Is(err, service.ErrUnknownProvider) {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, 600, "/api/v1/auth/oidc/"+provider, "", o.config.ServerState == "production", true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// @Summary OIDC login callback endpoint
+// @Description The identity provider redirects here, returns the token pair or a two-factor challenge
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name from OIDC_PROVIDERS"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the start endpoint"
+// @Router /auth/oidc/{provider}/callback [get]
+func (o *OIDCHandler) Callback(c *gin.Context) {
+	var req dto.OIDCCallbackRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	provider := c.Param("provider")
+	c.SetCookie(oidcStateCookie, "", -1, "/api/v1/auth/oidc/"+provider, "", o.config.ServerState == "production", true)
+
+	if req.Error != "" {
+		message := req.Error
+		if req.ErrorDescription != "" {
+			message += ": " + req.ErrorDescription
+		}
+		utils.SendError(c, http.StatusUnauthorized, message)
+		return
+	}
+	if req.Code == "" {
+		utils.SendError(c, http.StatusBadRequest, "Missing authorization code")
+		return
+	}
+
+	if cookie, err := c.Cookie(oidcStateCookie); err != nil || cookie != req.State {
+		utils.SendError(c, http.StatusBadRequest, service.ErrInvalidOIDCState.Error())
+		return
+	}
+
+	// The code exchange and key lookup are calls to the provider
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	login, err := o.oidcService.Callback(ctx, provider, req.State, req.Code, clientInfo(c, ""))
+	switch {
+	case errors.Is(err, service.ErrUnknownProvider):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, service.ErrInvalidOIDCState):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, service.ErrRegistrationClosed):
+		utils.SendError(c, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, oidc.ErrInvalidIDToken), errors.Is(err, oidc.ErrExchangeFailed), errors.Is(err, service.ErrOIDCEmailNotVerified):
+		utils.SendError(c, http.StatusUnauthorized, err.Error())
+		return
+	case err != nil:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if login.Challenge != nil {
+		utils.SendSuccess(c, http.StatusOK, login.Challenge, "Two-factor authentication required")
+		return
+	}
+	utils.SendSuccess(c, http.StatusOK, login.TokenPair, "Login successful")
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OrderHandler struct {
+	orderService      *service.OrderService
+	permissionService *service.PermissionService
+}
+
+func NewOrderHandler(orderService *service.OrderService, permissionService *service.PermissionService) *OrderHandler {
+	return &OrderHandler{
+		orderService:      orderService,
+		permissionService: permissionService,
+	}
+}
+
+// visibleOrder loads the order of the id path parameter. Orders of other
+// users need order:manage and are reported as not found without it.
+func (o *OrderHandler) visibleOrder(ctx context.Context, c *gin.Context) (*model.Order, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return nil, false
+	}
+
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return nil, false
+	}
+
+	order, err := o.orderService.FindByID(ctx, id)
+	if err != nil {
+		sendOrderError(c, err)
+		return nil, false
+	}
+
+	allowed, err := o.permissionService.IsOwnerOrPermitted(ctx, principal, order.UserID, utils.PermOrderManage)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if !allowed {
+		sendOrderError(c, mongo.ErrNoDocuments)
+		return nil, false
+	}
+	return order, true
+}
+
+// @Summary Create order endpoint
+// @Description Post the products to order. Their stock is sold and the user's reservations of them are used up in one transaction, 409 when a product has not enough stock. Names and prices are kept on the order as they were at checkout
+// @Tags order
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param request body dto.CreateOrderRequest true "Order items"
+// @Router /orders [post]
+func (o *OrderHandler) CreateOrder(c *gin.Context) {
+	var req dto.CreateOrderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	order, err := o.orderService.Create(ctx, principal.User.ID, req.Items)
+	if err != nil {
+		sendOrderError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, order, "Order created")
+}
+
+// @Summary List orders endpoint
+// @Description Get the user's orders, newest first. Holders of order:manage see every order and may filter by customer
+// @Tags order
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param page query int false "Page number (default: 1)" default(1)
+// @Param pageSize query int false "Page size (default: 10)" default(10)
+// @Param status query string false "Filter by status"
+// @Param user_id query string false "Filter by customer"
+// @Router /orders [get]
+func (o *OrderHandler) GetOrders(c *gin.Context) {
+	page, pageSize := utils.PaginationParams(c)
+
+	var filter dto.OrderFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid filter parameters")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	canManage, err := o.permissionService.Allowed(ctx, principal, utils.PermOrderManage)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	mongoFilter := bson.D{}
+	if !canManage {
+		mongoFilter = append(mongoFilter, bson.E{Key: "user_id", Value: principal.User.ID})
+	} else if filter.UserID != "" {
+		userID, err := primitive.ObjectIDFromHex(filter.UserID)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		mongoFilter = append(mongoFilter, bson.E{Key: "user_id", Value: userID})
+	}
+
+	if filter.Status != "" {
+		mongoFilter = append(mongoFilter, bson.E{Key: "status", Value: filter.Status})
+	}
+
+	orders, total, err := o.orderService.FindAll(ctx, mongoFilter, page, pageSize)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, orders)
+	utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// @Summary Get order endpoint
+// @Description Get an order with its status history
+// @Tags order
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path string true "Order ID"
+// @Router /orders/{id} [get]
+func (o *OrderHandler) GetOrder(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	order, ok := o.visibleOrder(ctx, c)
+	if !ok {
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, order)
+}
+
+// @Summary Pay order endpoint
+// @Description Post a payment source to pay a pending order. 402 when the payment provider declines it
+// @Tags order
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path string true "Order ID"
+// @Param request body dto.PayOrderRequest true "Payment source"
+// @Router /orders/{id}/pay [post]
+func (o *OrderHandler) PayOrder(c *gin.Context) {
+	var req dto.PayOrderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	order, ok := o.visibleOrder(ctx, c)
+	if !ok {
+		return
+	}
+
+	// Staff manage orders, they do not pay for them
+	principal, _ := middleware.GetPrincipalFromContext(c)
+	if order.UserID != principal.User.ID {
+		utils.SendError(c, http.StatusForbidden, "Only the customer can pay an order")
+		return
+	}
+
+	order, err := o.orderService.Pay(ctx, order, req.Source)
+	if err != nil {
+		sendOrderError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, order, "Order paid")
+}
+
+// @Summary Cancel order endpoint
+// @Description Post to cancel a pending order and put its stock back
+// @Tags order
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path string true "Order ID"
+// @Router /orders/{id}/cancel [post]
+func (o *OrderHandler) CancelOrder(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	order, ok := o.visibleOrder(ctx, c)
+	if !ok {
+		return
+	}
+
+	order, err := o.orderService.Transition(ctx, order, model.OrderCancelled)
+	if err != nil {
+		sendOrderError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, order, "Order cancelled")
+}
+
+// @Summary Update order status endpoint
+// @Description Put the next status of an order: paid orders ship, pending ones cancel and paid or shipped ones refund through the payment provider. An order left refunding by an interrupted refund is finished by refunding it again. Stock of orders cancelled or refunded before shipping goes back
+// @Tags order
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param id path string true "Order ID"
+// @Param request body dto.UpdateOrderStatusRequest true "New status"
+// @Router /orders/{id}/status [put]
+func (o *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	var req dto.UpdateOrderStatusRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	order, ok := o.visibleOrder(ctx, c)
+	if !ok {
+		return
+	}
+
+	order, err := o.orderService.Transition(ctx, order, req.Status)
+	if err != nil {
+		sendOrderError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, order, "Order status updated")
+}
+
+func sendOrderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.SendError(c, http.StatusNotFound, "Order not found")
+	case errors.Is(err, service.ErrInvalidOrder):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrPaymentDeclined):
+		utils.SendError(c, http.StatusPaymentRequired, err.Error())
+	case errors.Is(err, service.ErrInsufficientStock),
+		errors.Is(err, service.ErrInvalidOrderTransition),
+		errors.Is(err, service.ErrOrderChanged):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,517 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OrgHandler struct {
+	orgService *service.OrgService
+}
+
+func NewOrgHandler(orgService *service.OrgService) *OrgHandler {
+	return &OrgHandler{
+		orgService: orgService,
+	}
+}
+
+// @Summary Create organization endpoint
+// @Description Post a new organization, the caller becomes its owner
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.OrgRequest true "Organization details"
+// @Router /orgs [post]
+func (o *OrgHandler) CreateOrg(c *gin.Context) {
+	var req dto.OrgRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	org, err := o.orgService.Create(ctx, user, req.Name)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, org, "Organization created successfully")
+}
+
+// @Summary Get organizations endpoint
+// @Description Get the organizations the caller belongs to, with the caller's role in each
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /orgs [get]
+func (o *OrgHandler) GetOrgs(c *gin.Context) {
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	orgs, err := o.orgService.ListForUser(ctx, user)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, orgs)
+}
+
+// @Summary Get organization endpoint
+// @Description Get an organization the caller belongs to
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Router /orgs/{orgId} [get]
+func (o *OrgHandler) GetOrg(c *gin.Context) {
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	org, err := o.orgService.Get(ctx, membership.OrgID)
+	if err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, service.UserOrganization{Organization: org, Role: membership.Role})
+}
+
+// @Summary Update organization endpoint
+// @Description Put the name of an organization, for its owners and admins
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Param request body dto.OrgRequest true "Organization details"
+// @Router /orgs/{orgId} [put]
+func (o *OrgHandler) UpdateOrg(c *gin.Context) {
+	var req dto.OrgRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	org, err := o.orgService.Update(ctx, membership.OrgID, req.Name)
+	if err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, org, "Organization updated successfully")
+}
+
+// @Summary Delete organization endpoint
+// @Description Delete an organization with its members and invitations, for its owners. Its products and files must be deleted first
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Router /orgs/{orgId} [delete]
+func (o *OrgHandler) DeleteOrg(c *gin.Context) {
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := o.orgService.Delete(ctx, user, membership.OrgID); err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Organization deleted successfully")
+}
+
+// @Summary Get organization members endpoint
+// @Description Get the members of an organization with their roles
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Router /orgs/{orgId}/members [get]
+func (o *OrgHandler) GetMembers(c *gin.Context) {
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	members, err := o.orgService.Members(ctx, membership.OrgID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, members)
+}
+
+// @Summary Update organization member endpoint
+// @Description Put the role of a member. Admins manage admins and members, only owners manage owners
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Param userId path string true "User ID"
+// @Param request body dto.OrgMemberRequest true "Member role"
+// @Router /orgs/{orgId}/members/{userId} [put]
+func (o *OrgHandler) UpdateMember(c *gin.Context) {
+	var req dto.OrgMemberRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	member, err := o.orgService.UpdateMember(ctx, membership, userID, req.Role)
+	if err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, member, "Member updated successfully")
+}
+
+// @Summary Remove organization member endpoint
+// @Description Delete a member from an organization. Members may remove themselves to leave, the last owner cannot leave
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Param userId path string true "User ID"
+// @Router /orgs/{orgId}/members/{userId} [delete]
+func (o *OrgHandler) RemoveMember(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := o.orgService.RemoveMember(ctx, membership, userID); err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Member removed successfully")
+}
+
+// @Summary Invite organization member endpoint
+// @Description Post an invitation mailed to the address, accepting it adds the account with that email as a member
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Param request body dto.OrgInvitationRequest true "Invitation details"
+// @Router /orgs/{orgId}/invitations [post]
+func (o *OrgHandler) Invite(c *gin.Context) {
+	var req dto.OrgInvitationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	invitation, err := o.orgService.Invite(ctx, membership, user, req.Email, req.Role)
+	if err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, invitation, "Invitation sent successfully")
+}
+
+// @Summary Get organization invitations endpoint
+// @Description Get the pending invitations of an organization
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Router /orgs/{orgId}/invitations [get]
+func (o *OrgHandler) GetInvitations(c *gin.Context) {
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	invitations, err := o.orgService.Invitations(ctx, membership.OrgID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, invitations)
+}
+
+// @Summary Revoke organization invitation endpoint
+// @Description Delete a pending invitation, its link stops working
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param orgId path string true "Organization ID"
+// @Param id path string true "Invitation ID"
+// @Router /orgs/{orgId}/invitations/{id} [delete]
+func (o *OrgHandler) RevokeInvitation(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	membership, ok := middleware.GetMembershipFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := o.orgService.RevokeInvitation(ctx, membership.OrgID, id); err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Invitation revoked successfully")
+}
+
+// @Summary Accept organization invitation endpoint
+// @Description Post the token of an invitation mailed to the caller's email address to join the organization
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.AcceptOrgInvitationRequest true "Invitation token"
+// @Router /orgs/invitations/accept [post]
+func (o *OrgHandler) AcceptInvitation(c *gin.Context) {
+	var req dto.AcceptOrgInvitationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	membership, err := o.orgService.AcceptInvitation(ctx, user, req.Token)
+	if err != nil {
+		sendOrgError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, membership, "Invitation accepted successfully")
+}
+
+// @Summary Switch organization endpoint
+// @Description Post a refresh token to get a token pair whose org claim selects the organization, an empty org_id returns to the personal space. The refresh token is used up like on a refresh
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.SwitchOrgRequest true "Refresh token and organization"
+// @Router /auth/switch-org [post]
+func (o *OrgHandler) SwitchOrg(c *gin.Context) {
+	var req dto.SwitchOrgRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	tokenPair, err := o.orgService.SwitchOrg(ctx, req.RefreshToken, req.OrgID, clientInfo(c, ""))
+	if errors.Is(err, service.ErrNotOrgMember) {
+		utils.SendError(c, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, tokenPair, "Organization switched successfully")
+}
+
+func sendOrgError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.SendError(c, http.StatusNotFound, "Not found")
+	case errors.Is(err, service.ErrNotOrgMember),
+		errors.Is(err, service.ErrOrgAdminRequired),
+		errors.Is(err, service.ErrOrgOwnerRequired),
+		errors.Is(err, service.ErrInvitationEmailMismatch):
+		utils.SendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrLastOrgOwner),
+		errors.Is(err, service.ErrOrgNotEmpty),
+		errors.Is(err, service.ErrAlreadyOrgMember):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidOrgRole),
+		errors.Is(err, service.ErrInvalidInvitation):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -43,7 +43,7 @@ func (p *PingHandler) Ping(c *gin.Context) {
 		return
 	}
 
-	_, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	err := p.httpService.Get(c, req.Url)
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PrivacyHandler struct {
+	privacyService *service.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService *service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+	}
+}
+
+// @Summary Data export endpoint
+// @Description Get a ZIP archive of everything stored about the user: profile, organizations, products, orders, files with their metadata and audit history
+// @Tags user
+// @Produce application/zip
+// @Security Bearer
+// @Router /user/me/export [get]
+func (p *PrivacyHandler) Export(c *gin.Context) {
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	// The archive is built first, so a failure can still be answered with an error
+	archive, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+
+	if err := p.privacyService.Export(ctx, user, archive); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to export data")
+		return
+	}
+
+	c.FileAttachment(archive.Name(), fmt.Sprintf("export-%s.zip", user.ID.Hex()))
+}
+
+// @Summary Request erasure endpoint
+// @Description Post to erase the account and its data after ERASURE_GRACE_PERIOD. Products and files in organizations go to another member, personal ones are deleted. Accounts with a password must confirm it, the last admin gets 409
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.RequestErasureRequest true "Current password"
+// @Router /user/me/erasure [post]
+func (p *PrivacyHandler) RequestErasure(c *gin.Context) {
+	var req dto.RequestErasureRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	updated, err := p.privacyService.RequestErasure(ctx, user, req.Password)
+	if err != nil {
+		sendPrivacyError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusAccepted, gin.H{"erasure_scheduled_at": updated.ErasureScheduledAt}, "Account erasure scheduled")
+}
+
+// @Summary Cancel erasure endpoint
+// @Description Delete a scheduled erasure during its grace period
+// @Tags user
+// @Produce json
+// @Security Bearer
+// @Router /user/me/erasure [delete]
+func (p *PrivacyHandler) CancelErasure(c *gin.Context) {
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := p.privacyService.CancelErasure(ctx, user); err != nil {
+		sendPrivacyError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Account erasure cancelled")
+}
+
+// @Summary Purge user endpoint
+// @Description Delete the API's soft-deleted user for good, with their personal content. Their content in organizations is handed over to another member. The last admin gets 409
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Router /user/{id}/purge [delete]
+func (p *PrivacyHandler) PurgeUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	// Removing the user's files and org content takes longer than a lookup
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := p.privacyService.Purge(ctx, id); err != nil {
+		sendPrivacyError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "User purged successfully")
+}
+
+func sendPrivacyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		utils.SendError(c, http.StatusUnauthorized, "Invalid password")
+	case errors.Is(err, service.ErrErasureScheduled):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrErasureNotScheduled):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrUserNotDeleted),
+		errors.Is(err, service.ErrLastAdminErasure):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
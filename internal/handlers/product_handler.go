@@ -1,44 +1,149 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
 	"example-go-project/internal/service"
 	"example-go-project/pkg/middleware"
 	"example-go-project/pkg/utils"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ProductHandler struct {
-	productService *service.ProductService
-	userService    *service.UserService
+	productService    *service.ProductService
+	categoryService   *service.CategoryService
+	userService       *service.UserService
+	permissionService *service.PermissionService
 }
 
-func NewProductHandler(productService *service.ProductService, userService *service.UserService) *ProductHandler {
+func NewProductHandler(productService *service.ProductService, categoryService *service.CategoryService, userService *service.UserService, permissionService *service.PermissionService) *ProductHandler {
 	return &ProductHandler{
-		productService: productService,
-		userService:    userService,
+		productService:    productService,
+		categoryService:   categoryService,
+		userService:       userService,
+		permissionService: permissionService,
+	}
+}
+
+// ProductOwner resolves the creator of the product in the :id path parameter
+// for AuthMiddleware.OwnerOrPermission
+func (p *ProductHandler) ProductOwner(c *gin.Context) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	product, err := p.productService.FindByID(ctx, objID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return product.UserID, nil
+}
+
+// productETag is the strong ETag of the product's version
+func productETag(product *model.Product) string {
+	return fmt.Sprintf(`"%d"`, product.Version)
+}
+
+// ifMatchVersions reads the product versions listed in If-Match, nil when the
+// header is absent or "*". Weak and malformed tags never match.
+func ifMatchVersions(c *gin.Context) []int64 {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// visibleProduct finds a product the caller may see, as in the product list:
+// members see every product of the active organization, and in the personal
+// space users without product:list only their own. It sends the error
+// response itself and reports false on failure.
+func visibleProduct(ctx context.Context, c *gin.Context, productService *service.ProductService, permissionService *service.PermissionService, id primitive.ObjectID) (*model.Product, bool) {
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return nil, false
+	}
+
+	product, err := productService.FindByID(ctx, id)
+	if err != nil {
+		sendProductError(c, err)
+		return nil, false
+	}
+
+	if _, inOrg := middleware.GetMembershipFromContext(c); !inOrg {
+		allowed, err := permissionService.IsOwnerOrPermitted(ctx, principal, product.UserID, utils.PermProductList)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			return nil, false
+		}
+		if !allowed {
+			utils.SendError(c, http.StatusNotFound, "Product not found")
+			return nil, false
+		}
+	}
+	return product, true
+}
+
+func sendProductError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.SendError(c, http.StatusNotFound, "Product not found")
+	case errors.Is(err, service.ErrProductVersionMismatch):
+		utils.SendError(c, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, service.ErrUnknownCategory):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
 	}
 }
 
 // @Summary Create product endpoint
-// @Description Post the API's create product
+// @Description Post the API's create product, optionally in a category of the same organization or personal space and with tags
 // @Tags product
 // @Accept json
 // @Produce json
 // @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID, the product is created in the personal space without one"
 // @Param request body dto.CreateProductRequest true "Product details"
 // @Router /product [post]
 func (p *ProductHandler) CreateProduct(c *gin.Context) {
 	var req dto.CreateProductRequest
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	user, ok := middleware.GetUserFromContext(c)
@@ -60,26 +165,235 @@ func (p *ProductHandler) CreateProduct(c *gin.Context) {
 	}
 	res, err := p.productService.CreateProduct(ctx, &req, user.ID)
 
+	if errors.Is(err, service.ErrUnknownCategory) {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		utils.SendError(c, http.StatusInternalServerError, "Failed to create product")
 		return
 	}
 
+	c.Header("ETag", productETag(res))
 	utils.SendSuccess(c, http.StatusCreated, res, "Product created successfully")
 }
 
+// @Summary Get product endpoint
+// @Description Get a product of the organization in X-Org-ID or the token, or a personal product without one. In the personal space users without product:list only get their own products. The ETag header carries the product version for If-Match
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param id path string true "Product ID"
+// @Router /product/{id} [get]
+func (p *ProductHandler) GetProduct(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	product, ok := visibleProduct(ctx, c, p.productService, p.permissionService, id)
+	if !ok {
+		return
+	}
+
+	c.Header("ETag", productETag(product))
+	utils.SendSuccess(c, http.StatusOK, product)
+}
+
+// @Summary Update product endpoint
+// @Description Put the name, price, category and tags of a product, leaving out the category or tags removes them. Stock changes through stock movements. With If-Match the update only happens while the product still has that ETag, 412 otherwise
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param If-Match header string false "ETag of the product version being replaced"
+// @Param id path string true "Product ID"
+// @Param request body dto.UpdateProductRequest true "Product details"
+// @Router /product/{id} [put]
+func (p *ProductHandler) UpdateProduct(c *gin.Context) {
+	var req dto.UpdateProductRequest
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	product, err := p.productService.Update(ctx, id, &req, ifMatchVersions(c))
+	if err != nil {
+		sendProductError(c, err)
+		return
+	}
+
+	c.Header("ETag", productETag(product))
+	utils.SendSuccess(c, http.StatusOK, product, "Product updated successfully")
+}
+
+// @Summary Patch product endpoint
+// @Description Patch a product with a JSON Merge Patch (RFC 7386) of its name, price, category_id and tags. With If-Match the patch only applies while the product still has that ETag, 412 otherwise
+// @Tags product
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param If-Match header string false "ETag of the product version being patched"
+// @Param id path string true "Product ID"
+// @Param request body object true "Merge patch"
+// @Router /product/{id} [patch]
+func (p *ProductHandler) PatchProduct(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if contentType := c.ContentType(); contentType != "application/merge-patch+json" && contentType != "application/json" {
+		utils.SendError(c, http.StatusUnsupportedMediaType, "Send a JSON Merge Patch as application/merge-patch+json")
+		return
+	}
+	patch, err := c.GetRawData()
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	product, err := p.productService.FindByID(ctx, id)
+	if err != nil {
+		sendProductError(c, err)
+		return
+	}
+	if !matchesVersion(ifMatchVersions(c), product.Version) {
+		sendProductError(c, service.ErrProductVersionMismatch)
+		return
+	}
+
+	base := dto.UpdateProductRequest{Name: product.Name, Price: &product.Price, Tags: product.Tags}
+	if product.CategoryID != nil {
+		categoryID := product.CategoryID.Hex()
+		base.CategoryID = &categoryID
+	}
+	current, err := json.Marshal(base)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	patched, err := utils.MergePatch(current, patch)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid merge patch")
+		return
+	}
+
+	var req dto.UpdateProductRequest
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Only name, price, category_id and tags can be patched, stock changes through stock movements")
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": utils.FormatValidationError(err),
+		})
+		return
+	}
+
+	// The patch was computed from this version, a concurrent change fails it
+	updated, err := p.productService.Update(ctx, id, &req, []int64{product.Version})
+	if err != nil {
+		sendProductError(c, err)
+		return
+	}
+
+	c.Header("ETag", productETag(updated))
+	utils.SendSuccess(c, http.StatusOK, updated, "Product updated successfully")
+}
+
+// matchesVersion reports whether If-Match allows the version, nil allows any.
+func matchesVersion(versions []int64, version int64) bool {
+	if versions == nil {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// @Summary Delete product endpoint
+// @Description Delete a product. With If-Match the product is only deleted while it still has that ETag, 412 otherwise
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
+// @Param If-Match header string false "ETag of the product version being deleted"
+// @Param id path string true "Product ID"
+// @Router /product/{id} [delete]
+func (p *ProductHandler) DeleteProduct(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := p.productService.Delete(ctx, id, ifMatchVersions(c)); err != nil {
+		sendProductError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Product deleted successfully")
+}
+
 // @Summary Get products endpoint
-// @Description Get the API's get products
+// @Description Get the API's get products of the organization in X-Org-ID or the token, or the personal products without one. In the personal space users without product:list only get their own products
 // @Tags product
 // @Accept json
 // @Produce json
 // @Security Bearer
+// @Security ApiKey
+// @Param X-Org-ID header string false "Organization ID"
 // @Param page query int false "Page number (default: 1)" default(1)
 // @Param pageSize query int false "Page size (default: 10)" default(10)
 // @Param name query string false "Filter by product name"
 // @Param price query float64 false "Filter by product price"
 // Param stock query int false "Filter by product stock"
 // @Param user_id query string false "Filter by product user ID"
+// @Param category query string false "Filter by category ID, including its subcategories"
+// @Param tags query []string false "Filter by tags, products must have all of them" collectionFormat(multi)
 // @Router /product [get]
 func (p *ProductHandler) GetProducts(c *gin.Context) {
 	page, pageSize := utils.PaginationParams(c)
@@ -90,9 +404,21 @@ func (p *ProductHandler) GetProducts(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	canListAll, err := p.permissionService.Allowed(ctx, principal, utils.PermProductList)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	mongoFilter := bson.D{}
 	if filter.Name != "" {
 		mongoFilter = append(mongoFilter, bson.E{
@@ -124,10 +450,50 @@ func (p *ProductHandler) GetProducts(c *gin.Context) {
 		})
 	}
 
-	if filter.UserId != "" {
+	if filter.Category != "" {
+		categoryID, err := primitive.ObjectIDFromHex(filter.Category)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid category ID")
+			return
+		}
+		subtree, err := p.categoryService.Subtree(ctx, categoryID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			utils.SendError(c, http.StatusBadRequest, service.ErrUnknownCategory.Error())
+			return
+		}
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		mongoFilter = append(mongoFilter, bson.E{
+			Key:   "category_id",
+			Value: bson.D{{Key: "$in", Value: subtree}},
+		})
+	}
+
+	if tags := service.NormalizeTags(filter.Tags); len(tags) > 0 {
+		mongoFilter = append(mongoFilter, bson.E{
+			Key:   "tags",
+			Value: bson.D{{Key: "$all", Value: tags}},
+		})
+	}
+
+	// Members see every product of the active organization
+	_, inOrg := middleware.GetMembershipFromContext(c)
+	if !canListAll && !inOrg {
+		mongoFilter = append(mongoFilter, bson.E{
+			Key:   "user_id",
+			Value: principal.User.ID,
+		})
+	} else if filter.UserId != "" {
+		userID, err := primitive.ObjectIDFromHex(filter.UserId)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
 		mongoFilter = append(mongoFilter, bson.E{
 			Key:   "user_id",
-			Value: filter.UserId,
+			Value: userID,
 		})
 	}
 
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RoleHandler struct {
+	roleService       *service.RoleService
+	permissionService *service.PermissionService
+}
+
+func NewRoleHandler(roleService *service.RoleService, permissionService *service.PermissionService) *RoleHandler {
+	return &RoleHandler{
+		roleService:       roleService,
+		permissionService: permissionService,
+	}
+}
+
+type roleChange func(ctx context.Context, actor *model.User, userID primitive.ObjectID, role string, ip string) (*model.User, error)
+
+// @Summary Grant role endpoint
+// @Description Post a role to grant it to a user, it applies to the user's existing sessions immediately
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Param request body dto.RoleRequest true "Role"
+// @Router /user/{id}/roles [post]
+func (r *RoleHandler) GrantRole(c *gin.Context) {
+	r.changeRole(c, r.roleService.GrantRole, "Role granted successfully")
+}
+
+// @Summary Revoke role endpoint
+// @Description Delete a role from a user, the last admin cannot be demoted
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Param request body dto.RoleRequest true "Role"
+// @Router /user/{id}/roles [delete]
+func (r *RoleHandler) RevokeRole(c *gin.Context) {
+	r.changeRole(c, r.roleService.RevokeRole, "Role revoked successfully")
+}
+
+func (r *RoleHandler) changeRole(c *gin.Context, change roleChange, message string) {
+	var req dto.RoleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := change(ctx, actor, objectID, req.Role, c.ClientIP())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		utils.SendError(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if errors.Is(err, service.ErrRoleNotFound) {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrLastAdmin) {
+		utils.SendError(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{
+		"id":    user.ID.Hex(),
+		"roles": user.Roles,
+	}, message)
+}
+
+// @Summary Role list endpoint
+// @Description Get the role definitions and the permissions they grant
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /roles [get]
+func (r *RoleHandler) ListRoles(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	roles, err := r.permissionService.ListRoles(ctx)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{
+		"roles":       roles,
+		"permissions": utils.AllPermissions,
+	})
+}
+
+// @Summary Create role endpoint
+// @Description Post a new role definition
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.CreateRoleRequest true "Role definition"
+// @Router /roles [post]
+func (r *RoleHandler) CreateRole(c *gin.Context) {
+	var req dto.CreateRoleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	role, err := r.permissionService.CreateRole(ctx, actor, req.Name, req.Description, req.Permissions)
+	if err != nil {
+		sendRoleError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, role, "Role created successfully")
+}
+
+// @Summary Update role endpoint
+// @Description Put the description and permissions of a role, every instance picks up the change at once
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param name path string true "Role name"
+// @Param request body dto.UpdateRoleRequest true "Role definition"
+// @Router /roles/{name} [put]
+func (r *RoleHandler) UpdateRole(c *gin.Context) {
+	var req dto.UpdateRoleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	role, err := r.permissionService.UpdateRole(ctx, actor, c.Param("name"), req.Description, req.Permissions)
+	if err != nil {
+		sendRoleError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, role, "Role updated successfully")
+}
+
+// @Summary Delete role endpoint
+// @Description Delete a role definition that no user holds
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param name path string true "Role name"
+// @Router /roles/{name} [delete]
+func (r *RoleHandler) DeleteRole(c *gin.Context) {
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := r.permissionService.DeleteRole(ctx, actor, c.Param("name")); err != nil {
+		sendRoleError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Role deleted successfully")
+}
+
+func sendRoleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrRoleExists),
+		errors.Is(err, service.ErrRoleInUse):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrRoleBuiltIn),
+		errors.Is(err, service.ErrAdminRoleFixed),
+		errors.Is(err, service.ErrUnknownPermission):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
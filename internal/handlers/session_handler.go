@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionHandler struct {
+	tokenService *service.TokenService
+	userService  *service.UserService
+}
+
+func NewSessionHandler(tokenService *service.TokenService, userService *service.UserService) *SessionHandler {
+	return &SessionHandler{
+		tokenService: tokenService,
+		userService:  userService,
+	}
+}
+
+// @Summary List sessions endpoint
+// @Description Get the sessions of the current user, one per logged in device
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /user/sessions [get]
+func (s *SessionHandler) GetSessions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	sessions, err := s.tokenService.ListSessions(ctx, user.ID.Hex())
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if claims, ok := middleware.GetClaimsFromContext(c); ok {
+		for _, session := range sessions {
+			session.Current = session.ID == claims.FamilyID
+		}
+	}
+
+	utils.SendSuccess(c, http.StatusOK, sessions)
+}
+
+// @Summary Revoke session endpoint
+// @Description Delete one session of the current user
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Session ID"
+// @Router /user/sessions/{id} [delete]
+func (s *SessionHandler) RevokeSession(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	err := s.tokenService.RevokeSession(ctx, user.ID.Hex(), c.Param("id"))
+	if errors.Is(err, service.ErrSessionNotFound) {
+		utils.SendError(c, http.StatusNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Session revoked successfully")
+}
+
+// @Summary Revoke all sessions endpoint
+// @Description Delete every session of the current user, logging out everywhere
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /user/sessions [delete]
+func (s *SessionHandler) RevokeAllSessions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if err := s.tokenService.RevokeAllSessions(ctx, user.ID.Hex()); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Logged out from all sessions")
+}
+
+// @Summary List user sessions endpoint
+// @Description Get the sessions of any user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Router /user/{id}/sessions [get]
+func (s *SessionHandler) GetUserSessions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := s.findUserID(ctx, c)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.tokenService.ListSessions(ctx, userID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, sessions)
+}
+
+// @Summary Revoke user session endpoint
+// @Description Delete one session of any user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Param sessionId path string true "Session ID"
+// @Router /user/{id}/sessions/{sessionId} [delete]
+func (s *SessionHandler) RevokeUserSession(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := s.findUserID(ctx, c)
+	if !ok {
+		return
+	}
+
+	err := s.tokenService.RevokeSession(ctx, userID, c.Param("sessionId"))
+	if errors.Is(err, service.ErrSessionNotFound) {
+		utils.SendError(c, http.StatusNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "Session revoked successfully")
+}
+
+// @Summary Revoke all user sessions endpoint
+// @Description Delete every session of any user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Router /user/{id}/sessions [delete]
+func (s *SessionHandler) RevokeAllUserSessions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := s.findUserID(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := s.tokenService.RevokeAllSessions(ctx, userID); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "User logged out from all sessions")
+}
+
+// findUserID resolves the :id path parameter to an existing user.
+func (s *SessionHandler) findUserID(ctx context.Context, c *gin.Context) (string, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+		return "", false
+	}
+
+	user, err := s.userService.FindByID(ctx, objID.Hex())
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "User not found")
+		return "", false
+	}
+
+	return user.ID.Hex(), true
+}
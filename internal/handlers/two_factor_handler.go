@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// @Summary Two-factor login endpoint
+// @Description Post the challenge token from /auth/login and a TOTP or recovery code to get the token pair. Wrong codes count as failed logins of the account, 429 once it is throttled or locked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TwoFactorLoginRequest true "Login challenge and code"
+// @Router /auth/login/2fa [post]
+func (t *TwoFactorHandler) Login(c *gin.Context) {
+	var req dto.TwoFactorLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	tokenPair, err := t.twoFactorService.CompleteChallenge(ctx, req.ChallengeToken, req.Code, clientInfo(c, req.DeviceName))
+	if errors.Is(err, service.ErrInvalidChallenge) || errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		utils.SendError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrAccountLocked) || errors.Is(err, service.ErrLoginThrottled) {
+		utils.SendError(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, tokenPair, "Login successful")
+}
+
+// @Summary Two-factor setup endpoint
+// @Description Post the API's two-factor setup, returns a new TOTP secret and its otpauth URI
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /user/2fa/setup [post]
+func (t *TwoFactorHandler) Setup(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	setup, err := t.twoFactorService.Setup(ctx, user)
+	if errors.Is(err, service.ErrTwoFactorAlreadyEnabled) {
+		utils.SendError(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, setup)
+}
+
+// @Summary Two-factor enable endpoint
+// @Description Post a code of the new secret to enable two-factor authentication, returns the recovery codes
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.TwoFactorCodeRequest true "TOTP code"
+// @Router /user/2fa/enable [post]
+func (t *TwoFactorHandler) Enable(c *gin.Context) {
+	t.withCode(c, func(ctx context.Context, code string) {
+		user, _ := middleware.GetUserFromContext(c)
+		codes, err := t.twoFactorService.Enable(ctx, user, code)
+		if err != nil {
+			sendTwoFactorError(c, err)
+			return
+		}
+
+		utils.SendSuccess(c, http.StatusOK, gin.H{"recovery_codes": codes}, "Two-factor authentication enabled")
+	})
+}
+
+// @Summary Two-factor disable endpoint
+// @Description Post a TOTP or recovery code to disable two-factor authentication
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Router /user/2fa/disable [post]
+func (t *TwoFactorHandler) Disable(c *gin.Context) {
+	t.withCode(c, func(ctx context.Context, code string) {
+		user, _ := middleware.GetUserFromContext(c)
+		if err := t.twoFactorService.Disable(ctx, user, code); err != nil {
+			sendTwoFactorError(c, err)
+			return
+		}
+
+		utils.SendSuccess(c, http.StatusOK, nil, "Two-factor authentication disabled")
+	})
+}
+
+// @Summary Recovery codes endpoint
+// @Description Post a TOTP or recovery code to replace all recovery codes
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Router /user/2fa/recovery-codes [post]
+func (t *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	t.withCode(c, func(ctx context.Context, code string) {
+		user, _ := middleware.GetUserFromContext(c)
+		codes, err := t.twoFactorService.RegenerateRecoveryCodes(ctx, user, code)
+		if err != nil {
+			sendTwoFactorError(c, err)
+			return
+		}
+
+		utils.SendSuccess(c, http.StatusOK, gin.H{"recovery_codes": codes}, "Recovery codes regenerated")
+	})
+}
+
+// @Summary Two-factor policy endpoint
+// @Description Get the roles that must use two-factor authentication
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /user/2fa/policy [get]
+func (t *TwoFactorHandler) GetPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	roles, err := t.twoFactorService.RequiredRoles(ctx)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"roles": roles})
+}
+
+// @Summary Update two-factor policy endpoint
+// @Description Put the roles that must use two-factor authentication
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body dto.TwoFactorPolicyRequest true "Roles requiring two-factor authentication"
+// @Router /user/2fa/policy [put]
+func (t *TwoFactorHandler) UpdatePolicy(c *gin.Context) {
+	var req dto.TwoFactorPolicyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := t.twoFactorService.SetRequiredRoles(ctx, req.Roles); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"roles": req.Roles}, "Two-factor policy updated")
+}
+
+// withCode binds a TwoFactorCodeRequest for the current user and runs next.
+func (t *TwoFactorHandler) withCode(c *gin.Context, next func(ctx context.Context, code string)) {
+	var req dto.TwoFactorCodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors := utils.FormatValidationError(err)
+		if len(errors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": errors,
+			})
+			return
+		}
+
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if _, ok := middleware.GetUserFromContext(c); !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	next(ctx, req.Code)
+}
+
+func sendTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		utils.SendError(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrTwoFactorEnforced):
+		utils.SendError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, service.ErrTwoFactorNotEnabled),
+		errors.Is(err, service.ErrTwoFactorSetupExpired):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
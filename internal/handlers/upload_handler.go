@@ -14,27 +14,49 @@ import (
 )
 
 type UploadHandler struct {
-	fileService *service.FileService
-	userService *service.UserService
+	fileService       *service.FileService
+	userService       *service.UserService
+	permissionService *service.PermissionService
 }
 
-func NewUploadHandler(fileService *service.FileService, userService *service.UserService) *UploadHandler {
+func NewUploadHandler(fileService *service.FileService, userService *service.UserService, permissionService *service.PermissionService) *UploadHandler {
 	return &UploadHandler{
-		fileService: fileService,
-		userService: userService,
+		fileService:       fileService,
+		userService:       userService,
+		permissionService: permissionService,
 	}
 }
 
+// FileOwner resolves the uploader of the file in the :id path parameter for
+// AuthMiddleware.OwnerOrPermission
+func (u *UploadHandler) FileOwner(c *gin.Context) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	file, err := u.fileService.FindById(ctx, objID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return file.UserID, nil
+}
+
 // @Summary     Upload multiple files
 // @Description Upload multiple files to the server
 // @Tags        uploads
 // @Accept      multipart/form-data
 // @Produce     json
 // @Security    Bearer
+// @Security    ApiKey
+// @Param       X-Org-ID header string false "Organization ID"
 // @Param       files formData []file true "Multiple files to upload"
 // @Router      /local_upload [post]
 func (u *UploadHandler) UploadMultipleLocalFiles(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	user, ok := middleware.GetUserFromContext(c)
@@ -67,16 +89,18 @@ func (u *UploadHandler) UploadMultipleLocalFiles(c *gin.Context) {
 }
 
 // @Summary     Delete a file
-// @Description Delete a file from the server
+// @Description Delete a file from the server, only the uploader or a user with file:delete may delete it
 // @Tags        uploads
 // @Accept      json
 // @Produce     json
 // @Security    Bearer
+// @Security    ApiKey
+// @Param       X-Org-ID header string false "Organization ID"
 // @Param       id path string true "File ID"
 // @Router      /local_upload/{id} [delete]
 func (u *UploadHandler) DeleteFile(c *gin.Context) {
 	id := c.Param("id")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	ObjID, err := primitive.ObjectIDFromHex(id)
@@ -101,17 +125,37 @@ func (u *UploadHandler) DeleteFile(c *gin.Context) {
 }
 
 // @Summary     Get all files
-// @Description Get all files from the server
+// @Description Get all files of the organization in X-Org-ID or the token, or the personal files without one. In the personal space users without file:list only get their own uploads
 // @Tags        uploads
 // @Accept      json
 // @Produce     json
 // @Security    Bearer
+// @Security    ApiKey
+// @Param       X-Org-ID header string false "Organization ID"
 // @Router      /local_upload [get]
 func (u *UploadHandler) GetFileAll(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	files, err := u.fileService.FindAll(ctx, bson.D{}, nil)
+	principal, ok := middleware.GetPrincipalFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	canListAll, err := u.permissionService.Allowed(ctx, principal, utils.PermFileList)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Members see every file of the active organization
+	query := bson.D{}
+	if _, inOrg := middleware.GetMembershipFromContext(c); !canListAll && !inOrg {
+		query = append(query, bson.E{Key: "user_id", Value: principal.User.ID})
+	}
+
+	files, err := u.fileService.FindAll(ctx, query, nil)
 	if err != nil {
 		utils.SendError(c, http.StatusInternalServerError, err.Error())
 		return
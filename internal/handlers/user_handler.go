@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"example-go-project/internal/service"
 	"example-go-project/pkg/middleware"
 	"example-go-project/pkg/utils"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,17 +22,23 @@ import (
 )
 
 type UserHandler struct {
-	userService *service.UserService
+	userService         *service.UserService
+	twoFactorService    *service.TwoFactorService
+	verificationService *service.EmailVerificationService
+	loginThrottle       *service.LoginThrottleService
 }
 
-func NewUserHandler(userService *service.UserService) *UserHandler {
+func NewUserHandler(userService *service.UserService, twoFactorService *service.TwoFactorService, verificationService *service.EmailVerificationService, loginThrottle *service.LoginThrottleService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:         userService,
+		twoFactorService:    twoFactorService,
+		verificationService: verificationService,
+		loginThrottle:       loginThrottle,
 	}
 }
 
 // @Summary Login endpoint
-// @Description Post the API's login
+// @Description Post the API's login. Users with two-factor authentication get a challenge token for /auth/login/2fa instead of the token pair. Repeated failures slow the account down and then lock it, answered with 429 and Retry-After
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -49,30 +60,55 @@ func (u *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
+	client := clientInfo(c, req.DeviceName)
+	if wait, err := u.loginThrottle.Check(ctx, req.Email, client.IP); err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		utils.SendError(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	// Unknown emails go through Login too and fail the same way as a wrong
+	// password, the response must not tell which accounts exist
 	user, err := u.userService.FindByEmail(ctx, req.Email)
-	if err != nil {
-		utils.SendError(c, http.StatusNotFound, "Failed to find user")
+	if err != nil && err != mongo.ErrNoDocuments {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to find user")
 		return
 	}
 
-	if user == nil {
+	tokenPair, err := u.userService.Login(ctx, req.Password, user, client)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		var userID primitive.ObjectID
+		if user != nil {
+			userID = user.ID
+		}
+		u.loginThrottle.RecordFailure(ctx, req.Email, client.IP, userID)
 		utils.SendError(c, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
-
-	tokenPair, err := u.userService.Login(ctx, req.Password, user)
+	// The failures are only cleared once the second factor passed too
+	if errors.Is(err, service.ErrTwoFactorRequired) {
+		challenge, err := u.twoFactorService.CreateChallenge(ctx, user, client, utils.AMRPassword)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.SendSuccess(c, http.StatusOK, challenge, "Two-factor authentication required")
+		return
+	}
 	if err != nil {
-		utils.SendError(c, http.StatusUnauthorized, "Invalid password")
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+
+	u.loginThrottle.RecordSuccess(ctx, req.Email)
 	utils.SendSuccess(c, http.StatusOK, tokenPair, "Login successful")
 }
 
 // @Summary Register endpoint
-// @Description Post the API's register
+// @Description Post the API's register, a verification link is mailed to the new account. Closed with INVITE_ONLY, accounts are then created from invitations
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -81,6 +117,11 @@ func (u *UserHandler) Login(c *gin.Context) {
 func (u *UserHandler) Register(c *gin.Context) {
 	var req dto.RegisterRequest
 
+	if !u.userService.SelfRegistration() {
+		utils.SendError(c, http.StatusForbidden, service.ErrRegistrationClosed.Error())
+		return
+	}
+
 	if err := c.ShouldBindJSON(&req); err != nil {
 		errors := utils.FormatValidationError(err)
 		if len(errors) > 0 {
@@ -94,20 +135,9 @@ func (u *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	// Check if email already exists
-	existingUser, err := u.userService.FindByEmail(ctx, req.Email)
-	if err != nil && err != mongo.ErrNoDocuments {
-		utils.SendError(c, http.StatusInternalServerError, "Failed to check existing user")
-		return
-	}
-	if existingUser != nil {
-		utils.SendError(c, http.StatusBadRequest, "Email already exists")
-		return
-	}
-
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -115,20 +145,25 @@ func (u *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	if len(req.Roles) == 0 {
-		req.Roles = []string{string(utils.UserRole)}
-	}
-
 	// Create new user
 	req.Password = string(hashedPassword)
 
-	// Save to database
+	// Save to database, the unique email index rejects a taken email
 	user, err := u.userService.Create(ctx, &req)
+	if errors.Is(err, service.ErrEmailExists) {
+		utils.SendError(c, http.StatusBadRequest, "Email already exists")
+		return
+	}
 	if err != nil {
 		utils.SendError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	// The account exists either way, a failed mail can be sent again through the resend route
+	if err := u.verificationService.SendVerification(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID.Hex(), err)
+	}
+
 	utils.SendSuccess(c, http.StatusOK, user)
 }
 
@@ -140,7 +175,7 @@ func (u *UserHandler) Register(c *gin.Context) {
 // @Security Bearer
 // @Router /user/profile [get]
 func (u *UserHandler) GetProfile(c *gin.Context) {
-	_, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	user, ok := middleware.GetUserFromContext(c)
@@ -150,17 +185,22 @@ func (u *UserHandler) GetProfile(c *gin.Context) {
 	}
 
 	res := gin.H{
-		"id":    user.ID.Hex(),
-		"name":  user.Name,
-		"email": user.Email,
-		"roles": user.Roles,
+		"id":                 user.ID.Hex(),
+		"name":               user.Name,
+		"email":              user.Email,
+		"roles":              user.Roles,
+		"email_verified":     user.EmailVerified,
+		"two_factor_enabled": user.TwoFactorEnabled,
+	}
+	if user.ErasureScheduledAt != nil {
+		res["erasure_scheduled_at"] = user.ErasureScheduledAt
 	}
 
 	utils.SendSuccess(c, http.StatusOK, res)
 }
 
 // @Summary Update endpoint
-// @Description Get the API's update user
+// @Description Put the API's update user, only the user itself or a user with user:update may update a profile
 // @Tags user
 // @Accept json
 // @Produce json
@@ -185,7 +225,7 @@ func (u *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	objID, err := primitive.ObjectIDFromHex(id)
@@ -210,7 +250,7 @@ func (u *UserHandler) UpdateProfile(c *gin.Context) {
 }
 
 // @Summary Delete endpoint
-// @Description Get the API's delete user
+// @Description Delete the API's user, the user is soft-deleted and can be restored until purged
 // @Tags admin
 // @Accept json
 // @Produce json
@@ -218,9 +258,13 @@ func (u *UserHandler) UpdateProfile(c *gin.Context) {
 // @Param id path string true "User ID"
 // @Router /user/{id} [delete]
 func (u *UserHandler) DeleteUser(c *gin.Context) {
-	id := c.Param("id")
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	user, ok := middleware.GetUserFromContext(c)
@@ -229,12 +273,17 @@ func (u *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if user.ID.Hex() == id {
+	if user.ID == id {
 		utils.SendError(c, http.StatusUnauthorized, "You cannot delete yourself")
 		return
 	}
 
-	if err := u.userService.Delete(ctx, user.ID); err != nil {
+	err = u.userService.Delete(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		utils.SendError(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
 		utils.SendError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -242,6 +291,81 @@ func (u *UserHandler) DeleteUser(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusOK, nil, "User deleted successfully")
 }
 
+// @Summary Restore user endpoint
+// @Description Post the API's restore user, brings back a soft-deleted user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Router /user/{id}/restore [post]
+func (u *UserHandler) RestoreUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := u.userService.Restore(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		utils.SendError(c, http.StatusNotFound, "Deleted user not found")
+		return
+	}
+	if errors.Is(err, service.ErrEmailExists) {
+		utils.SendError(c, http.StatusConflict, "Another user has registered with this email")
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, user, "User restored successfully")
+}
+
+// @Summary Unlock user endpoint
+// @Description Post the API's unlock user, lifts a lock after too many failed logins
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Router /user/{id}/unlock [post]
+func (u *UserHandler) UnlockUser(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	actor, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	user, err := u.userService.FindByID(ctx, c.Param("id"))
+	if err == primitive.ErrInvalidHex {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if err == mongo.ErrNoDocuments {
+		utils.SendError(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := u.loginThrottle.Unlock(ctx, actor, user, c.ClientIP()); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, nil, "User unlocked successfully")
+}
+
 // @Summary User list endpoint
 // @Description Get the API's user list
 // @Tags admin
@@ -251,6 +375,7 @@ func (u *UserHandler) DeleteUser(c *gin.Context) {
 // @Param page query int false "Page number (default: 1)" default(1)
 // @Param pageSize query int false "Page size (default: 10)" default(10)
 // @Param name query string false "Filter by user name"
+// @Param deleted query bool false "List soft-deleted users instead"
 // @Router /user/list [get]
 func (u *UserHandler) UserList(c *gin.Context) {
 	page, pageSize := utils.PaginationParams(c)
@@ -261,7 +386,7 @@ func (u *UserHandler) UserList(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	users, total, err := u.userService.FindAll(ctx, filter, page, pageSize)
@@ -297,10 +422,10 @@ func (u *UserHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	tokenPair, err := u.userService.RefreshToken(ctx, req.RefreshToken)
+	tokenPair, err := u.userService.RefreshToken(ctx, req.RefreshToken, clientInfo(c, ""))
 	if err != nil {
 		utils.SendError(c, http.StatusUnauthorized, err.Error())
 		return
@@ -324,7 +449,7 @@ func (u *UserHandler) RefreshToken(c *gin.Context) {
 // @Param X-Refresh-Token header string true "Refresh token"
 // @Router /user/logout [get]
 func (u *UserHandler) Logout(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
 	// Get access token from context
@@ -355,3 +480,16 @@ func (u *UserHandler) Logout(c *gin.Context) {
 
 	utils.SendSuccess(c, http.StatusOK, nil, "Logout successful")
 }
+
+// clientInfo describes the device of the current request for session records.
+func clientInfo(c *gin.Context, deviceName string) model.ClientInfo {
+	if deviceName == "" {
+		deviceName = c.GetHeader("X-Device-Name")
+	}
+
+	return model.ClientInfo{
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	}
+}
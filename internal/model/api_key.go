@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// API key types. A personal key acts with the permissions of its owner,
+// narrowed by its scopes; a service key has exactly its scopes, for as long
+// as its owner still holds them and apikey:manage.
+const (
+	APIKeyPersonal = "personal"
+	APIKeyService  = "service"
+)
+
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Type       string             `bson:"type" json:"type"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Hash       string             `bson:"hash" json:"-"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Active reports whether the key is neither revoked nor expired.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// Scoped reports whether the key is limited to its scopes: every service key
+// and the personal keys created with scopes.
+func (k *APIKey) Scoped() bool {
+	return k.Type == APIKeyService || len(k.Scopes) > 0
+}
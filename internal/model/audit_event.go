@@ -0,0 +1,90 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions
+const (
+	AuditRoleGranted = "user.role_granted"
+	AuditRoleRevoked = "user.role_revoked"
+	AuditRoleCreated = "role.created"
+	AuditRoleUpdated = "role.updated"
+	AuditRoleDeleted = "role.deleted"
+
+	AuditUserLocked   = "user.locked"
+	AuditUserUnlocked = "user.unlocked"
+
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonatedRequest  = "impersonation.request"
+
+	AuditAPIKeyCreated = "apikey.created"
+	AuditAPIKeyRevoked = "apikey.revoked"
+
+	AuditIdentityLinked = "user.identity_linked"
+
+	AuditUserInvited        = "user.invited"
+	AuditInvitationRevoked  = "invitation.revoked"
+	AuditInvitationAccepted = "invitation.accepted"
+
+	AuditOrgCreated       = "org.created"
+	AuditOrgUpdated       = "org.updated"
+	AuditOrgDeleted       = "org.deleted"
+	AuditOrgMemberInvited = "org.member_invited"
+	AuditOrgMemberJoined  = "org.member_joined"
+	AuditOrgMemberUpdated = "org.member_updated"
+	AuditOrgMemberRemoved = "org.member_removed"
+
+	AuditLogin              = "auth.login"
+	AuditTokenRefreshed     = "auth.token_refreshed"
+	AuditRefreshTokenReused = "auth.refresh_token_reused"
+	AuditUserUpdated        = "user.updated"
+	AuditUserDeleted        = "user.deleted"
+	AuditFileUploaded       = "file.uploaded"
+	AuditFileDeleted        = "file.deleted"
+	AuditProductCreated     = "product.created"
+	AuditProductUpdated     = "product.updated"
+	AuditProductDeleted     = "product.deleted"
+	AuditOrderCreated       = "order.created"
+	AuditOrderStatusChanged = "order.status_changed"
+	AuditCategoryCreated    = "category.created"
+	AuditCategoryUpdated    = "category.updated"
+	AuditCategoryDeleted    = "category.deleted"
+
+	AuditDataExported     = "user.data_exported"
+	AuditErasureRequested = "user.erasure_requested"
+	AuditErasureCancelled = "user.erasure_cancelled"
+	AuditUserErased       = "user.erased"
+	AuditUserRestored     = "user.restored"
+	AuditUserPurged       = "user.purged"
+
+	AuditEmailChangeRequested = "user.email_change_requested"
+	AuditEmailChanged         = "user.email_changed"
+
+	AuditPasswordReset   = "user.password_reset"
+	AuditPasswordChanged = "user.password_changed"
+
+	AuditTwoFactorEnabled       = "user.two_factor_enabled"
+	AuditTwoFactorDisabled      = "user.two_factor_disabled"
+	AuditTwoFactorPolicyUpdated = "two_factor.policy_updated"
+
+	AuditSessionRevoked  = "auth.session_revoked"
+	AuditSessionsRevoked = "auth.sessions_revoked"
+)
+
+type AuditEvent struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Action    string                 `bson:"action" json:"action"`
+	ActorID   primitive.ObjectID     `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	TargetID  primitive.ObjectID     `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	Metadata  map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	IP        string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent string                 `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	RequestID string                 `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	// Before and After hold the changed fields of the target, see service.AuditDiff
+	Before    map[string]interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After     map[string]interface{} `bson:"after,omitempty" json:"after,omitempty"`
+	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
+}
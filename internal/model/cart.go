@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CartItem is a product in a cart. Price is the price when the item was
+// added or last updated, reading the cart compares it with the current one.
+type CartItem struct {
+	ProductID primitive.ObjectID  `bson:"product_id" json:"product_id"`
+	OrgID     *primitive.ObjectID `bson:"org_id,omitempty" json:"org_id,omitempty"`
+	Quantity  int                 `bson:"quantity" json:"quantity"`
+	Price     float64             `bson:"price" json:"price"`
+	AddedAt   time.Time           `bson:"added_at" json:"added_at"`
+}
+
+// Cart is the cart of a signed in user. Anonymous carts have the same items
+// but live in Redis.
+type Cart struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Items     []CartItem         `bson:"items" json:"items"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CartLine is a cart item priced against the product as it is now
+type CartLine struct {
+	ProductID  primitive.ObjectID `json:"product_id"`
+	Name       string             `json:"name,omitempty"`
+	Quantity   int                `json:"quantity"`
+	Price      float64            `json:"price"`
+	AddedPrice float64            `json:"added_price"`
+	Subtotal   float64            `json:"subtotal"`
+	Available  int                `json:"available"`
+	// PriceChanged is set when the price differs from the one the item was
+	// added at
+	PriceChanged bool `json:"price_changed"`
+	// OutOfStock is set when less stock is free than the quantity
+	OutOfStock bool `json:"out_of_stock"`
+	// Unavailable is set when the product no longer exists
+	Unavailable bool `json:"unavailable"`
+}
+
+// PricedCart is a cart as it is read. Total only counts lines that can be
+// ordered. CartID is set for anonymous carts.
+type PricedCart struct {
+	CartID string     `json:"cart_id,omitempty"`
+	Items  []CartLine `json:"items"`
+	Total  float64    `json:"total"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Order states. A pending order holds its stock until it is paid or
+// cancelled; cancelled and refunded orders are final. A refunding order is
+// being refunded by the payment provider and only moves on to refunded.
+const (
+	OrderPending   = "pending"
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+	OrderRefunding = "refunding"
+	OrderRefunded  = "refunded"
+)
+
+// orderTransitions lists the states each state may move to
+var orderTransitions = map[string][]string{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderRefunded},
+	OrderRefunding: {OrderRefunded},
+}
+
+// CanTransition reports whether an order in state from may move to state to.
+func CanTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderItem snapshots the product as it was sold, later changes of the
+// product do not alter the order.
+type OrderItem struct {
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	Name      string             `bson:"name" json:"name"`
+	Price     float64            `bson:"price" json:"price"`
+	Quantity  int                `bson:"quantity" json:"quantity"`
+	Subtotal  float64            `bson:"subtotal" json:"subtotal"`
+}
+
+type OrderStatusChange struct {
+	Status    string             `bson:"status" json:"status"`
+	ActorID   primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ChangedAt time.Time          `bson:"changed_at" json:"changed_at"`
+}
+
+type Order struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	OrgID     *primitive.ObjectID `bson:"org_id,omitempty" json:"org_id,omitempty"`
+	Items     []OrderItem         `bson:"items" json:"items"`
+	Total     float64             `bson:"total" json:"total"`
+	Status    string              `bson:"status" json:"status"`
+	PaymentID string              `bson:"payment_id,omitempty" json:"payment_id,omitempty"`
+	History   []OrderStatusChange `bson:"history" json:"history"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Roles of a member within an organization. Owners manage the organization
+// and its owners, admins manage members and invitations.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+type Organization struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Membership gives a user a role in an organization.
+type Membership struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	OrgID     primitive.ObjectID     `bson:"org_id" json:"org_id"`
+	UserID    primitive.ObjectID     `bson:"user_id" json:"user_id"`
+	Role      string                 `bson:"role" json:"role"`
+	User      *UserResponseOnProduct `bson:"user,omitempty" json:"user,omitempty"`
+	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// OrgInvitation invites an email address to join an organization. Only a
+// hash of the token is stored, the token itself is mailed to the invitee.
+type OrgInvitation struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID      primitive.ObjectID `bson:"org_id" json:"org_id"`
+	Email      string             `bson:"email" json:"email"`
+	Role       string             `bson:"role" json:"role"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	InvitedBy  primitive.ObjectID `bson:"invited_by" json:"invited_by"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	AcceptedAt *time.Time         `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// IsOrgRole reports whether role is one of the organization roles.
+func IsOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin || role == OrgRoleMember
+}
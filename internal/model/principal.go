@@ -0,0 +1,20 @@
+package model
+
+// Principal is who a request acts for: a user signed in with an access
+// token, or an API key acting for the user that created it. While an admin
+// impersonates User, Impersonator is that admin.
+type Principal struct {
+	User         *User
+	APIKey       *APIKey
+	Impersonator *User
+}
+
+// IsAPIKey reports whether the request was authenticated with an API key.
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKey != nil
+}
+
+// IsImpersonated reports whether someone else is acting as the user.
+func (p *Principal) IsImpersonated() bool {
+	return p.Impersonator != nil
+}
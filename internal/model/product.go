@@ -6,13 +6,21 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Product is sold from Stock, of which Reserved is held by active
+// reservations. Stock only changes through the stock ledger. Version counts
+// the edits of the product and is its ETag. Tags are lower case.
 type Product struct {
-	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
-	Name      string                 `bson:"name" json:"name"`
-	Price     float64                `bson:"price" json:"price"`
-	Stock     int                    `bson:"stock" json:"stock"`
-	UserID    primitive.ObjectID     `bson:"user_id"`
-	User      *UserResponseOnProduct `bson:"user,omitempty"`
-	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time              `bson:"updated_at" json:"updated_at"`
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Name       string                 `bson:"name" json:"name"`
+	Price      float64                `bson:"price" json:"price"`
+	Stock      int                    `bson:"stock" json:"stock"`
+	Reserved   int                    `bson:"reserved" json:"reserved"`
+	Version    int64                  `bson:"version" json:"version"`
+	CategoryID *primitive.ObjectID    `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	Tags       []string               `bson:"tags,omitempty" json:"tags,omitempty"`
+	UserID     primitive.ObjectID     `bson:"user_id"`
+	OrgID      *primitive.ObjectID    `bson:"org_id,omitempty" json:"org_id,omitempty"`
+	User       *UserResponseOnProduct `bson:"user,omitempty"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time              `bson:"updated_at" json:"updated_at"`
 }
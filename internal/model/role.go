@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// RoleDefinition maps a role name to the permissions it grants. The name is
+// the document id, so role names are unique.
+type RoleDefinition struct {
+	Name        string    `bson:"_id" json:"name"`
+	Description string    `bson:"description" json:"description"`
+	Permissions []string  `bson:"permissions" json:"permissions"`
+	BuiltIn     bool      `bson:"built_in" json:"built_in"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Session is a login on one device. It is backed by the refresh token
+// family started at login, so revoking a session revokes its tokens.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// ClientInfo describes the device a session is started or refreshed from.
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
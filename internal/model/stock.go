@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Stock movement types. Receipts and returns add stock, sales remove it and
+// adjustments correct it either way.
+const (
+	StockReceipt    = "receipt"
+	StockSale       = "sale"
+	StockAdjustment = "adjustment"
+	StockReturn     = "return"
+)
+
+// StockMovement is an entry of the append-only stock ledger. Quantity is the
+// signed change, StockAfter the product's stock once it was applied.
+type StockMovement struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ProductID   primitive.ObjectID  `bson:"product_id" json:"product_id"`
+	OrgID       *primitive.ObjectID `bson:"org_id,omitempty" json:"org_id,omitempty"`
+	Type        string              `bson:"type" json:"type"`
+	Quantity    int                 `bson:"quantity" json:"quantity"`
+	StockAfter  int                 `bson:"stock_after" json:"stock_after"`
+	Note        string              `bson:"note,omitempty" json:"note,omitempty"`
+	ReferenceID *primitive.ObjectID `bson:"reference_id,omitempty" json:"reference_id,omitempty"`
+	ActorID     primitive.ObjectID  `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// StockReservation holds stock of a product for a user until it expires, is
+// released or is consumed by a sale.
+type StockReservation struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ProductID  primitive.ObjectID  `bson:"product_id" json:"product_id"`
+	OrgID      *primitive.ObjectID `bson:"org_id,omitempty" json:"org_id,omitempty"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Quantity   int                 `bson:"quantity" json:"quantity"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expires_at"`
+	ReleasedAt *time.Time          `bson:"released_at,omitempty" json:"released_at,omitempty"`
+	ConsumedAt *time.Time          `bson:"consumed_at,omitempty" json:"consumed_at,omitempty"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+}
@@ -15,6 +15,29 @@ type User struct {
 	Products  []*Product         `bson:"products,omitempty"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+
+	EmailVerified   bool       `bson:"email_verified" json:"email_verified"`
+	EmailVerifiedAt *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+
+	TwoFactorEnabled bool     `bson:"two_factor_enabled" json:"two_factor_enabled"`
+	TOTPSecret       string   `bson:"totp_secret,omitempty" json:"-"`
+	RecoveryCodes    []string `bson:"recovery_codes,omitempty" json:"-"` // sha256 hashes of the unused codes
+
+	Identities []ExternalIdentity `bson:"identities,omitempty" json:"-"`
+
+	// Set while an erasure asked for by the user waits out its grace period
+	ErasureScheduledAt *time.Time `bson:"erasure_scheduled_at,omitempty" json:"erasure_scheduled_at,omitempty"`
+	ErasedAt           *time.Time `bson:"erased_at,omitempty" json:"erased_at,omitempty"`
+
+	// Soft-deleted users are hidden from queries until restored or purged
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+}
+
+// ExternalIdentity links the account to a user of an OIDC provider.
+type ExternalIdentity struct {
+	Provider string    `bson:"provider"`
+	Subject  string    `bson:"subject"`
+	LinkedAt time.Time `bson:"linked_at"`
 }
 
 type UserResponseOnProduct struct {
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APIKeyRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, key *model.APIKey) error
+	Update(ctx context.Context, payload bson.M, id primitive.ObjectID) (*model.APIKey, error)
+	FindOne(ctx context.Context, query bson.M) (*model.APIKey, error)
+	FindAll(ctx context.Context, query bson.M) ([]*model.APIKey, error)
+}
+
+type apiKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository(db *mongo.Database) APIKeyRepository {
+	return &apiKeyRepository{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+// EnsureIndexes makes key lookups by hash an index hit and hashes unique.
+func (r *apiKeyRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, payload bson.M, id primitive.ObjectID) (*model.APIKey, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var key model.APIKey
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": payload}, opts).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindOne(ctx context.Context, query bson.M) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := r.collection.FindOne(ctx, query).Decode(&key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindAll(ctx context.Context, query bson.M) ([]*model.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*model.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Name of the created_at index, a TTL index when a retention is set
+const auditRetentionIndex = "created_at_1"
+
+// AuditRepository is append-only: events are never updated, and only the
+// retention index removes them.
+type AuditRepository interface {
+	EnsureIndexes(ctx context.Context, retention time.Duration) error
+	Create(ctx context.Context, event *model.AuditEvent) error
+	FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.AuditEvent, error)
+	Count(ctx context.Context, query bson.D) (int64, error)
+	Each(ctx context.Context, query bson.D, opts *options.FindOptions, fn func(*model.AuditEvent) error) error
+}
+
+type auditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(db *mongo.Database) AuditRepository {
+	return &auditRepository{
+		collection: db.Collection("audit_events"),
+	}
+}
+
+// EnsureIndexes creates the indexes of the audit filters. With a retention
+// created_at is a TTL index and MongoDB deletes older events, 0 keeps them
+// forever. A changed retention replaces the index.
+func (a *auditRepository) EnsureIndexes(ctx context.Context, retention time.Duration) error {
+	_, err := a.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	retentionIndex := options.Index().SetName(auditRetentionIndex)
+	if retention > 0 {
+		retentionIndex.SetExpireAfterSeconds(int32(retention.Seconds()))
+	}
+
+	cursor, err := a.collection.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var indexes []bson.M
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if index["name"] != auditRetentionIndex {
+			continue
+		}
+		if expireAfterSeconds(index) == int64(retention.Seconds()) {
+			return nil
+		}
+		if _, err := a.collection.Indexes().DropOne(ctx, auditRetentionIndex); err != nil {
+			return err
+		}
+	}
+
+	_, err = a.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: retentionIndex,
+	})
+	return err
+}
+
+// expireAfterSeconds reads the TTL of an index spec, 0 for a plain index.
+func expireAfterSeconds(index bson.M) int64 {
+	switch value := index["expireAfterSeconds"].(type) {
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case float64:
+		return int64(value)
+	}
+	return 0
+}
+
+func (a *auditRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	_, err := a.collection.InsertOne(ctx, event)
+	return err
+}
+
+func (a *auditRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.AuditEvent, error) {
+	cursor, err := a.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*model.AuditEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (a *auditRepository) Count(ctx context.Context, query bson.D) (int64, error) {
+	return a.collection.CountDocuments(ctx, query)
+}
+
+// Each calls fn for every matching event without loading them all, for
+// exports larger than a page.
+func (a *auditRepository) Each(ctx context.Context, query bson.D, opts *options.FindOptions, fn func(*model.AuditEvent) error) error {
+	cursor, err := a.collection.Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var event model.AuditEvent
+		if err := cursor.Decode(&event); err != nil {
+			return err
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CartRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	FindOne(ctx context.Context, query bson.M) (*model.Cart, error)
+	Save(ctx context.Context, cart *model.Cart) error
+	Delete(ctx context.Context, query bson.M) error
+}
+
+type cartRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCartRepository(db *mongo.Database) CartRepository {
+	return &cartRepository{
+		collection: db.Collection("carts"),
+	}
+}
+
+// EnsureIndexes keeps one cart per user.
+func (r *cartRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *cartRepository) FindOne(ctx context.Context, query bson.M) (*model.Cart, error) {
+	var cart model.Cart
+	if err := r.collection.FindOne(ctx, query).Decode(&cart); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// Save replaces the user's cart, creating it on first use.
+func (r *cartRepository) Save(ctx context.Context, cart *model.Cart) error {
+	_, err := r.collection.ReplaceOne(ctx,
+		bson.M{"user_id": cart.UserID},
+		bson.M{"user_id": cart.UserID, "items": cart.Items, "updated_at": cart.UpdatedAt},
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// Delete removes the cart matching query, a missing cart is not an error.
+func (r *cartRepository) Delete(ctx context.Context, query bson.M) error {
+	_, err := r.collection.DeleteOne(ctx, query)
+	return err
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CategoryRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, category *model.Category) error
+	FindOne(ctx context.Context, query bson.M) (*model.Category, error)
+	FindAll(ctx context.Context, query bson.D) ([]*model.Category, error)
+	FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Category, error)
+	Count(ctx context.Context, query bson.D) (int64, error)
+	Delete(ctx context.Context, query bson.M) error
+}
+
+// Like products, every query of categoryRepository is scoped to the
+// organization selected with WithOrg, or to the personal space without one.
+type categoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCategoryRepository(db *mongo.Database) CategoryRepository {
+	return &categoryRepository{
+		collection: db.Collection("categories"),
+	}
+}
+
+// EnsureIndexes makes subtree lookups index hits and keeps sibling names
+// unique.
+func (r *categoryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "org_id", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	return err
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *model.Category) error {
+	category.OrgID = orgRef(ctx)
+	_, err := r.collection.InsertOne(ctx, category)
+	return err
+}
+
+func (r *categoryRepository) FindOne(ctx context.Context, query bson.M) (*model.Category, error) {
+	var category model.Category
+	if err := r.collection.FindOne(ctx, scopeM(ctx, query)).Decode(&category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindAll returns the matching categories sorted by name, trees are small
+// enough to be read whole.
+func (r *categoryRepository) FindAll(ctx context.Context, query bson.D) ([]*model.Category, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, scopeD(ctx, query), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var categories []*model.Category
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// FindOneAndUpdate applies a raw update to the first category matching
+// query and returns the updated document, or mongo.ErrNoDocuments when
+// nothing matched.
+func (r *categoryRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Category, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var category model.Category
+	if err := r.collection.FindOneAndUpdate(ctx, scopeM(ctx, query), update, opts).Decode(&category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) Count(ctx context.Context, query bson.D) (int64, error) {
+	return r.collection.CountDocuments(ctx, scopeD(ctx, query))
+}
+
+// Delete removes the category matching query, mongo.ErrNoDocuments when
+// nothing matched.
+func (r *categoryRepository) Delete(ctx context.Context, query bson.M) error {
+	res, err := r.collection.DeleteOne(ctx, scopeM(ctx, query))
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -23,8 +23,12 @@ type LocalFileRepository interface {
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.FileStorage, error)
 	FindOne(ctx context.Context, query bson.M) (*model.FileStorage, error)
+	Count(ctx context.Context, query bson.D) (int64, error)
+	UpdateMany(ctx context.Context, query bson.D, update bson.M) (int64, error)
 }
 
+// Every query of localFileRepository is scoped to the organization selected
+// with WithOrg, or to personal files without one.
 type localFileRepository struct {
 	collection *mongo.Collection
 	config     *config.Config
@@ -84,6 +88,7 @@ func (r *localFileRepository) Uploads(ctx context.Context, files []*multipart.Fi
 			BasePath: basePath,
 			Dir:      uploadDir,
 			UserID:   user.ID,
+			OrgID:    orgRef(ctx),
 		}
 		resFileStore, err := r.collection.InsertOne(ctx, payload)
 		if err != nil {
@@ -98,13 +103,14 @@ func (r *localFileRepository) Uploads(ctx context.Context, files []*multipart.Fi
 
 func (r *localFileRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	var fileStorage model.FileStorage
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&fileStorage)
+	err := r.collection.FindOne(ctx, scopeM(ctx, bson.M{"_id": id})).Decode(&fileStorage)
 	if err != nil {
 		return err
 	}
 
+	// A file already gone from disk only leaves its document to remove
 	filePath := filepath.Join(fileStorage.Dir, fileStorage.Name)
-	if err := os.Remove(filePath); err != nil {
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -112,7 +118,7 @@ func (r *localFileRepository) Delete(ctx context.Context, id primitive.ObjectID)
 }
 
 func (r *localFileRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.FileStorage, error) {
-	cursor, err := r.collection.Find(ctx, query, opts)
+	cursor, err := r.collection.Find(ctx, scopeD(ctx, query), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -128,9 +134,21 @@ func (r *localFileRepository) FindAll(ctx context.Context, query bson.D, opts *o
 
 func (r *localFileRepository) FindOne(ctx context.Context, query bson.M) (*model.FileStorage, error) {
 	var fileStorage model.FileStorage
-	err := r.collection.FindOne(ctx, query).Decode(&fileStorage)
+	err := r.collection.FindOne(ctx, scopeM(ctx, query)).Decode(&fileStorage)
 	if err != nil {
 		return nil, err
 	}
 	return &fileStorage, nil
 }
+
+func (r *localFileRepository) Count(ctx context.Context, query bson.D) (int64, error) {
+	return r.collection.CountDocuments(ctx, scopeD(ctx, query))
+}
+
+func (r *localFileRepository) UpdateMany(ctx context.Context, query bson.D, update bson.M) (int64, error) {
+	res, err := r.collection.UpdateMany(ctx, scopeD(ctx, query), update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InvitationRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, invitation *model.Invitation) error
+	FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Invitation, error)
+	UpdateMany(ctx context.Context, query bson.M, update bson.M) error
+	FindAll(ctx context.Context, query bson.M) ([]*model.Invitation, error)
+}
+
+type invitationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewInvitationRepository(db *mongo.Database) InvitationRepository {
+	return &invitationRepository{
+		collection: db.Collection("invitations"),
+	}
+}
+
+// EnsureIndexes makes looking up the invitations of an address an index hit.
+func (r *invitationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "email", Value: 1}},
+	})
+	return err
+}
+
+func (r *invitationRepository) Create(ctx context.Context, invitation *model.Invitation) error {
+	_, err := r.collection.InsertOne(ctx, invitation)
+	return err
+}
+
+// FindOneAndUpdate applies update to the first invitation matching query and
+// returns it updated, or mongo.ErrNoDocuments when nothing matched.
+func (r *invitationRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Invitation, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var invitation model.Invitation
+	if err := r.collection.FindOneAndUpdate(ctx, query, update, opts).Decode(&invitation); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) UpdateMany(ctx context.Context, query bson.M, update bson.M) error {
+	_, err := r.collection.UpdateMany(ctx, query, update)
+	return err
+}
+
+func (r *invitationRepository) FindAll(ctx context.Context, query bson.M) ([]*model.Invitation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []*model.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
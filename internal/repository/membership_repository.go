@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MembershipRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, membership *model.Membership) error
+	Update(ctx context.Context, query bson.M, payload bson.M) (*model.Membership, error)
+	Delete(ctx context.Context, query bson.M) error
+	DeleteMany(ctx context.Context, query bson.M) error
+	FindOne(ctx context.Context, query bson.M) (*model.Membership, error)
+	FindAll(ctx context.Context, query bson.M) ([]*model.Membership, error)
+	Count(ctx context.Context, query bson.M) (int64, error)
+}
+
+type membershipRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMembershipRepository(db *mongo.Database) MembershipRepository {
+	return &membershipRepository{
+		collection: db.Collection("memberships"),
+	}
+}
+
+// EnsureIndexes allows one membership per user and organization and makes
+// listing the organizations of a user an index hit.
+func (r *membershipRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
+
+// Create inserts a membership, an existing one fails with a duplicate key error.
+func (r *membershipRepository) Create(ctx context.Context, membership *model.Membership) error {
+	_, err := r.collection.InsertOne(ctx, membership)
+	return err
+}
+
+func (r *membershipRepository) Update(ctx context.Context, query bson.M, payload bson.M) (*model.Membership, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var membership model.Membership
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		query,
+		bson.M{
+			"$set": payload,
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+		opts,
+	).Decode(&membership)
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *membershipRepository) Delete(ctx context.Context, query bson.M) error {
+	res, err := r.collection.DeleteOne(ctx, query)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *membershipRepository) DeleteMany(ctx context.Context, query bson.M) error {
+	_, err := r.collection.DeleteMany(ctx, query)
+	return err
+}
+
+func (r *membershipRepository) FindOne(ctx context.Context, query bson.M) (*model.Membership, error) {
+	var membership model.Membership
+	if err := r.collection.FindOne(ctx, query).Decode(&membership); err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// FindAll returns the memberships with the name and email of each member.
+func (r *membershipRepository) FindAll(ctx context.Context, query bson.M) ([]*model.Membership, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	memberships := []*model.Membership{}
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (r *membershipRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
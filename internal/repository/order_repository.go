@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrderRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, order *model.Order) error
+	FindOne(ctx context.Context, query bson.M) (*model.Order, error)
+	FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Order, error)
+	FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.Order, error)
+	Count(ctx context.Context, query bson.D) (int64, error)
+}
+
+type orderRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOrderRepository(db *mongo.Database) OrderRepository {
+	return &orderRepository{
+		collection: db.Collection("orders"),
+	}
+}
+
+// EnsureIndexes makes the order history of a user and the admin listing by
+// status index hits.
+func (r *orderRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+func (r *orderRepository) Create(ctx context.Context, order *model.Order) error {
+	_, err := r.collection.InsertOne(ctx, order)
+	return err
+}
+
+func (r *orderRepository) FindOne(ctx context.Context, query bson.M) (*model.Order, error) {
+	var order model.Order
+	if err := r.collection.FindOne(ctx, query).Decode(&order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindOneAndUpdate applies a raw update to the first order matching query
+// and returns the updated document, or mongo.ErrNoDocuments when nothing
+// matched.
+func (r *orderRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Order, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var order model.Order
+	if err := r.collection.FindOneAndUpdate(ctx, query, update, opts).Decode(&order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.Order, error) {
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []*model.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *orderRepository) Count(ctx context.Context, query bson.D) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrgInvitationRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, invitation *model.OrgInvitation) error
+	FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.OrgInvitation, error)
+	Delete(ctx context.Context, query bson.M) error
+	DeleteMany(ctx context.Context, query bson.M) error
+	FindAll(ctx context.Context, query bson.M) ([]*model.OrgInvitation, error)
+}
+
+type orgInvitationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOrgInvitationRepository(db *mongo.Database) OrgInvitationRepository {
+	return &orgInvitationRepository{
+		collection: db.Collection("org_invitations"),
+	}
+}
+
+// EnsureIndexes makes token lookups an index hit and token hashes unique.
+func (r *orgInvitationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "org_id", Value: 1}}},
+	})
+	return err
+}
+
+func (r *orgInvitationRepository) Create(ctx context.Context, invitation *model.OrgInvitation) error {
+	_, err := r.collection.InsertOne(ctx, invitation)
+	return err
+}
+
+// FindOneAndUpdate applies update to the first invitation matching query and
+// returns it updated, or mongo.ErrNoDocuments when nothing matched.
+func (r *orgInvitationRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.OrgInvitation, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var invitation model.OrgInvitation
+	if err := r.collection.FindOneAndUpdate(ctx, query, update, opts).Decode(&invitation); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *orgInvitationRepository) Delete(ctx context.Context, query bson.M) error {
+	res, err := r.collection.DeleteOne(ctx, query)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *orgInvitationRepository) DeleteMany(ctx context.Context, query bson.M) error {
+	_, err := r.collection.DeleteMany(ctx, query)
+	return err
+}
+
+func (r *orgInvitationRepository) FindAll(ctx context.Context, query bson.M) ([]*model.OrgInvitation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []*model.OrgInvitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type orgContextKey struct{}
+
+// WithOrg returns a context whose queries on org scoped collections, products
+// and files, only see the documents of the organization.
+func WithOrg(ctx context.Context, orgID primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, orgContextKey{}, orgID)
+}
+
+// OrgFromContext returns the organization selected with WithOrg.
+func OrgFromContext(ctx context.Context) (primitive.ObjectID, bool) {
+	orgID, ok := ctx.Value(orgContextKey{}).(primitive.ObjectID)
+	return orgID, ok && !orgID.IsZero()
+}
+
+// orgFilter matches the documents of the active organization. Without one
+// it matches the personal documents, the ones that belong to no organization.
+func orgFilter(ctx context.Context) bson.E {
+	if orgID, ok := OrgFromContext(ctx); ok {
+		return bson.E{Key: "org_id", Value: orgID}
+	}
+	return bson.E{Key: "org_id", Value: bson.M{"$exists": false}}
+}
+
+func scopeD(ctx context.Context, query bson.D) bson.D {
+	scoped := make(bson.D, 0, len(query)+1)
+	scoped = append(scoped, query...)
+	return append(scoped, orgFilter(ctx))
+}
+
+func scopeM(ctx context.Context, query bson.M) bson.M {
+	scoped := make(bson.M, len(query)+1)
+	for k, v := range query {
+		scoped[k] = v
+	}
+	filter := orgFilter(ctx)
+	scoped[filter.Key] = filter.Value
+	return scoped
+}
+
+// orgRef is the org_id stored on documents created in ctx.
+func orgRef(ctx context.Context) *primitive.ObjectID {
+	if orgID, ok := OrgFromContext(ctx); ok {
+		return &orgID
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *model.Organization) error
+	Update(ctx context.Context, payload bson.M, id primitive.ObjectID) (*model.Organization, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	FindOne(ctx context.Context, query bson.M) (*model.Organization, error)
+	FindAll(ctx context.Context, query bson.M) ([]*model.Organization, error)
+}
+
+type organizationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOrganizationRepository(db *mongo.Database) OrganizationRepository {
+	return &organizationRepository{
+		collection: db.Collection("organizations"),
+	}
+}
+
+func (r *organizationRepository) Create(ctx context.Context, org *model.Organization) error {
+	res, err := r.collection.InsertOne(ctx, org)
+	if err != nil {
+		return err
+	}
+	org.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *organizationRepository) Update(ctx context.Context, payload bson.M, id primitive.ObjectID) (*model.Organization, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var org model.Organization
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": payload,
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+		opts,
+	).Decode(&org)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *organizationRepository) FindOne(ctx context.Context, query bson.M) (*model.Organization, error) {
+	var org model.Organization
+	if err := r.collection.FindOne(ctx, query).Decode(&org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) FindAll(ctx context.Context, query bson.M) ([]*model.Organization, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orgs := []*model.Organization{}
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
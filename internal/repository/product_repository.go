@@ -11,11 +11,19 @@ import (
 )
 
 type ProductRepository interface {
+	EnsureIndexes(ctx context.Context) error
 	Create(ctx context.Context, product *model.Product) (*model.Product, error)
 	FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.Product, error)
 	Count(ctx context.Context, query bson.D) (int64, error)
+	FindOne(ctx context.Context, query bson.M) (*model.Product, error)
+	FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Product, error)
+	Delete(ctx context.Context, query bson.M) error
+	UpdateMany(ctx context.Context, query bson.D, update bson.M) (int64, error)
+	DeleteMany(ctx context.Context, query bson.D) (int64, error)
 }
 
+// Every query of productRepository is scoped to the organization selected
+// with WithOrg, or to personal products without one.
 type productRepository struct {
 	collection *mongo.Collection
 }
@@ -26,7 +34,17 @@ func NewProductRepository(db *mongo.Database) ProductRepository {
 	}
 }
 
+// EnsureIndexes makes the category and tag filters index hits.
+func (p *productRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := p.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "category_id", Value: 1}}},
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "tags", Value: 1}}},
+	})
+	return err
+}
+
 func (p *productRepository) Create(ctx context.Context, product *model.Product) (*model.Product, error) {
+	product.OrgID = orgRef(ctx)
 	res, err := p.collection.InsertOne(ctx, product)
 	if err != nil {
 		return nil, err
@@ -38,7 +56,7 @@ func (p *productRepository) Create(ctx context.Context, product *model.Product)
 
 func (p *productRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.Product, error) {
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: query}},
+		{{Key: "$match", Value: scopeD(ctx, query)}},
 		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
 		{{Key: "$skip", Value: opts.Skip}},
 		{{Key: "$limit", Value: opts.Limit}},
@@ -65,5 +83,54 @@ func (p *productRepository) FindAll(ctx context.Context, query bson.D, opts *opt
 }
 
 func (p *productRepository) Count(ctx context.Context, query bson.D) (int64, error) {
-	return p.collection.CountDocuments(ctx, query)
+	return p.collection.CountDocuments(ctx, scopeD(ctx, query))
+}
+
+func (p *productRepository) FindOne(ctx context.Context, query bson.M) (*model.Product, error) {
+	var product model.Product
+	if err := p.collection.FindOne(ctx, scopeM(ctx, query)).Decode(&product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// FindOneAndUpdate applies a raw update to the first product matching query
+// and returns the updated document, or mongo.ErrNoDocuments when nothing
+// matched.
+func (p *productRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Product, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var product model.Product
+	if err := p.collection.FindOneAndUpdate(ctx, scopeM(ctx, query), update, opts).Decode(&product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// Delete removes the product matching query, mongo.ErrNoDocuments when
+// nothing matched.
+func (p *productRepository) Delete(ctx context.Context, query bson.M) error {
+	res, err := p.collection.DeleteOne(ctx, scopeM(ctx, query))
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (p *productRepository) UpdateMany(ctx context.Context, query bson.D, update bson.M) (int64, error) {
+	res, err := p.collection.UpdateMany(ctx, scopeD(ctx, query), update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (p *productRepository) DeleteMany(ctx context.Context, query bson.D) (int64, error) {
+	res, err := p.collection.DeleteMany(ctx, scopeD(ctx, query))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
 }
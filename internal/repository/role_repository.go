@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *model.RoleDefinition) error
+	Update(ctx context.Context, name string, payload bson.M) (*model.RoleDefinition, error)
+	Delete(ctx context.Context, name string) error
+	FindOne(ctx context.Context, name string) (*model.RoleDefinition, error)
+	FindAll(ctx context.Context) ([]*model.RoleDefinition, error)
+}
+
+type roleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRoleRepository(db *mongo.Database) RoleRepository {
+	return &roleRepository{
+		collection: db.Collection("roles"),
+	}
+}
+
+// Create inserts a role, a taken name fails with a duplicate key error.
+func (r *roleRepository) Create(ctx context.Context, role *model.RoleDefinition) error {
+	_, err := r.collection.InsertOne(ctx, role)
+	return err
+}
+
+func (r *roleRepository) Update(ctx context.Context, name string, payload bson.M) (*model.RoleDefinition, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var role model.RoleDefinition
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": name},
+		bson.M{
+			"$set": payload,
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+		opts,
+	).Decode(&role)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) Delete(ctx context.Context, name string) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *roleRepository) FindOne(ctx context.Context, name string) (*model.RoleDefinition, error) {
+	var role model.RoleDefinition
+	if err := r.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]*model.RoleDefinition, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []*model.RoleDefinition
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"example-go-project/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StockMovementRepository is append-only, the ledger is never rewritten.
+type StockMovementRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, movement *model.StockMovement) error
+	FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.StockMovement, error)
+	Count(ctx context.Context, query bson.D) (int64, error)
+}
+
+type stockMovementRepository struct {
+	collection *mongo.Collection
+}
+
+func NewStockMovementRepository(db *mongo.Database) StockMovementRepository {
+	return &stockMovementRepository{
+		collection: db.Collection("stock_movements"),
+	}
+}
+
+// EnsureIndexes makes the history of a product an index hit.
+func (r *stockMovementRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	return err
+}
+
+func (r *stockMovementRepository) Create(ctx context.Context, movement *model.StockMovement) error {
+	_, err := r.collection.InsertOne(ctx, movement)
+	return err
+}
+
+func (r *stockMovementRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.StockMovement, error) {
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var movements []*model.StockMovement
+	if err := cursor.All(ctx, &movements); err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+func (r *stockMovementRepository) Count(ctx context.Context, query bson.D) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
+
+type StockReservationRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, reservation *model.StockReservation) error
+	FindOne(ctx context.Context, query bson.M) (*model.StockReservation, error)
+	FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.StockReservation, error)
+	FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]*model.StockReservation, error)
+}
+
+type stockReservationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewStockReservationRepository(db *mongo.Database) StockReservationRepository {
+	return &stockReservationRepository{
+		collection: db.Collection("stock_reservations"),
+	}
+}
+
+// EnsureIndexes makes the lookup of expired reservations an index hit.
+func (r *stockReservationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
+
+func (r *stockReservationRepository) Create(ctx context.Context, reservation *model.StockReservation) error {
+	_, err := r.collection.InsertOne(ctx, reservation)
+	return err
+}
+
+func (r *stockReservationRepository) FindOne(ctx context.Context, query bson.M) (*model.StockReservation, error) {
+	var reservation model.StockReservation
+	if err := r.collection.FindOne(ctx, query).Decode(&reservation); err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// FindOneAndUpdate applies a raw update to the first reservation matching
+// query and returns the updated document, or mongo.ErrNoDocuments when
+// nothing matched.
+func (r *stockReservationRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.StockReservation, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var reservation model.StockReservation
+	if err := r.collection.FindOneAndUpdate(ctx, query, update, opts).Decode(&reservation); err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (r *stockReservationRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]*model.StockReservation, error) {
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []*model.StockReservation
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
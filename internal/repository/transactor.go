@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs work in a MongoDB transaction. Repositories called with the
+// context passed to fn take part in it. Transactions need a replica set.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+func NewTransactor(db *mongo.Database) Transactor {
+	return &mongoTransactor{
+		client: db.Client(),
+	}
+}
+
+// WithTransaction commits when fn succeeds and aborts when it fails. fn is
+// run again on transient errors, so it must not have effects outside the
+// database.
+func (t *mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
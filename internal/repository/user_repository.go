@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"example-go-project/internal/model"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,14 +12,20 @@ import (
 )
 
 type UserRepository interface {
+	EnsureIndexes(ctx context.Context) error
 	Create(ctx context.Context, user *model.User) error
 	Update(ctx context.Context, payload bson.M, id primitive.ObjectID) (*model.User, error)
+	FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.User, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	Purge(ctx context.Context, id primitive.ObjectID) error
 	FindOne(ctx context.Context, query bson.M) (*model.User, error)
 	FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]model.User, error)
 	Count(ctx context.Context, query bson.D) (int64, error)
 }
 
+// Soft-deleted users are left out of every query of userRepository that does
+// not name deleted_at itself. Restore and Purge only work on them.
 type userRepository struct {
 	collection *mongo.Collection
 }
@@ -29,6 +36,42 @@ func NewUserRepository(db *mongo.Database) UserRepository {
 	}
 }
 
+var notDeleted = bson.M{"$exists": false}
+
+func activeM(query bson.M) bson.M {
+	if _, ok := query["deleted_at"]; ok {
+		return query
+	}
+	active := make(bson.M, len(query)+1)
+	for k, v := range query {
+		active[k] = v
+	}
+	active["deleted_at"] = notDeleted
+	return active
+}
+
+func activeD(query bson.D) bson.D {
+	for _, e := range query {
+		if e.Key == "deleted_at" {
+			return query
+		}
+	}
+	active := make(bson.D, 0, len(query)+1)
+	active = append(active, query...)
+	return append(active, bson.E{Key: "deleted_at", Value: notDeleted})
+}
+
+// EnsureIndexes makes emails unique among active users. deleted_at is part of
+// the key so soft-deleted users do not hold on to their address: every active
+// user has it missing, while deleted users differ by their deletion time.
+func (r *userRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}, {Key: "deleted_at", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
 func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 	_, err := r.collection.InsertOne(ctx, user)
 	return err
@@ -36,7 +79,7 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 
 func (r *userRepository) FindOne(ctx context.Context, query bson.M) (*model.User, error) {
 	var user model.User
-	err := r.collection.FindOne(ctx, query).Decode(&user)
+	err := r.collection.FindOne(ctx, activeM(query)).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +92,7 @@ func (r *userRepository) Update(ctx context.Context, payload bson.M, id primitiv
 	var updatedUser model.User
 	err := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": id},
+		activeM(bson.M{"_id": id}),
 		bson.M{
 			"$set": payload,
 			"$currentDate": bson.M{
@@ -64,13 +107,66 @@ func (r *userRepository) Update(ctx context.Context, payload bson.M, id primitiv
 	return &updatedUser, nil
 }
 
+// FindOneAndUpdate applies a raw update to the first user matching query and
+// returns the updated document, or mongo.ErrNoDocuments when nothing matched.
+func (r *userRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.User, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if _, ok := update["$currentDate"]; !ok {
+		update["$currentDate"] = bson.M{"updated_at": true}
+	}
+
+	var updatedUser model.User
+	err := r.collection.FindOneAndUpdate(ctx, activeM(query), update, opts).Decode(&updatedUser)
+	if err != nil {
+		return nil, err
+	}
+	return &updatedUser, nil
+}
+
+// Delete soft-deletes the user by setting deleted_at.
 func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	res, err := r.collection.UpdateOne(ctx, activeM(bson.M{"_id": id}), bson.M{
+		"$set":         bson.M{"deleted_at": time.Now()},
+		"$currentDate": bson.M{"updated_at": true},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Restore brings back a soft-deleted user.
+func (r *userRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}}, bson.M{
+		"$unset":       bson.M{"deleted_at": ""},
+		"$currentDate": bson.M{"updated_at": true},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Purge removes a soft-deleted user for good.
+func (r *userRepository) Purge(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
 }
 
 func (r *userRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]model.User, error) {
-	cursor, err := r.collection.Find(ctx, query, opts)
+	cursor, err := r.collection.Find(ctx, activeD(query), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -85,5 +181,5 @@ func (r *userRepository) FindAll(ctx context.Context, query bson.D, opts *option
 }
 
 func (r *userRepository) Count(ctx context.Context, query bson.D) (int64, error) {
-	return r.collection.CountDocuments(ctx, query)
+	return r.collection.CountDocuments(ctx, activeD(query))
 }
@@ -2,6 +2,7 @@ package routers
 
 import (
 	_ "example-go-project/docs"
+	"example-go-project/internal/model"
 	"example-go-project/pkg/config"
 	"example-go-project/pkg/middleware"
 	"example-go-project/pkg/utils"
@@ -16,17 +17,40 @@ import (
 )
 
 type Application struct {
-	Router         *gin.Engine
-	helperHandler  *handlers.HealthHandler
-	UserHandler    *handlers.UserHandler
-	PingHandler    *handlers.PingHandler
-	ProductHandler *handlers.ProductHandler
-	UploadHandler  *handlers.UploadHandler
-	AuthMiddleware *middleware.AuthMiddleware
-	Config         *config.Config
+	Router                   *gin.Engine
+	helperHandler            *handlers.HealthHandler
+	UserHandler              *handlers.UserHandler
+	PingHandler              *handlers.PingHandler
+	ProductHandler           *handlers.ProductHandler
+	UploadHandler            *handlers.UploadHandler
+	SessionHandler           *handlers.SessionHandler
+	TwoFactorHandler         *handlers.TwoFactorHandler
+	PasswordHandler          *handlers.PasswordHandler
+	EmailVerificationHandler *handlers.EmailVerificationHandler
+	RoleHandler              *handlers.RoleHandler
+	APIKeyHandler            *handlers.APIKeyHandler
+	OIDCHandler              *handlers.OIDCHandler
+	ImpersonationHandler     *handlers.ImpersonationHandler
+	OrgHandler               *handlers.OrgHandler
+	InvitationHandler        *handlers.InvitationHandler
+	MagicLinkHandler         *handlers.MagicLinkHandler
+	AuditHandler             *handlers.AuditHandler
+	PrivacyHandler           *handlers.PrivacyHandler
+	EmailChangeHandler       *handlers.EmailChangeHandler
+	InventoryHandler         *handlers.InventoryHandler
+	OrderHandler             *handlers.OrderHandler
+	CartHandler              *handlers.CartHandler
+	CategoryHandler          *handlers.CategoryHandler
+	JWKSHandler              *handlers.JWKSHandler
+	AuthMiddleware           *middleware.AuthMiddleware
+	OrgMiddleware            *middleware.OrgMiddleware
+	Config                   *config.Config
 }
 
 func (app *Application) SetupRoutes() {
+	// Public signing keys for access token verification
+	app.Router.GET("/.well-known/jwks.json", app.JWKSHandler.JWKS)
+
 	// API version group
 	v1 := app.Router.Group("/api/v1")
 
@@ -44,46 +68,178 @@ func (app *Application) SetupRoutes() {
 		auth.Use(middleware.RateLimit(20, time.Minute))
 		{
 			auth.POST("/register", app.UserHandler.Register)
+			auth.POST("/invitations/accept", app.InvitationHandler.Accept)
 			auth.POST("/login", app.UserHandler.Login)
+			auth.POST("/login/2fa", app.TwoFactorHandler.Login)
+			auth.POST("/magic-link", app.MagicLinkHandler.Request)
+			auth.POST("/magic-link/verify", app.MagicLinkHandler.Verify)
 			auth.POST("/refresh", app.UserHandler.RefreshToken)
+			auth.POST("/switch-org", app.OrgHandler.SwitchOrg)
+			auth.POST("/password/forgot", app.PasswordHandler.ForgotPassword)
+			auth.POST("/password/reset", app.PasswordHandler.ResetPassword)
+			auth.POST("/verify-email", app.EmailVerificationHandler.Verify)
+			auth.POST("/email-change/confirm", app.EmailChangeHandler.Confirm)
+			// Unverified accounts must be able to ask for a new link
+			auth.POST("/verify-email/resend", app.AuthMiddleware.ProtectedUnverified(), app.AuthMiddleware.RequireScope(), app.EmailVerificationHandler.Resend)
+			auth.GET("/oidc/:provider/start", app.OIDCHandler.Start)
+			auth.GET("/oidc/:provider/callback", app.OIDCHandler.Callback)
 		}
 
 		ping := public.Group("/ping")
 		{
 			ping.POST("/", app.PingHandler.Ping)
 		}
+
+		// Anonymous carts are named by X-Cart-ID, signed in users get their own
+		cart := public.Group("/cart", app.AuthMiddleware.Optional(), app.AuthMiddleware.ImpersonationReadOnly(), app.AuthMiddleware.RequireScope(), app.OrgMiddleware.OrgContext())
+		{
+			cart.GET("", app.CartHandler.GetCart)
+			cart.POST("/items", app.CartHandler.AddItem)
+			cart.PUT("/items/:productId", app.CartHandler.UpdateItem)
+			cart.DELETE("/items/:productId", app.CartHandler.RemoveItem)
+		}
 	}
 
 	// Protected routes
 	protected := v1.Group("")
-	protected.Use(app.AuthMiddleware.Protected())
+	// The active organization scopes product and file queries. Impersonation
+	// tokens may only read
+	protected.Use(app.AuthMiddleware.Protected(), app.AuthMiddleware.ImpersonationReadOnly(), app.OrgMiddleware.OrgContext())
+	// Routes that check no permission of their own name the scopes a scoped
+	// API key needs, scope() without any keeps such keys out
+	scope := app.AuthMiddleware.RequireScope
 	{
 		// User routes
 		user := protected.Group("/user")
 		{
-			user.GET("/profile", app.UserHandler.GetProfile)
-			user.PUT("/profile/:id", app.UserHandler.UpdateProfile)
-			user.GET("/logout", app.UserHandler.Logout)
+			user.GET("/profile", scope(), app.UserHandler.GetProfile)
+			user.PUT("/profile/:id", app.AuthMiddleware.OwnerOrPermission(middleware.PathOwner("id"), utils.PermUserUpdate), app.UserHandler.UpdateProfile)
+			user.GET("/logout", scope(), app.UserHandler.Logout)
+
+			// Account security is managed by the person, not by API keys or
+			// support staff impersonating them
+			account := user.Group("", app.AuthMiddleware.DenyAPIKey(), app.AuthMiddleware.DenyImpersonation())
+			{
+				account.PUT("/password", app.PasswordHandler.ChangePassword)
+				account.PUT("/email", app.EmailChangeHandler.Request)
+				account.GET("/sessions", app.SessionHandler.GetSessions)
+				account.DELETE("/sessions", app.SessionHandler.RevokeAllSessions)
+				account.DELETE("/sessions/:id", app.SessionHandler.RevokeSession)
+				account.POST("/2fa/setup", app.TwoFactorHandler.Setup)
+				account.POST("/2fa/enable", app.TwoFactorHandler.Enable)
+				account.POST("/2fa/disable", app.TwoFactorHandler.Disable)
+				account.POST("/2fa/recovery-codes", app.TwoFactorHandler.RegenerateRecoveryCodes)
+				account.GET("/me/export", app.PrivacyHandler.Export)
+				account.POST("/me/erasure", app.PrivacyHandler.RequestErasure)
+				account.DELETE("/me/erasure", app.PrivacyHandler.CancelErasure)
+			}
 		}
 	}
 
-	adminProtected := protected.Group("")
-	// Admin routes
-	adminProtected.Use(app.AuthMiddleware.RequireRoles(utils.Role("admin")))
+	// Routes checked by permission, role definitions map roles to permissions
+	permissioned := protected.Group("")
+	permissioned.Use(app.AuthMiddleware.RequireTwoFactor())
 	{
-		adminProtected.POST("/local_upload", app.UploadHandler.UploadMultipleLocalFiles)
-		adminProtected.DELETE("/local_upload/:id", app.UploadHandler.DeleteFile)
-		adminProtected.GET("/local_upload", app.UploadHandler.GetFileAll)
+		perm := app.AuthMiddleware.RequirePermission
+
+		permissioned.POST("/local_upload", perm(utils.PermFileUpload), app.UploadHandler.UploadMultipleLocalFiles)
+		// Owners manage their own files, file:list and file:delete reach everyone's
+		permissioned.DELETE("/local_upload/:id", app.AuthMiddleware.OwnerOrPermission(app.UploadHandler.FileOwner, utils.PermFileDelete), app.UploadHandler.DeleteFile)
+		permissioned.GET("/local_upload", scope(utils.PermFileList), app.UploadHandler.GetFileAll)
+
+		admin := permissioned.Group("/user")
+		{
+			admin.DELETE("/:id", perm(utils.PermUserDelete), app.UserHandler.DeleteUser)
+			admin.POST("/:id/restore", perm(utils.PermUserDelete), app.UserHandler.RestoreUser)
+			admin.DELETE("/:id/purge", perm(utils.PermUserDelete), app.PrivacyHandler.PurgeUser)
+			admin.GET("/list", perm(utils.PermUserList), app.UserHandler.UserList)
+			admin.GET("/2fa/policy", perm(utils.PermTwoFactorPolicy), app.TwoFactorHandler.GetPolicy)
+			admin.PUT("/2fa/policy", perm(utils.PermTwoFactorPolicy), app.TwoFactorHandler.UpdatePolicy)
+			admin.POST("/:id/roles", perm(utils.PermUserRoles), app.RoleHandler.GrantRole)
+			admin.DELETE("/:id/roles", perm(utils.PermUserRoles), app.RoleHandler.RevokeRole)
+			admin.POST("/:id/unlock", perm(utils.PermUserUnlock), app.UserHandler.UnlockUser)
+			admin.POST("/:id/impersonate", app.AuthMiddleware.DenyAPIKey(), perm(utils.PermUserImpersonate), app.ImpersonationHandler.Impersonate)
+			admin.GET("/:id/sessions", perm(utils.PermUserSessions), app.SessionHandler.GetUserSessions)
+			admin.DELETE("/:id/sessions", perm(utils.PermUserSessions), app.SessionHandler.RevokeAllUserSessions)
+			admin.DELETE("/:id/sessions/:sessionId", perm(utils.PermUserSessions), app.SessionHandler.RevokeUserSession)
+
+			// Behind RequireTwoFactor, so keys of users who must use two-factor
+			// can only be created from a session that passed it
+			apiKeys := admin.Group("/api-keys", app.AuthMiddleware.DenyAPIKey(), app.AuthMiddleware.DenyImpersonation())
+			{
+				apiKeys.GET("", app.APIKeyHandler.GetAPIKeys)
+				apiKeys.POST("", app.APIKeyHandler.CreateAPIKey)
+				apiKeys.DELETE("/:id", app.AuthMiddleware.OwnerOrPermission(app.APIKeyHandler.APIKeyOwner, utils.PermAPIKeyManage), app.APIKeyHandler.RevokeAPIKey)
+			}
+		}
+		permissioned.GET("/api-keys", perm(utils.PermAPIKeyManage), app.APIKeyHandler.GetAllAPIKeys)
+		invitations := permissioned.Group("/invitations", perm(utils.PermUserInvite))
+		{
+			invitations.GET("", app.InvitationHandler.GetInvitations)
+			invitations.POST("", app.InvitationHandler.Invite)
+			invitations.DELETE("/:id", app.InvitationHandler.RevokeInvitation)
+		}
+		audit := permissioned.Group("/audit", perm(utils.PermAuditRead))
+		{
+			audit.GET("", app.AuditHandler.GetEvents)
+			audit.GET("/export", app.AuditHandler.Export)
+		}
+		roles := permissioned.Group("/roles", perm(utils.PermRoleManage))
+		{
+			roles.GET("", app.RoleHandler.ListRoles)
+			roles.POST("", app.RoleHandler.CreateRole)
+			roles.PUT("/:name", app.RoleHandler.UpdateRole)
+			roles.DELETE("/:name", app.RoleHandler.DeleteRole)
+		}
+		orgs := permissioned.Group("/orgs", scope())
+		{
+			orgRole := app.OrgMiddleware.RequireOrgRole
 
-		admin := adminProtected.Group("/user")
+			orgs.POST("", app.OrgHandler.CreateOrg)
+			orgs.GET("", app.OrgHandler.GetOrgs)
+			orgs.POST("/invitations/accept", app.OrgHandler.AcceptInvitation)
+			orgs.GET("/:orgId", orgRole(model.OrgRoleMember), app.OrgHandler.GetOrg)
+			orgs.PUT("/:orgId", orgRole(model.OrgRoleAdmin), app.OrgHandler.UpdateOrg)
+			orgs.DELETE("/:orgId", orgRole(model.OrgRoleOwner), app.OrgHandler.DeleteOrg)
+			orgs.GET("/:orgId/members", orgRole(model.OrgRoleMember), app.OrgHandler.GetMembers)
+			orgs.PUT("/:orgId/members/:userId", orgRole(model.OrgRoleAdmin), app.OrgHandler.UpdateMember)
+			// Members may remove themselves, the service checks the rest
+			orgs.DELETE("/:orgId/members/:userId", orgRole(model.OrgRoleMember), app.OrgHandler.RemoveMember)
+			orgs.POST("/:orgId/invitations", orgRole(model.OrgRoleAdmin), app.OrgHandler.Invite)
+			orgs.GET("/:orgId/invitations", orgRole(model.OrgRoleAdmin), app.OrgHandler.GetInvitations)
+			orgs.DELETE("/:orgId/invitations/:id", orgRole(model.OrgRoleAdmin), app.OrgHandler.RevokeInvitation)
+		}
+		product := permissioned.Group("/product")
+		{
+			product.POST("/", perm(utils.PermProductCreate), app.ProductHandler.CreateProduct)
+			// Scoped to the caller's products without product:list
+			product.GET("/", scope(utils.PermProductList), app.ProductHandler.GetProducts)
+			product.GET("/:id", scope(utils.PermProductList), app.ProductHandler.GetProduct)
+			product.PUT("/:id", app.AuthMiddleware.OwnerOrPermission(app.ProductHandler.ProductOwner, utils.PermProductUpdate), app.ProductHandler.UpdateProduct)
+			product.PATCH("/:id", app.AuthMiddleware.OwnerOrPermission(app.ProductHandler.ProductOwner, utils.PermProductUpdate), app.ProductHandler.PatchProduct)
+			product.DELETE("/:id", app.AuthMiddleware.OwnerOrPermission(app.ProductHandler.ProductOwner, utils.PermProductDelete), app.ProductHandler.DeleteProduct)
+			product.GET("/:id/stock/history", scope(utils.PermProductList), app.InventoryHandler.GetHistory)
+			product.POST("/:id/stock/movements", app.AuthMiddleware.OwnerOrPermission(app.ProductHandler.ProductOwner, utils.PermProductUpdate), app.InventoryHandler.RecordMovement)
+			product.POST("/:id/stock/reservations", scope(), app.InventoryHandler.Reserve)
+			product.DELETE("/:id/stock/reservations/:reservationId", scope(), app.InventoryHandler.Release)
+		}
+		categories := permissioned.Group("/categories")
 		{
-			admin.DELETE("/:id", app.UserHandler.DeleteUser)
-			admin.GET("/list", app.UserHandler.UserList)
+			categories.GET("", scope(utils.PermProductList), app.CategoryHandler.GetCategories)
+			categories.GET("/:id", scope(utils.PermProductList), app.CategoryHandler.GetCategory)
+			categories.POST("", perm(utils.PermCategoryManage), app.CategoryHandler.CreateCategory)
+			categories.PUT("/:id", perm(utils.PermCategoryManage), app.CategoryHandler.UpdateCategory)
+			categories.DELETE("/:id", perm(utils.PermCategoryManage), app.CategoryHandler.DeleteCategory)
 		}
-		product := adminProtected.Group("/product")
+		// Customers see their own orders, order:manage reaches everyone's
+		orders := permissioned.Group("/orders")
 		{
-			product.POST("/", app.ProductHandler.CreateProduct)
-			product.GET("/", app.ProductHandler.GetProducts)
+			orders.POST("", scope(), app.OrderHandler.CreateOrder)
+			orders.GET("", scope(utils.PermOrderManage), app.OrderHandler.GetOrders)
+			orders.GET("/:id", scope(utils.PermOrderManage), app.OrderHandler.GetOrder)
+			orders.POST("/:id/pay", scope(), app.OrderHandler.PayOrder)
+			orders.POST("/:id/cancel", scope(), app.OrderHandler.CancelOrder)
+			orders.PUT("/:id/status", perm(utils.PermOrderManage), app.OrderHandler.UpdateOrderStatus)
 		}
 	}
 
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/utils"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid or expired API key")
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+	ErrAPIKeyNotAllowed    = errors.New("API keys cannot manage API keys")
+	ErrServiceKeyForbidden = errors.New("creating service keys requires the apikey:manage permission")
+	ErrServiceKeyScopes    = errors.New("service keys need at least one scope")
+	ErrScopeNotHeld        = errors.New("a key cannot be given a permission its creator does not hold")
+)
+
+// Last use is written at most this often, not on every request
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService issues and checks API keys for machine clients. Only a hash
+// of each key is stored, the key itself is shown once when it is created.
+type APIKeyService struct {
+	apiKeyRepo        repository.APIKeyRepository
+	permissionService *PermissionService
+	auditService      *AuditService
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, permissionService *PermissionService, auditService *AuditService) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:        apiKeyRepo,
+		permissionService: permissionService,
+		auditService:      auditService,
+	}
+}
+
+// Create issues a key for the principal's user and returns it with the
+// plain key. The scopes must be permissions the creator holds.
+func (a *APIKeyService) Create(ctx context.Context, principal *model.Principal, req *dto.CreateAPIKeyRequest) (*model.APIKey, string, error) {
+	if principal.IsAPIKey() {
+		return nil, "", ErrAPIKeyNotAllowed
+	}
+
+	keyType := req.Type
+	if keyType == "" {
+		keyType = model.APIKeyPersonal
+	}
+
+	if err := validatePermissions(req.Scopes); err != nil {
+		return nil, "", err
+	}
+
+	scopes := make([]utils.Permission, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		scopes[i] = utils.Permission(scope)
+	}
+	held, err := a.permissionService.Allowed(ctx, principal, scopes...)
+	if err != nil {
+		return nil, "", err
+	}
+	if !held {
+		return nil, "", ErrScopeNotHeld
+	}
+
+	if keyType == model.APIKeyService {
+		if len(req.Scopes) == 0 {
+			return nil, "", ErrServiceKeyScopes
+		}
+		canManage, err := a.permissionService.Allowed(ctx, principal, utils.PermAPIKeyManage)
+		if err != nil {
+			return nil, "", err
+		}
+		if !canManage {
+			return nil, "", ErrServiceKeyForbidden
+		}
+	}
+
+	plain, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	key := &model.APIKey{
+		ID:        primitive.NewObjectID(),
+		Name:      req.Name,
+		Type:      keyType,
+		Prefix:    prefix,
+		Hash:      utils.HashToken(plain),
+		UserID:    principal.User.ID,
+		Scopes:    req.Scopes,
+		CreatedAt: now,
+	}
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := a.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	a.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditAPIKeyCreated,
+		ActorID:  principal.User.ID,
+		TargetID: key.ID,
+		Metadata: map[string]interface{}{"name": key.Name, "type": key.Type, "prefix": key.Prefix, "scopes": key.Scopes},
+	})
+	return key, plain, nil
+}
+
+// List returns the keys created by a user, or every key when userID is zero.
+func (a *APIKeyService) List(ctx context.Context, userID primitive.ObjectID) ([]*model.APIKey, error) {
+	query := bson.M{}
+	if !userID.IsZero() {
+		query["user_id"] = userID
+	}
+	return a.apiKeyRepo.FindAll(ctx, query)
+}
+
+func (a *APIKeyService) FindByID(ctx context.Context, id primitive.ObjectID) (*model.APIKey, error) {
+	key, err := a.apiKeyRepo.FindOne(ctx, bson.M{"_id": id})
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// Revoke disables a key for good. Revoked keys stay listed for reference.
+func (a *APIKeyService) Revoke(ctx context.Context, actor *model.User, id primitive.ObjectID) error {
+	key, err := a.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	if _, err := a.apiKeyRepo.Update(ctx, bson.M{"revoked_at": time.Now()}, id); err != nil {
+		return err
+	}
+
+	a.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditAPIKeyRevoked,
+		ActorID:  actor.ID,
+		TargetID: key.ID,
+		Metadata: map[string]interface{}{"name": key.Name, "prefix": key.Prefix},
+	})
+	return nil
+}
+
+// Authenticate returns the active key matching a plain key and records
+// where it was used.
+func (a *APIKeyService) Authenticate(ctx context.Context, plain, ip string) (*model.APIKey, error) {
+	if !strings.HasPrefix(plain, utils.APIKeyPrefix+"_") {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := a.apiKeyRepo.FindOne(ctx, bson.M{"hash": utils.HashToken(plain)})
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval || key.LastUsedIP != ip {
+		if _, err := a.apiKeyRepo.Update(ctx, bson.M{"last_used_at": now, "last_used_ip": ip}, key.ID); err != nil {
+			log.Printf("Failed to record use of API key %s: %v", key.Prefix, err)
+		}
+		key.LastUsedAt = &now
+		key.LastUsedIP = ip
+	}
+	return key, nil
+}
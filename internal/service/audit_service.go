@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"log"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidAuditFilter = errors.New("invalid audit filter")
+
+// Fields whose values never reach the audit log, a change only shows as redacted
+var auditRedactedFields = map[string]bool{
+	"password":       true,
+	"totp_secret":    true,
+	"recovery_codes": true,
+	"hash":           true,
+}
+
+const auditRedacted = "[redacted]"
+
+// AuditRequest describes the request behind audited changes. The RequestID
+// middleware and Protected put it in the request context, so services only
+// name the action and target.
+type AuditRequest struct {
+	ActorID        primitive.ObjectID
+	ImpersonatedID primitive.ObjectID
+	APIKeyID       primitive.ObjectID
+	IP             string
+	UserAgent      string
+	RequestID      string
+}
+
+type auditRequestKey struct{}
+
+// WithAuditRequest returns a context whose audit events are attributed to req.
+func WithAuditRequest(ctx context.Context, req AuditRequest) context.Context {
+	return context.WithValue(ctx, auditRequestKey{}, req)
+}
+
+// WithAuditPrincipal adds the authenticated principal to the audit request
+// of the context. While impersonating, the impersonator is the actor and the
+// impersonated user is kept in the metadata.
+func WithAuditPrincipal(ctx context.Context, principal *model.Principal) context.Context {
+	req, _ := AuditRequestFromContext(ctx)
+	req.ActorID = principal.User.ID
+	if principal.Impersonator != nil {
+		req.ActorID = principal.Impersonator.ID
+		req.ImpersonatedID = principal.User.ID
+	}
+	if principal.APIKey != nil {
+		req.APIKeyID = principal.APIKey.ID
+	}
+	return WithAuditRequest(ctx, req)
+}
+
+func AuditRequestFromContext(ctx context.Context) (AuditRequest, bool) {
+	req, ok := ctx.Value(auditRequestKey{}).(AuditRequest)
+	return req, ok
+}
+
+// AuditService records security relevant changes in the audit_events collection.
+type AuditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// Record stores an audit event. Fields left empty are taken from the audit
+// request of the context. A failed write is logged rather than returned, the
+// audited change has already happened by the time it is recorded.
+func (a *AuditService) Record(ctx context.Context, event *model.AuditEvent) {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+
+	if req, ok := AuditRequestFromContext(ctx); ok {
+		if event.ActorID.IsZero() {
+			event.ActorID = req.ActorID
+		}
+		if event.IP == "" {
+			event.IP = req.IP
+		}
+		if event.UserAgent == "" {
+			event.UserAgent = req.UserAgent
+		}
+		if event.RequestID == "" {
+			event.RequestID = req.RequestID
+		}
+		if !req.ImpersonatedID.IsZero() {
+			event.Metadata = withMetadata(event.Metadata, "impersonated_user_id", req.ImpersonatedID.Hex())
+		}
+		if !req.APIKeyID.IsZero() {
+			event.Metadata = withMetadata(event.Metadata, "api_key_id", req.APIKeyID.Hex())
+		}
+	}
+
+	if err := a.auditRepo.Create(ctx, event); err != nil {
+		log.Printf("audit: failed to record %s by %s on %s: %v", event.Action, event.ActorID.Hex(), event.TargetID.Hex(), err)
+	}
+}
+
+func withMetadata(metadata map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	if _, ok := metadata[key]; !ok {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// AuditDiff returns the fields that differ between two versions of a
+// document, as stored in the before and after of an event. Either side may be
+// nil for a created or deleted document. Secrets are redacted.
+func AuditDiff(before, after interface{}) (map[string]interface{}, map[string]interface{}) {
+	beforeDoc, afterDoc := auditDocument(before), auditDocument(after)
+
+	changedBefore, changedAfter := map[string]interface{}{}, map[string]interface{}{}
+	for key, value := range beforeDoc {
+		if other, ok := afterDoc[key]; !ok || !reflect.DeepEqual(value, other) {
+			changedBefore[key] = auditValue(key, value)
+		}
+	}
+	for key, value := range afterDoc {
+		if other, ok := beforeDoc[key]; !ok || !reflect.DeepEqual(value, other) {
+			changedAfter[key] = auditValue(key, value)
+		}
+	}
+
+	if len(changedBefore) == 0 {
+		changedBefore = nil
+	}
+	if len(changedAfter) == 0 {
+		changedAfter = nil
+	}
+	return changedBefore, changedAfter
+}
+
+func auditDocument(value interface{}) bson.M {
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil() {
+		return nil
+	}
+	data, err := bson.Marshal(value)
+	if err != nil {
+		log.Printf("audit: failed to encode %T: %v", value, err)
+		return nil
+	}
+	var document bson.M
+	if err := bson.Unmarshal(data, &document); err != nil {
+		log.Printf("audit: failed to decode %T: %v", value, err)
+		return nil
+	}
+	return document
+}
+
+func auditValue(key string, value interface{}) interface{} {
+	if auditRedactedFields[key] {
+		return auditRedacted
+	}
+	return value
+}
+
+// auditQuery turns the filter into a query, ids must be valid hex.
+func auditQuery(filter dto.AuditFilter) (bson.D, error) {
+	query := bson.D{}
+	for key, value := range map[string]string{"actor_id": filter.ActorID, "target_id": filter.TargetID} {
+		if value == "" {
+			continue
+		}
+		objectID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, ErrInvalidAuditFilter
+		}
+		query = append(query, bson.E{Key: key, Value: objectID})
+	}
+	if filter.Action != "" {
+		query = append(query, bson.E{Key: "action", Value: filter.Action})
+	}
+
+	createdAt := bson.D{}
+	if filter.From != nil {
+		createdAt = append(createdAt, bson.E{Key: "$gte", Value: *filter.From})
+	}
+	if filter.To != nil {
+		createdAt = append(createdAt, bson.E{Key: "$lt", Value: *filter.To})
+	}
+	if len(createdAt) > 0 {
+		query = append(query, bson.E{Key: "created_at", Value: createdAt})
+	}
+	return query, nil
+}
+
+// FindAll lists a page of events, newest first.
+func (a *AuditService) FindAll(ctx context.Context, filter dto.AuditFilter, page, pageSize int) ([]*model.AuditEvent, int64, error) {
+	query, err := auditQuery(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := a.auditRepo.Count(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	events, err := a.auditRepo.FindAll(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// Export calls fn for every matching event, oldest first.
+func (a *AuditService) Export(ctx context.Context, filter dto.AuditFilter, fn func(*model.AuditEvent) error) error {
+	query, err := auditQuery(filter)
+	if err != nil {
+		return err
+	}
+	return a.auditRepo.Each(ctx, query, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}), fn)
+}
+
+// ForUser calls fn for every event the user took part in as actor or
+// target, oldest first.
+func (a *AuditService) ForUser(ctx context.Context, userID primitive.ObjectID, fn func(*model.AuditEvent) error) error {
+	query := bson.D{{Key: "$or", Value: bson.A{
+		bson.M{"actor_id": userID},
+		bson.M{"target_id": userID},
+	}}}
+	return a.auditRepo.Each(ctx, query, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}), fn)
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrCartItemNotFound = errors.New("product is not in the cart")
+	ErrCartFull         = errors.New("cart is full")
+)
+
+const (
+	// Lines in one cart, the most an order takes
+	maxCartItems = 50
+	// Quantity of one line
+	maxCartQuantity = 1000
+)
+
+// CartRef names a cart: the cart of the user when UserID is set, otherwise
+// the anonymous cart with CartID, if any.
+type CartRef struct {
+	UserID primitive.ObjectID
+	CartID string
+}
+
+func (r CartRef) anonymous() bool {
+	return r.UserID.IsZero()
+}
+
+// CartService keeps carts of products before checkout. Carts of signed in
+// users are stored in MongoDB, anonymous carts in Redis until CART_TTL
+// passes without a change. An anonymous cart is merged into the user's cart
+// once they sign in.
+type CartService struct {
+	cartRepo    repository.CartRepository
+	productRepo repository.ProductRepository
+	redisClient *redis.Client
+	config      *config.Config
+}
+
+func NewCartService(cartRepo repository.CartRepository, productRepo repository.ProductRepository, redisClient *redis.Client, config *config.Config) *CartService {
+	return &CartService{
+		cartRepo:    cartRepo,
+		productRepo: productRepo,
+		redisClient: redisClient,
+		config:      config,
+	}
+}
+
+func cartKey(cartID string) string {
+	return "cart:" + utils.HashToken(cartID)
+}
+
+// load returns the items of the cart, none for a cart that does not exist
+// (anymore).
+func (s *CartService) load(ctx context.Context, ref CartRef) ([]model.CartItem, error) {
+	if !ref.anonymous() {
+		cart, err := s.cartRepo.FindOne(ctx, bson.M{"user_id": ref.UserID})
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return cart.Items, nil
+	}
+
+	if ref.CartID == "" {
+		return nil, nil
+	}
+	raw, err := s.redisClient.Get(ctx, cartKey(ref.CartID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []model.CartItem
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// save stores the items of the cart. A new anonymous cart gets an ID, which
+// is returned in the ref.
+func (s *CartService) save(ctx context.Context, ref CartRef, items []model.CartItem) (CartRef, error) {
+	if !ref.anonymous() {
+		return ref, s.cartRepo.Save(ctx, &model.Cart{UserID: ref.UserID, Items: items, UpdatedAt: time.Now()})
+	}
+
+	if ref.CartID == "" {
+		cartID, err := utils.GenerateRandomToken(32)
+		if err != nil {
+			return ref, err
+		}
+		ref.CartID = cartID
+	}
+	payload, err := json.Marshal(items)
+	if err != nil {
+		return ref, err
+	}
+	return ref, s.redisClient.Set(ctx, cartKey(ref.CartID), payload, s.config.CartTTL).Err()
+}
+
+// Get returns the cart priced against the products as they are now.
+func (s *CartService) Get(ctx context.Context, ref CartRef) (*model.PricedCart, error) {
+	items, err := s.load(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.price(ctx, ref, items)
+}
+
+// AddItem adds quantity of the product in the scope of ctx to the cart.
+// Adding a product already in the cart raises its quantity and takes the
+// current price. Both fail with ErrInsufficientStock when not enough stock is
+// free.
+func (s *CartService) AddItem(ctx context.Context, ref CartRef, productID primitive.ObjectID, quantity int) (*model.PricedCart, error) {
+	items, err := s.load(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	index := cartItemIndex(items, productID)
+	if index < 0 {
+		if len(items) >= maxCartItems {
+			return nil, ErrCartFull
+		}
+		items = append(items, model.CartItem{ProductID: productID, AddedAt: time.Now()})
+		index = len(items) - 1
+	}
+	return s.setQuantity(ctx, ref, items, index, items[index].Quantity+quantity)
+}
+
+// UpdateItem sets the quantity of a product in the cart and takes its
+// current price.
+func (s *CartService) UpdateItem(ctx context.Context, ref CartRef, productID primitive.ObjectID, quantity int) (*model.PricedCart, error) {
+	items, err := s.load(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	index := cartItemIndex(items, productID)
+	if index < 0 {
+		return nil, ErrCartItemNotFound
+	}
+	return s.setQuantity(ctx, ref, items, index, quantity)
+}
+
+func (s *CartService) setQuantity(ctx context.Context, ref CartRef, items []model.CartItem, index, quantity int) (*model.PricedCart, error) {
+	if quantity > maxCartQuantity {
+		return nil, ErrCartFull
+	}
+
+	// Items already in the cart are looked up where they were added from,
+	// new ones in the scope of ctx
+	item := &items[index]
+	scoped := ctx
+	if item.Quantity > 0 {
+		scoped = orgScope(ctx, item.OrgID)
+	}
+	product, err := s.productRepo.FindOne(scoped, bson.M{"_id": item.ProductID})
+	if err != nil {
+		return nil, err
+	}
+	if product.Stock-product.Reserved < quantity {
+		return nil, ErrInsufficientStock
+	}
+
+	item.OrgID = product.OrgID
+	item.Quantity = quantity
+	item.Price = product.Price
+
+	if ref, err = s.save(ctx, ref, items); err != nil {
+		return nil, err
+	}
+	return s.price(ctx, ref, items)
+}
+
+// RemoveItem takes the product out of the cart.
+func (s *CartService) RemoveItem(ctx context.Context, ref CartRef, productID primitive.ObjectID) (*model.PricedCart, error) {
+	items, err := s.load(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	index := cartItemIndex(items, productID)
+	if index < 0 {
+		return nil, ErrCartItemNotFound
+	}
+	items = append(items[:index], items[index+1:]...)
+
+	if ref, err = s.save(ctx, ref, items); err != nil {
+		return nil, err
+	}
+	return s.price(ctx, ref, items)
+}
+
+// Merge moves the items of the anonymous cart into the user's cart and
+// deletes the anonymous cart. Quantities of products in both carts add up,
+// within the limits of a cart.
+func (s *CartService) Merge(ctx context.Context, userID primitive.ObjectID, cartID string) error {
+	anonymous, err := s.load(ctx, CartRef{CartID: cartID})
+	if err != nil {
+		return err
+	}
+	if len(anonymous) == 0 {
+		return nil
+	}
+
+	ref := CartRef{UserID: userID}
+	items, err := s.load(ctx, ref)
+	if err != nil {
+		return err
+	}
+	for _, item := range anonymous {
+		index := cartItemIndex(items, item.ProductID)
+		if index >= 0 {
+			items[index].Quantity = min(items[index].Quantity+item.Quantity, maxCartQuantity)
+			continue
+		}
+		if len(items) < maxCartItems {
+			items = append(items, item)
+		}
+	}
+
+	if _, err := s.save(ctx, ref, items); err != nil {
+		return err
+	}
+	return s.redisClient.Del(ctx, cartKey(cartID)).Err()
+}
+
+// price looks up every product of the cart and flags the lines whose price
+// changed since they were added, that have not enough stock or whose product
+// is gone.
+func (s *CartService) price(ctx context.Context, ref CartRef, items []model.CartItem) (*model.PricedCart, error) {
+	cart := &model.PricedCart{Items: []model.CartLine{}}
+	if ref.anonymous() {
+		cart.CartID = ref.CartID
+	}
+
+	for _, item := range items {
+		line := model.CartLine{
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			Price:      item.Price,
+			AddedPrice: item.Price,
+		}
+
+		product, err := s.productRepo.FindOne(orgScope(ctx, item.OrgID), bson.M{"_id": item.ProductID})
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			line.Unavailable = true
+		case err != nil:
+			return nil, err
+		default:
+			line.Name = product.Name
+			line.Price = product.Price
+			line.Available = max(product.Stock-product.Reserved, 0)
+			line.PriceChanged = product.Price != item.Price
+			line.OutOfStock = line.Available < item.Quantity
+		}
+
+		line.Subtotal = roundPrice(line.Price * float64(line.Quantity))
+		if !line.Unavailable && !line.OutOfStock {
+			cart.Total = roundPrice(cart.Total + line.Subtotal)
+		}
+		cart.Items = append(cart.Items, line)
+	}
+	return cart, nil
+}
+
+func cartItemIndex(items []model.CartItem, productID primitive.ObjectID) int {
+	for i, item := range items {
+		if item.ProductID == productID {
+			return i
+		}
+	}
+	return -1
+}
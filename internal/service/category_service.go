@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrUnknownCategory     = errors.New("category does not exist")
+	ErrInvalidCategory     = errors.New("category cannot be placed under itself or one of its descendants")
+	ErrCategoryExists      = errors.New("a category with this name already exists under the parent")
+	ErrCategoryHasChildren = errors.New("category has subcategories, move or delete them first")
+	ErrCategoryInUse       = errors.New("category has products, reassign them to another category")
+)
+
+// CategoryService manages the category tree of products. Each category keeps
+// the IDs of its ancestors, so a subtree is found with a single query and a
+// move rewrites the ancestors of the moved subtree.
+type CategoryService struct {
+	categoryRepo repository.CategoryRepository
+	productRepo  repository.ProductRepository
+	auditService *AuditService
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepository, productRepo repository.ProductRepository, auditService *AuditService) *CategoryService {
+	return &CategoryService{
+		categoryRepo: categoryRepo,
+		productRepo:  productRepo,
+		auditService: auditService,
+	}
+}
+
+// Create adds a category under the parent, at the top of the tree without
+// one.
+func (s *CategoryService) Create(ctx context.Context, name string, parentID *primitive.ObjectID) (*model.Category, error) {
+	ancestors, err := s.ancestorsUnder(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	category := &model.Category{
+		ID:        primitive.NewObjectID(),
+		Name:      strings.TrimSpace(name),
+		ParentID:  parentID,
+		Ancestors: ancestors,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.categoryRepo.Create(ctx, category); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+
+	event := &model.AuditEvent{Action: model.AuditCategoryCreated, TargetID: category.ID}
+	_, event.After = AuditDiff(nil, category)
+	s.auditService.Record(ctx, event)
+	return category, nil
+}
+
+func (s *CategoryService) FindAll(ctx context.Context) ([]*model.Category, error) {
+	return s.categoryRepo.FindAll(ctx, bson.D{})
+}
+
+func (s *CategoryService) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
+	return s.categoryRepo.FindOne(ctx, bson.M{"_id": id})
+}
+
+// Update renames the category and moves it under the parent, the top of the
+// tree without one. Moving a category carries its subtree along, it fails
+// with ErrInvalidCategory when the parent is the category or below it.
+func (s *CategoryService) Update(ctx context.Context, id primitive.ObjectID, name string, parentID *primitive.ObjectID) (*model.Category, error) {
+	before, err := s.categoryRepo.FindOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.ancestorsUnder(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor == id {
+			return nil, ErrInvalidCategory
+		}
+	}
+
+	set := bson.M{
+		"name":       strings.TrimSpace(name),
+		"ancestors":  ancestors,
+		"updated_at": time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if parentID != nil {
+		set["parent_id"] = *parentID
+	} else {
+		update["$unset"] = bson.M{"parent_id": ""}
+	}
+	after, err := s.categoryRepo.FindOneAndUpdate(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+
+	if !sameIDs(before.Ancestors, ancestors) {
+		if err := s.moveDescendants(ctx, after); err != nil {
+			return nil, err
+		}
+	}
+
+	event := &model.AuditEvent{Action: model.AuditCategoryUpdated, TargetID: id}
+	event.Before, event.After = AuditDiff(before, after)
+	s.auditService.Record(ctx, event)
+	return after, nil
+}
+
+// moveDescendants rewrites the ancestors of every category below the moved
+// one: its new ancestors followed by the part of the path below it.
+func (s *CategoryService) moveDescendants(ctx context.Context, moved *model.Category) error {
+	descendants, err := s.categoryRepo.FindAll(ctx, bson.D{{Key: "ancestors", Value: moved.ID}})
+	if err != nil {
+		return err
+	}
+
+	prefix := append(append([]primitive.ObjectID{}, moved.Ancestors...), moved.ID)
+	for _, descendant := range descendants {
+		below := descendant.Ancestors
+		for i, ancestor := range below {
+			if ancestor == moved.ID {
+				below = below[i+1:]
+				break
+			}
+		}
+		ancestors := append(append([]primitive.ObjectID{}, prefix...), below...)
+		if _, err := s.categoryRepo.FindOneAndUpdate(ctx, bson.M{"_id": descendant.ID}, bson.M{
+			"$set": bson.M{"ancestors": ancestors, "updated_at": time.Now()},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a category without subcategories. Its products move to the
+// category reassignTo, without one the delete fails with ErrCategoryInUse
+// while the category has products.
+func (s *CategoryService) Delete(ctx context.Context, id primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	before, err := s.categoryRepo.FindOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	children, err := s.categoryRepo.Count(ctx, bson.D{{Key: "parent_id", Value: id}})
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrCategoryHasChildren
+	}
+
+	inCategory := bson.D{{Key: "category_id", Value: id}}
+	products, err := s.productRepo.Count(ctx, inCategory)
+	if err != nil {
+		return err
+	}
+	var reassigned int64
+	if products > 0 {
+		if reassignTo == nil {
+			return ErrCategoryInUse
+		}
+		if *reassignTo == id {
+			return ErrInvalidCategory
+		}
+		if _, err := s.categoryRepo.FindOne(ctx, bson.M{"_id": *reassignTo}); err != nil {
+			return err
+		}
+		reassigned, err = s.productRepo.UpdateMany(ctx, inCategory, bson.M{
+			"$set": bson.M{"category_id": *reassignTo, "updated_at": time.Now()},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.categoryRepo.Delete(ctx, bson.M{"_id": id}); err != nil {
+		return err
+	}
+
+	event := &model.AuditEvent{Action: model.AuditCategoryDeleted, TargetID: id}
+	event.Before, _ = AuditDiff(before, nil)
+	if reassigned > 0 {
+		event.Metadata = map[string]interface{}{"reassigned_to": reassignTo.Hex(), "products": reassigned}
+	}
+	s.auditService.Record(ctx, event)
+	return nil
+}
+
+// Subtree returns the ID of the category and of every category below it.
+func (s *CategoryService) Subtree(ctx context.Context, id primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if _, err := s.categoryRepo.FindOne(ctx, bson.M{"_id": id}); err != nil {
+		return nil, err
+	}
+
+	descendants, err := s.categoryRepo.FindAll(ctx, bson.D{{Key: "ancestors", Value: id}})
+	if err != nil {
+		return nil, err
+	}
+	ids := []primitive.ObjectID{id}
+	for _, descendant := range descendants {
+		ids = append(ids, descendant.ID)
+	}
+	return ids, nil
+}
+
+// ancestorsUnder returns the ancestors of a category placed under the
+// parent, none at the top of the tree.
+func (s *CategoryService) ancestorsUnder(ctx context.Context, parentID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if parentID == nil {
+		return []primitive.ObjectID{}, nil
+	}
+	parent, err := s.categoryRepo.FindOne(ctx, bson.M{"_id": *parentID})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]primitive.ObjectID{}, parent.Ancestors...), parent.ID), nil
+}
+
+// NormalizeTags trims and lower cases tags and drops empty and repeated ones,
+// keeping their order.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func sameIDs(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidEmailChange = errors.New("invalid or expired email change link")
+	ErrEmailUnchanged     = errors.New("new email is the current email")
+)
+
+// EmailChangeService moves an account to a new email address. The new
+// address must be confirmed through a link mailed to it before the account
+// changes, and the old address is told about the request. Like verification
+// links, only the latest link sent to a user works and it expires after
+// EMAIL_VERIFICATION_TTL.
+type EmailChangeService struct {
+	userRepo     repository.UserRepository
+	redisClient  *redis.Client
+	tokenService *TokenService
+	auditService *AuditService
+	mailService  MailService
+	config       *config.Config
+}
+
+func NewEmailChangeService(userRepo repository.UserRepository, redisClient *redis.Client, tokenService *TokenService, auditService *AuditService, mailService MailService, config *config.Config) *EmailChangeService {
+	return &EmailChangeService{
+		userRepo:     userRepo,
+		redisClient:  redisClient,
+		tokenService: tokenService,
+		auditService: auditService,
+		mailService:  mailService,
+		config:       config,
+	}
+}
+
+func emailChangeKey(hash string) string {
+	return "email_change:" + hash
+}
+
+func userEmailChangeKey(userID string) string {
+	return "email_change_user:" + userID
+}
+
+// Request mails a confirmation link to the new address and a notice to the
+// current one. Accounts with a password must confirm it.
+func (e *EmailChangeService) Request(ctx context.Context, user *model.User, email, password string) error {
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return ErrInvalidCredentials
+		}
+	}
+
+	email = strings.TrimSpace(email)
+	if email == user.Email {
+		return ErrEmailUnchanged
+	}
+	if _, err := e.userRepo.FindOne(ctx, bson.M{"email": email}); err == nil {
+		return ErrEmailExists
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	hash := utils.HashToken(token)
+
+	previous, err := e.redisClient.Get(ctx, userEmailChangeKey(user.ID.Hex())).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	ttl := e.config.EmailVerificationTTL
+	pipe := e.redisClient.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, emailChangeKey(previous))
+	}
+	pipe.HSet(ctx, emailChangeKey(hash),
+		"user_id", user.ID.Hex(),
+		"old_email", user.Email,
+		"email", email,
+	)
+	pipe.Expire(ctx, emailChangeKey(hash), ttl)
+	pipe.Set(ctx, userEmailChangeKey(user.ID.Hex()), hash, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/confirm-email?token=%s", e.config.AppURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm that you want to use this address for your account with the link below. It expires in %s.\n\n%s",
+		user.Name, ttl, link)
+	if err := e.mailService.Send(ctx, email, "Confirm your new email", body); err != nil {
+		return err
+	}
+
+	notice := fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email of your account to %s. The change happens once the new address is confirmed.\n\nIf you did not ask for this, change your password.",
+		user.Name, email)
+	if err := e.mailService.Send(ctx, user.Email, "Your email is being changed", notice); err != nil {
+		log.Printf("Failed to send email change notice to user %s: %v", user.ID.Hex(), err)
+	}
+
+	e.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditEmailChangeRequested,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]interface{}{"email": email},
+	})
+	return nil
+}
+
+// Confirm consumes a confirmation link and moves the account to the new
+// address, which counts as verified. The user's sessions are revoked, they
+// sign in again with the new address. The change fails with ErrEmailExists
+// when the address was taken after the link was sent.
+func (e *EmailChangeService) Confirm(ctx context.Context, token string) error {
+	key := emailChangeKey(utils.HashToken(token))
+	pipe := e.redisClient.TxPipeline()
+	get := pipe.HGetAll(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	values := get.Val()
+	if len(values) == 0 {
+		return ErrInvalidEmailChange
+	}
+	e.redisClient.Del(ctx, userEmailChangeKey(values["user_id"]))
+
+	userID, err := primitive.ObjectIDFromHex(values["user_id"])
+	if err != nil {
+		return ErrInvalidEmailChange
+	}
+
+	// The current email is part of the query, a link for an address the
+	// account has already left behind does nothing
+	user, err := e.userRepo.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "email": values["old_email"]},
+		bson.M{"$set": bson.M{
+			"email":             values["email"],
+			"email_verified":    true,
+			"email_verified_at": time.Now(),
+		}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidEmailChange
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := e.tokenService.RevokeAllSessions(ctx, user.ID.Hex()); err != nil {
+		return err
+	}
+
+	e.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditEmailChanged,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Before:   map[string]interface{}{"email": values["old_email"]},
+		After:    map[string]interface{}{"email": user.Email},
+	})
+	return nil
+}
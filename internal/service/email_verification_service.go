@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+)
+
+// EmailVerificationService mails verification links and marks accounts as
+// verified. Like reset tokens, verification tokens are stored only as hashes
+// and only the latest one sent to a user works.
+type EmailVerificationService struct {
+	userRepo    repository.UserRepository
+	redisClient *redis.Client
+	mailService MailService
+	config      *config.Config
+}
+
+func NewEmailVerificationService(userRepo repository.UserRepository, redisClient *redis.Client, mailService MailService, config *config.Config) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:    userRepo,
+		redisClient: redisClient,
+		mailService: mailService,
+		config:      config,
+	}
+}
+
+func verificationTokenKey(hash string) string {
+	return "email_verify:" + hash
+}
+
+func userVerificationTokenKey(userID string) string {
+	return "email_verify_user:" + userID
+}
+
+// SendVerification mails a new verification link to the user.
+func (e *EmailVerificationService) SendVerification(ctx context.Context, user *model.User) error {
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	hash := utils.HashToken(token)
+
+	previous, err := e.redisClient.Get(ctx, userVerificationTokenKey(user.ID.Hex())).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := e.redisClient.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, verificationTokenKey(previous))
+	}
+	pipe.Set(ctx, verificationTokenKey(hash), user.ID.Hex(), e.config.EmailVerificationTTL)
+	pipe.Set(ctx, userVerificationTokenKey(user.ID.Hex()), hash, e.config.EmailVerificationTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", e.config.AppURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address with the link below. It expires in %s.\n\n%s",
+		user.Name, e.config.EmailVerificationTTL, link)
+
+	return e.mailService.Send(ctx, user.Email, "Verify your email", body)
+}
+
+// Verify consumes a verification token and marks the account as verified.
+func (e *EmailVerificationService) Verify(ctx context.Context, token string) error {
+	userID, err := e.redisClient.GetDel(ctx, verificationTokenKey(utils.HashToken(token))).Result()
+	if err == redis.Nil {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+	e.redisClient.Del(ctx, userVerificationTokenKey(userID))
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	_, err = e.userRepo.Update(ctx, bson.M{
+		"email_verified":    true,
+		"email_verified_at": time.Now(),
+	}, objectID)
+	return err
+}
@@ -13,11 +13,13 @@ import (
 
 type FileService struct {
 	fileStoreRepo repository.LocalFileRepository
+	auditService  *AuditService
 }
 
-func NewFileService(fileStoreRepo repository.LocalFileRepository) *FileService {
+func NewFileService(fileStoreRepo repository.LocalFileRepository, auditService *AuditService) *FileService {
 	return &FileService{
 		fileStoreRepo: fileStoreRepo,
+		auditService:  auditService,
 	}
 }
 
@@ -26,6 +28,12 @@ func (f *FileService) UploadFile(ctx context.Context, files []*multipart.FileHea
 	if err != nil {
 		return nil, err
 	}
+
+	for _, file := range res {
+		event := &model.AuditEvent{Action: model.AuditFileUploaded, TargetID: file.ID}
+		_, event.After = AuditDiff(nil, file)
+		f.auditService.Record(ctx, event)
+	}
 	return res, nil
 }
 
@@ -35,7 +43,19 @@ func (f *FileService) DeleteFile(ctx context.Context, id string) error {
 		return nil
 	}
 
-	return f.fileStoreRepo.Delete(ctx, objectID)
+	file, err := f.fileStoreRepo.FindOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if err := f.fileStoreRepo.Delete(ctx, objectID); err != nil {
+		return err
+	}
+
+	event := &model.AuditEvent{Action: model.AuditFileDeleted, TargetID: file.ID}
+	event.Before, _ = AuditDiff(file, nil)
+	f.auditService.Record(ctx, event)
+	return nil
 }
 
 func (f *FileService) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.FileStorage, error) {
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInsufficientStock    = errors.New("not enough stock available")
+	ErrInvalidStockMovement = errors.New("invalid stock movement")
+	ErrReservationNotFound  = errors.New("reservation not found or no longer active")
+	ErrReservationLimit     = errors.New("reservation limit reached for this product")
+)
+
+const (
+	// How often the expiry worker releases reservations past their expiry
+	reservationExpiryInterval = time.Minute
+	// Expired reservations released by one run of the expiry worker
+	reservationExpiryBatchSize = 100
+)
+
+// Stock of a product not held by reservations, products created before
+// reservations have no reserved field
+var availableStock = bson.M{"$subtract": bson.A{"$stock", bson.M{"$ifNull": bson.A{"$reserved", 0}}}}
+
+// InventoryService moves product stock. Every change of Product.Stock is an
+// atomic conditional update followed by an entry in the stock_movements
+// ledger, so stock never goes below what reservations hold and its history
+// can be replayed. Reservations hold stock for STOCK_RESERVATION_TTL.
+type InventoryService struct {
+	productRepo     repository.ProductRepository
+	movementRepo    repository.StockMovementRepository
+	reservationRepo repository.StockReservationRepository
+	config          *config.Config
+}
+
+func NewInventoryService(productRepo repository.ProductRepository, movementRepo repository.StockMovementRepository, reservationRepo repository.StockReservationRepository, config *config.Config) *InventoryService {
+	return &InventoryService{
+		productRepo:     productRepo,
+		movementRepo:    movementRepo,
+		reservationRepo: reservationRepo,
+		config:          config,
+	}
+}
+
+// movementDelta turns the quantity of a movement into the change of stock.
+// Receipts, sales and returns take a positive quantity, adjustments a signed
+// one.
+func movementDelta(kind string, quantity int) (int, error) {
+	switch kind {
+	case model.StockReceipt, model.StockReturn:
+		if quantity > 0 {
+			return quantity, nil
+		}
+	case model.StockSale:
+		if quantity > 0 {
+			return -quantity, nil
+		}
+	case model.StockAdjustment:
+		if quantity != 0 {
+			return quantity, nil
+		}
+	}
+	return 0, ErrInvalidStockMovement
+}
+
+// Record applies a movement to the product in the scope of ctx. A decrease
+// beyond the stock not held by reservations fails with ErrInsufficientStock.
+func (s *InventoryService) Record(ctx context.Context, productID primitive.ObjectID, kind string, quantity int, note string, referenceID *primitive.ObjectID) (*model.StockMovement, error) {
+	delta, err := movementDelta(kind, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"_id": productID}
+	if delta < 0 {
+		query["$expr"] = bson.M{"$gte": bson.A{availableStock, -delta}}
+	}
+	product, err := s.productRepo.FindOneAndUpdate(ctx, query, bson.M{
+		"$inc":         bson.M{"stock": delta},
+		"$currentDate": bson.M{"updated_at": true},
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := s.productRepo.FindOne(ctx, bson.M{"_id": productID}); err != nil {
+			return nil, err
+		}
+		return nil, ErrInsufficientStock
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.appendMovement(ctx, product, kind, delta, note, referenceID)
+}
+
+// appendMovement adds the movement that brought the product to its current
+// stock to the ledger. When that fails the change is undone, stock must not
+// move without a ledger entry.
+func (s *InventoryService) appendMovement(ctx context.Context, product *model.Product, kind string, delta int, note string, referenceID *primitive.ObjectID) (*model.StockMovement, error) {
+	req, _ := AuditRequestFromContext(ctx)
+	movement := &model.StockMovement{
+		ID:          primitive.NewObjectID(),
+		ProductID:   product.ID,
+		OrgID:       product.OrgID,
+		Type:        kind,
+		Quantity:    delta,
+		StockAfter:  product.Stock,
+		Note:        note,
+		ReferenceID: referenceID,
+		ActorID:     req.ActorID,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.movementRepo.Create(ctx, movement); err != nil {
+		if _, undoErr := s.productRepo.FindOneAndUpdate(ctx, bson.M{"_id": product.ID}, bson.M{"$inc": bson.M{"stock": -delta}}); undoErr != nil {
+			log.Printf("Failed to undo stock movement of product %s: %v", product.ID.Hex(), undoErr)
+		}
+		return nil, err
+	}
+	return movement, nil
+}
+
+// History lists a page of the product's movements, oldest first. The stock
+// level at any time is the StockAfter of the last movement before it.
+func (s *InventoryService) History(ctx context.Context, productID primitive.ObjectID, from, to *time.Time, page, pageSize int) ([]*model.StockMovement, int64, error) {
+	query := bson.D{{Key: "product_id", Value: productID}}
+	createdAt := bson.D{}
+	if from != nil {
+		createdAt = append(createdAt, bson.E{Key: "$gte", Value: *from})
+	}
+	if to != nil {
+		createdAt = append(createdAt, bson.E{Key: "$lt", Value: *to})
+	}
+	if len(createdAt) > 0 {
+		query = append(query, bson.E{Key: "created_at", Value: createdAt})
+	}
+
+	total, err := s.movementRepo.Count(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	movements, err := s.movementRepo.FindAll(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return movements, total, nil
+}
+
+// Reserve holds quantity of the product for the user until
+// STOCK_RESERVATION_TTL has passed, failing with ErrInsufficientStock when
+// not enough stock is free. The user's active reservations of the product
+// hold at most STOCK_RESERVATION_MAX_QUANTITY, ErrReservationLimit
+// otherwise, so nobody can hold back the whole stock.
+func (s *InventoryService) Reserve(ctx context.Context, product *model.Product, userID primitive.ObjectID, quantity int) (*model.StockReservation, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidStockMovement
+	}
+
+	held, err := s.reservationRepo.FindAll(ctx, activeReservation(bson.M{"user_id": userID, "product_id": product.ID}), nil)
+	if err != nil {
+		return nil, err
+	}
+	total := quantity
+	for _, reservation := range held {
+		total += reservation.Quantity
+	}
+	if total > s.config.StockReservationMaxQuantity {
+		return nil, ErrReservationLimit
+	}
+
+	_, err = s.productRepo.FindOneAndUpdate(ctx,
+		bson.M{"_id": product.ID, "$expr": bson.M{"$gte": bson.A{availableStock, quantity}}},
+		bson.M{"$inc": bson.M{"reserved": quantity}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInsufficientStock
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reservation := &model.StockReservation{
+		ID:        primitive.NewObjectID(),
+		ProductID: product.ID,
+		OrgID:     product.OrgID,
+		UserID:    userID,
+		Quantity:  quantity,
+		ExpiresAt: now.Add(s.config.StockReservationTTL),
+		CreatedAt: now,
+	}
+	if err := s.reservationRepo.Create(ctx, reservation); err != nil {
+		if _, undoErr := s.productRepo.FindOneAndUpdate(ctx, bson.M{"_id": product.ID}, bson.M{"$inc": bson.M{"reserved": -quantity}}); undoErr != nil {
+			log.Printf("Failed to undo reservation of product %s: %v", product.ID.Hex(), undoErr)
+		}
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// FindReservation returns an active reservation of the product.
+func (s *InventoryService) FindReservation(ctx context.Context, productID, id primitive.ObjectID) (*model.StockReservation, error) {
+	reservation, err := s.reservationRepo.FindOne(ctx, activeReservation(bson.M{"_id": id, "product_id": productID}))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrReservationNotFound
+	}
+	return reservation, err
+}
+
+func activeReservation(query bson.M) bson.M {
+	query["released_at"] = bson.M{"$exists": false}
+	query["consumed_at"] = bson.M{"$exists": false}
+	return query
+}
+
+// orgScope scopes ctx to the organization of a document, or to the personal
+// space for documents without one.
+func orgScope(ctx context.Context, orgID *primitive.ObjectID) context.Context {
+	if orgID != nil {
+		return repository.WithOrg(ctx, *orgID)
+	}
+	return repository.WithOrg(ctx, primitive.NilObjectID)
+}
+
+// Release gives the reserved stock back. Only the first release of a
+// reservation counts, later ones fail with ErrReservationNotFound.
+func (s *InventoryService) Release(ctx context.Context, id primitive.ObjectID) error {
+	reservation, err := s.reservationRepo.FindOneAndUpdate(ctx,
+		activeReservation(bson.M{"_id": id}),
+		bson.M{"$set": bson.M{"released_at": time.Now()}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrReservationNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.productRepo.FindOneAndUpdate(orgScope(ctx, reservation.OrgID),
+		bson.M{"_id": reservation.ProductID},
+		bson.M{"$inc": bson.M{"reserved": -reservation.Quantity}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The product is gone and its reservations with it
+		return nil
+	}
+	return err
+}
+
+// ConsumeReservations turns the user's active reservations of the product
+// into a sale: the reserved stock is freed for the sale recorded next.
+func (s *InventoryService) ConsumeReservations(ctx context.Context, userID, productID primitive.ObjectID) error {
+	reservations, err := s.reservationRepo.FindAll(ctx, activeReservation(bson.M{"user_id": userID, "product_id": productID}), nil)
+	if err != nil {
+		return err
+	}
+
+	for _, reservation := range reservations {
+		_, err := s.reservationRepo.FindOneAndUpdate(ctx,
+			activeReservation(bson.M{"_id": reservation.ID}),
+			bson.M{"$set": bson.M{"consumed_at": time.Now()}},
+		)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := s.productRepo.FindOneAndUpdate(orgScope(ctx, reservation.OrgID),
+			bson.M{"_id": reservation.ProductID},
+			bson.M{"$inc": bson.M{"reserved": -reservation.Quantity}},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseExpired releases up to reservationExpiryBatchSize reservations past
+// their expiry.
+func (s *InventoryService) ReleaseExpired(ctx context.Context) {
+	reservations, err := s.reservationRepo.FindAll(ctx,
+		activeReservation(bson.M{"expires_at": bson.M{"$lte": time.Now()}}),
+		options.Find().SetLimit(reservationExpiryBatchSize),
+	)
+	if err != nil {
+		log.Printf("Failed to find expired reservations: %v", err)
+		return
+	}
+
+	for _, reservation := range reservations {
+		if err := s.Release(ctx, reservation.ID); err != nil && !errors.Is(err, ErrReservationNotFound) {
+			log.Printf("Failed to release reservation %s: %v", reservation.ID.Hex(), err)
+		}
+	}
+}
+
+// StartExpiry releases expired reservations every reservationExpiryInterval
+// until ctx is done.
+func (s *InventoryService) StartExpiry(ctx context.Context) {
+	ticker := time.NewTicker(reservationExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.ReleaseExpired(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidInvite     = errors.New("invalid or expired invitation")
+	ErrEmailExists       = errors.New("email already exists")
+	ErrInviteRoleNotHeld = errors.New("cannot invite with a role that has permissions you do not hold")
+	ErrInviteNotFound    = errors.New("invitation not found")
+)
+
+// InvitationService lets admins invite people by email with a role chosen in
+// advance. The link carries a signed token naming the stored invitation, so
+// it expires after INVITATION_TTL, works once and can be revoked.
+type InvitationService struct {
+	invitationRepo    repository.InvitationRepository
+	userRepo          repository.UserRepository
+	userService       *UserService
+	permissionService *PermissionService
+	mailService       MailService
+	auditService      *AuditService
+	config            *config.Config
+	secret            []byte
+}
+
+func NewInvitationService(invitationRepo repository.InvitationRepository, userRepo repository.UserRepository, userService *UserService, permissionService *PermissionService, mailService MailService, auditService *AuditService, config *config.Config) *InvitationService {
+	return &InvitationService{
+		invitationRepo:    invitationRepo,
+		userRepo:          userRepo,
+		userService:       userService,
+		permissionService: permissionService,
+		mailService:       mailService,
+		auditService:      auditService,
+		config:            config,
+		secret:            inviteSecret(config),
+	}
+}
+
+// inviteSecret is INVITE_SECRET or, without one, a key derived from the
+// refresh token secret. Deriving keeps invite and refresh tokens from ever
+// sharing a key.
+func inviteSecret(cfg *config.Config) []byte {
+	if cfg.InviteSecret != "" {
+		return []byte(cfg.InviteSecret)
+	}
+	mac := hmac.New(sha256.New, []byte(cfg.JWTRefreshKey))
+	mac.Write([]byte("invitation"))
+	return mac.Sum(nil)
+}
+
+// pendingInvitations matches invitations that can still be accepted.
+func pendingInvitations(now time.Time) bson.M {
+	return bson.M{
+		"accepted_at": bson.M{"$exists": false},
+		"revoked_at":  bson.M{"$exists": false},
+		"expires_at":  bson.M{"$gt": now},
+	}
+}
+
+// Invite mails an invitation for the role. The inviter must hold every
+// permission of the role, and only the newest invitation of an address works.
+func (i *InvitationService) Invite(ctx context.Context, inviter *model.User, email, role, ip string) (*model.Invitation, error) {
+	exists, err := i.permissionService.RoleExists(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+
+	covered, err := i.permissionService.Covers(ctx, inviter.Roles, []string{role})
+	if err != nil {
+		return nil, err
+	}
+	if !covered {
+		return nil, ErrInviteRoleNotHeld
+	}
+
+	email = strings.TrimSpace(email)
+	if _, err := i.userRepo.FindOne(ctx, bson.M{"email": email}); err == nil {
+		return nil, ErrEmailExists
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	now := time.Now()
+	query := pendingInvitations(now)
+	query["email"] = email
+	if err := i.invitationRepo.UpdateMany(ctx, query, bson.M{"$set": bson.M{"revoked_at": now}}); err != nil {
+		return nil, err
+	}
+
+	invitation := &model.Invitation{
+		ID:        primitive.NewObjectID(),
+		Email:     email,
+		Role:      role,
+		InvitedBy: inviter.ID,
+		ExpiresAt: now.Add(i.config.InvitationTTL),
+		CreatedAt: now,
+	}
+	token, err := utils.SignInviteToken(i.secret, utils.InviteClaims{
+		Email: invitation.Email,
+		Role:  invitation.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invitation.ID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(invitation.ExpiresAt),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s/accept-invite?token=%s", i.config.AppURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi,\n\n%s invited you to %s. Use the link below to create your account. It expires in %s.\n\n%s",
+		inviter.Name, i.config.AppName, i.config.InvitationTTL, link)
+	if err := i.mailService.Send(ctx, email, "You are invited to "+i.config.AppName, body); err != nil {
+		return nil, err
+	}
+
+	i.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditUserInvited,
+		ActorID:  inviter.ID,
+		Metadata: map[string]interface{}{"invitation_id": invitation.ID.Hex(), "email": email, "role": role},
+		IP:       ip,
+	})
+	return invitation, nil
+}
+
+// Pending lists the invitations that can still be accepted.
+func (i *InvitationService) Pending(ctx context.Context) ([]*model.Invitation, error) {
+	return i.invitationRepo.FindAll(ctx, pendingInvitations(time.Now()))
+}
+
+// Revoke stops a pending invitation from being accepted.
+func (i *InvitationService) Revoke(ctx context.Context, actor *model.User, id primitive.ObjectID, ip string) error {
+	now := time.Now()
+	query := pendingInvitations(now)
+	query["_id"] = id
+
+	invitation, err := i.invitationRepo.FindOneAndUpdate(ctx, query, bson.M{"$set": bson.M{"revoked_at": now}})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInviteNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	i.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditInvitationRevoked,
+		ActorID:  actor.ID,
+		Metadata: map[string]interface{}{"invitation_id": invitation.ID.Hex(), "email": invitation.Email},
+		IP:       ip,
+	})
+	return nil
+}
+
+// Accept creates the invited account through UserService.Create with the
+// role of the invitation. The address is marked verified, the invitee proved
+// it by opening the link.
+func (i *InvitationService) Accept(ctx context.Context, req *dto.AcceptInvitationRequest, ip string) (*model.User, error) {
+	claims, err := utils.ParseInviteToken(i.secret, req.Token)
+	if err != nil {
+		return nil, ErrInvalidInvite
+	}
+	invitationID, err := primitive.ObjectIDFromHex(claims.ID)
+	if err != nil {
+		return nil, ErrInvalidInvite
+	}
+
+	if _, err := i.userRepo.FindOne(ctx, bson.M{"email": claims.Email}); err == nil {
+		return nil, ErrEmailExists
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim the invitation first, so one link cannot create two accounts
+	now := time.Now()
+	query := pendingInvitations(now)
+	query["_id"] = invitationID
+	invitation, err := i.invitationRepo.FindOneAndUpdate(ctx, query, bson.M{"$set": bson.M{"accepted_at": now}})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidInvite
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := i.userService.Create(ctx, &dto.RegisterRequest{
+		Name:     req.Name,
+		Email:    invitation.Email,
+		Password: string(hashedPassword),
+	}, invitation.Role)
+	if err != nil {
+		// Give the link back, the account was not created
+		if _, undoErr := i.invitationRepo.FindOneAndUpdate(ctx, bson.M{"_id": invitation.ID}, bson.M{"$unset": bson.M{"accepted_at": ""}}); undoErr != nil {
+			log.Printf("Failed to release invitation %s: %v", invitation.ID.Hex(), undoErr)
+		}
+		return nil, err
+	}
+
+	if _, err := i.userRepo.Update(ctx, bson.M{"email_verified": true, "email_verified_at": now}, user.ID); err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &now
+
+	if _, err := i.invitationRepo.FindOneAndUpdate(ctx, bson.M{"_id": invitation.ID}, bson.M{"$set": bson.M{"user_id": user.ID}}); err != nil {
+		log.Printf("Failed to link invitation %s to user %s: %v", invitation.ID.Hex(), user.ID.Hex(), err)
+	}
+
+	i.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditInvitationAccepted,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]interface{}{"invitation_id": invitation.ID.Hex(), "invited_by": invitation.InvitedBy.Hex(), "role": invitation.Role},
+		IP:       ip,
+	})
+	return user, nil
+}
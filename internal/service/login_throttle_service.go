@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrAccountLocked  = errors.New("too many failed logins, the account is temporarily locked")
+	ErrLoginThrottled = errors.New("too many failed logins, try again later")
+)
+
+// The delay between attempts doubles with every failure up to this cap
+const maxLoginDelay = time.Minute
+
+// LoginThrottleService counts failed password logins per account and per IP
+// in Redis. Failures on an account first slow it down, then lock it for
+// LOGIN_LOCKOUT; an IP with too many failures across accounts is blocked for
+// the rest of the window. Unknown emails are tracked like real accounts.
+type LoginThrottleService struct {
+	redisClient  *redis.Client
+	auditService *AuditService
+	config       *config.Config
+}
+
+func NewLoginThrottleService(redisClient *redis.Client, auditService *AuditService, config *config.Config) *LoginThrottleService {
+	return &LoginThrottleService{
+		redisClient:  redisClient,
+		auditService: auditService,
+		config:       config,
+	}
+}
+
+// Accounts are keyed by a hash of the email, so Redis holds no addresses
+func accountKey(email string) string {
+	return utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func loginFailuresKey(email string) string {
+	return "login_fail:" + accountKey(email)
+}
+
+func loginDelayKey(email string) string {
+	return "login_delay:" + accountKey(email)
+}
+
+func loginLockKey(email string) string {
+	return "login_lock:" + accountKey(email)
+}
+
+func loginIPFailuresKey(ip string) string {
+	return "login_fail_ip:" + ip
+}
+
+// Check returns ErrAccountLocked or ErrLoginThrottled with the time to wait
+// when a login for the email from the IP must not be tried now. Redis errors
+// are logged and let the login through, an outage must not lock everyone out.
+func (l *LoginThrottleService) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	pipe := l.redisClient.Pipeline()
+	lock := pipe.PTTL(ctx, loginLockKey(email))
+	delay := pipe.PTTL(ctx, loginDelayKey(email))
+	ipFailures := pipe.Get(ctx, loginIPFailuresKey(ip))
+	ipWindow := pipe.PTTL(ctx, loginIPFailuresKey(ip))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Login throttle check failed: %v", err)
+		return 0, nil
+	}
+
+	if wait := lock.Val(); wait > 0 {
+		return wait, ErrAccountLocked
+	}
+	if wait := delay.Val(); wait > 0 {
+		return wait, ErrLoginThrottled
+	}
+	if max := l.config.LoginIPMaxFailures; max > 0 {
+		if failures, _ := ipFailures.Int(); failures >= max && ipWindow.Val() > 0 {
+			return ipWindow.Val(), ErrLoginThrottled
+		}
+	}
+	return 0, nil
+}
+
+// RecordFailure counts a failed login. userID is zero for unknown emails.
+func (l *LoginThrottleService) RecordFailure(ctx context.Context, email, ip string, userID primitive.ObjectID) {
+	window := l.config.LoginFailureWindow
+
+	// The account window restarts with every failure so slow guessing stays
+	// counted, the IP window is fixed so a blocked address is let in again
+	// at its end
+	pipe := l.redisClient.TxPipeline()
+	failures := pipe.Incr(ctx, loginFailuresKey(email))
+	pipe.Expire(ctx, loginFailuresKey(email), window)
+	pipe.SetNX(ctx, loginIPFailuresKey(ip), 0, window)
+	pipe.Incr(ctx, loginIPFailuresKey(ip))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record failed login: %v", err)
+		return
+	}
+
+	count := int(failures.Val())
+	if max := l.config.LoginMaxFailures; max > 0 && count >= max {
+		l.lock(ctx, email, userID, ip)
+		return
+	}
+
+	if after := l.config.LoginDelayAfter; after > 0 && count >= after {
+		wait := time.Second << uint(count-after)
+		if wait > maxLoginDelay || wait <= 0 {
+			wait = maxLoginDelay
+		}
+		if err := l.redisClient.Set(ctx, loginDelayKey(email), "1", wait).Err(); err != nil {
+			log.Printf("Failed to record login delay: %v", err)
+		}
+	}
+}
+
+// RecordSuccess clears the failures of an account after a correct password.
+// The IP counter is kept, one valid login must not reset a spraying attack.
+func (l *LoginThrottleService) RecordSuccess(ctx context.Context, email string) {
+	if err := l.redisClient.Del(ctx, loginFailuresKey(email), loginDelayKey(email)).Err(); err != nil {
+		log.Printf("Failed to clear failed logins: %v", err)
+	}
+}
+
+// Unlock lifts a lock and clears the failures of an account.
+func (l *LoginThrottleService) Unlock(ctx context.Context, actor *model.User, user *model.User, ip string) error {
+	deleted, err := l.redisClient.Del(ctx, loginLockKey(user.Email), loginFailuresKey(user.Email), loginDelayKey(user.Email)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return nil
+	}
+
+	l.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditUserUnlocked,
+		ActorID:  actor.ID,
+		TargetID: user.ID,
+		IP:       ip,
+	})
+	return nil
+}
+
+func (l *LoginThrottleService) lock(ctx context.Context, email string, userID primitive.ObjectID, ip string) {
+	pipe := l.redisClient.TxPipeline()
+	pipe.Set(ctx, loginLockKey(email), "1", l.config.LoginLockout)
+	pipe.Del(ctx, loginFailuresKey(email), loginDelayKey(email))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to lock account: %v", err)
+		return
+	}
+
+	if !userID.IsZero() {
+		l.auditService.Record(ctx, &model.AuditEvent{
+			Action:   model.AuditUserLocked,
+			TargetID: userID,
+			Metadata: map[string]interface{}{"duration": l.config.LoginLockout.String()},
+			IP:       ip,
+		})
+	}
+}
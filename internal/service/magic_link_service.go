@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidMagicLink   = errors.New("invalid or expired login link")
+	ErrMagicLinkThrottled = errors.New("too many login links requested, try again later")
+)
+
+// Window of MAGIC_LINK_MAX_PER_HOUR
+const magicLinkRateWindow = time.Hour
+
+// MagicLinkLogin is the outcome of a verified link: either a token pair or,
+// for users with two-factor authentication, a challenge to complete.
+type MagicLinkLogin struct {
+	TokenPair *utils.TokenPair
+	Challenge *TwoFactorChallenge
+}
+
+// MagicLinkService signs users in with a one-time link mailed to their
+// address. A link expires after MAGIC_LINK_TTL, works once and only from the
+// device and IP that asked for it: the device proves itself with the request
+// id returned when the link was requested.
+type MagicLinkService struct {
+	userRepo         repository.UserRepository
+	redisClient      *redis.Client
+	tokenService     *TokenService
+	twoFactorService *TwoFactorService
+	mailService      MailService
+	config           *config.Config
+}
+
+func NewMagicLinkService(userRepo repository.UserRepository, redisClient *redis.Client, tokenService *TokenService, twoFactorService *TwoFactorService, mailService MailService, config *config.Config) *MagicLinkService {
+	return &MagicLinkService{
+		userRepo:         userRepo,
+		redisClient:      redisClient,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		mailService:      mailService,
+		config:           config,
+	}
+}
+
+func magicLinkKey(hash string) string {
+	return "magic_link:" + hash
+}
+
+func userMagicLinkKey(userID string) string {
+	return "magic_link_user:" + userID
+}
+
+func magicLinkRateKey(email string) string {
+	return "magic_link_rate:" + accountKey(email)
+}
+
+// Request mails a login link in the background and returns the request id
+// the device must present with it. Unknown emails get a request id too and
+// no mail. Requests per email are limited to MAGIC_LINK_MAX_PER_HOUR, the
+// returned duration is the time to wait after ErrMagicLinkThrottled.
+func (m *MagicLinkService) Request(ctx context.Context, email string, client model.ClientInfo) (string, time.Duration, error) {
+	if wait, err := m.countRequest(ctx, email); err != nil {
+		return "", wait, err
+	}
+
+	requestID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return "", 0, err
+	}
+
+	user, err := m.userRepo.FindOne(ctx, bson.M{"email": email})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return requestID, 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", 0, err
+	}
+	hash := utils.HashToken(token)
+
+	// Only the newest link works, requesting another one kills the previous
+	previous, err := m.redisClient.Get(ctx, userMagicLinkKey(user.ID.Hex())).Result()
+	if err != nil && err != redis.Nil {
+		return "", 0, err
+	}
+
+	pipe := m.redisClient.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, magicLinkKey(previous))
+	}
+	pipe.HSet(ctx, magicLinkKey(hash),
+		"user_id", user.ID.Hex(),
+		"request", utils.HashToken(requestID),
+		"ip", client.IP,
+		"device_name", client.DeviceName,
+	)
+	pipe.Expire(ctx, magicLinkKey(hash), m.config.MagicLinkTTL)
+	pipe.Set(ctx, userMagicLinkKey(user.ID.Hex()), hash, m.config.MagicLinkTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", 0, err
+	}
+
+	link := fmt.Sprintf("%s/magic-link?token=%s", m.config.AppURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nUse the link below to sign in. It works once, only on the device you asked from, and expires in %s.\n\n%s\n\nIf you did not ask for this, you can ignore this email.",
+		user.Name, m.config.MagicLinkTTL, link)
+	sendInBackground(ctx, m.mailService, user.Email, "Your login link", body)
+	return requestID, 0, nil
+}
+
+// countRequest counts a link request for the email in a fixed window.
+// Redis errors are logged and let the request through.
+func (m *MagicLinkService) countRequest(ctx context.Context, email string) (time.Duration, error) {
+	max := m.config.MagicLinkMaxPerHour
+	if max <= 0 {
+		return 0, nil
+	}
+
+	key := magicLinkRateKey(email)
+	pipe := m.redisClient.TxPipeline()
+	pipe.SetNX(ctx, key, 0, magicLinkRateWindow)
+	count := pipe.Incr(ctx, key)
+	window := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to count login link request: %v", err)
+		return 0, nil
+	}
+
+	if int(count.Val()) > max {
+		return window.Val(), ErrMagicLinkThrottled
+	}
+	return 0, nil
+}
+
+// Verify consumes a login link and starts a session like a password login.
+// The link is used up even when the request id or IP do not match, a leaked
+// link must not stay usable.
+func (m *MagicLinkService) Verify(ctx context.Context, token, requestID string, client model.ClientInfo) (*MagicLinkLogin, error) {
+	key := magicLinkKey(utils.HashToken(token))
+	pipe := m.redisClient.TxPipeline()
+	get := pipe.HGetAll(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	values := get.Val()
+	if len(values) == 0 {
+		return nil, ErrInvalidMagicLink
+	}
+	m.redisClient.Del(ctx, userMagicLinkKey(values["user_id"]))
+
+	if values["request"] != utils.HashToken(requestID) || values["ip"] != client.IP {
+		return nil, ErrInvalidMagicLink
+	}
+
+	userID, err := primitive.ObjectIDFromHex(values["user_id"])
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+	user, err := m.userRepo.FindOne(ctx, bson.M{"_id": userID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Opening the link proves the address belongs to the user
+	if !user.EmailVerified {
+		now := time.Now()
+		if user, err = m.userRepo.Update(ctx, bson.M{"email_verified": true, "email_verified_at": now}, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if client.DeviceName == "" {
+		client.DeviceName = values["device_name"]
+	}
+	if user.TwoFactorEnabled {
+		challenge, err := m.twoFactorService.CreateChallenge(ctx, user, client, utils.AMREmail)
+		if err != nil {
+			return nil, err
+		}
+		return &MagicLinkLogin{Challenge: challenge}, nil
+	}
+
+	tokenPair, err := m.tokenService.StartFamily(ctx, user, client, []string{utils.AMREmail})
+	if err != nil {
+		return nil, err
+	}
+	return &MagicLinkLogin{TokenPair: tokenPair}, nil
+}
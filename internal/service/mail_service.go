@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"example-go-project/pkg/config"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Time a mail sent in the background may take
+const backgroundMailTimeout = 30 * time.Second
+
+// MailService sends transactional email. Without SMTP settings the messages
+// are only logged, which is what development and tests use.
+type MailService interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type logMailService struct{}
+
+type smtpMailService struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewMailService(cfg *config.Config) MailService {
+	if cfg.SMTPHost == "" {
+		return &logMailService{}
+	}
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	return &smtpMailService{
+		addr: net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		auth: auth,
+		from: cfg.MailFrom,
+	}
+}
+
+func (s *logMailService) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+func (s *smtpMailService) Send(ctx context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	msg := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{to}, []byte(msg))
+}
+
+// sendInBackground sends the mail without holding up the request and logs
+// failures. Endpoints that must not reveal whether an account exists use it,
+// so known emails answer as fast as unknown ones and a mail failure does not
+// show in the response.
+func sendInBackground(ctx context.Context, mailService MailService, to, subject, body string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundMailTimeout)
+	go func() {
+		defer cancel()
+		if err := mailService.Send(ctx, to, subject, body); err != nil {
+			log.Printf("Failed to send %q to %s: %v", subject, to, err)
+		}
+	}()
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/oidc"
+	"example-go-project/pkg/utils"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrInvalidOIDCState     = errors.New("invalid or expired login state")
+	ErrOIDCEmailNotVerified = errors.New("the identity provider has not verified this email address")
+)
+
+// Time the user has to finish the login at the provider
+const oidcStateTTL = 10 * time.Minute
+
+type oidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	DeviceName   string `json:"device_name,omitempty"`
+}
+
+// OIDCLogin is the outcome of a provider callback: either a token pair or,
+// for users with two-factor authentication, a challenge to complete.
+type OIDCLogin struct {
+	TokenPair *utils.TokenPair
+	Challenge *TwoFactorChallenge
+}
+
+// OIDCService signs users in with external OpenID Connect providers. The
+// state, nonce and PKCE verifier of a started login are kept in Redis and
+// can be used once.
+type OIDCService struct {
+	providers        map[string]*oidc.Provider
+	userRepo         repository.UserRepository
+	redisClient      *redis.Client
+	tokenService     *TokenService
+	twoFactorService *TwoFactorService
+	auditService     *AuditService
+	config           *config.Config
+}
+
+func NewOIDCService(providers []*oidc.Provider, userRepo repository.UserRepository, redisClient *redis.Client, tokenService *TokenService, twoFactorService *TwoFactorService, auditService *AuditService, config *config.Config) *OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &OIDCService{
+		providers:        byName,
+		userRepo:         userRepo,
+		redisClient:      redisClient,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		auditService:     auditService,
+		config:           config,
+	}
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + utils.HashToken(state)
+}
+
+// Start begins a login at the provider and returns the state, which the
+// caller binds to the browser, and the URL to send the user to.
+func (o *OIDCService) Start(ctx context.Context, providerName, deviceName string) (string, string, error) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+
+	payload, err := json.Marshal(&oidcState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		DeviceName:   deviceName,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if err := o.redisClient.Set(ctx, oidcStateKey(state), payload, oidcStateTTL).Err(); err != nil {
+		return "", "", err
+	}
+
+	return state, authURL, nil
+}
+
+// Callback finishes a login: it redeems the code, validates the ID token and
+// signs in the linked account, creating it on the first login.
+func (o *OIDCService) Callback(ctx context.Context, providerName, state, code string, client model.ClientInfo) (*OIDCLogin, error) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	raw, err := o.redisClient.GetDel(ctx, oidcStateKey(state)).Result()
+	if err == redis.Nil {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored oidcState
+	if err := json.Unmarshal([]byte(raw), &stored); err != nil || stored.Provider != providerName {
+		return nil, ErrInvalidOIDCState
+	}
+
+	token, err := provider.Exchange(ctx, code, stored.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, stored.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := o.linkUser(ctx, providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.DeviceName == "" {
+		client.DeviceName = stored.DeviceName
+	}
+	if user.TwoFactorEnabled {
+		challenge, err := o.twoFactorService.CreateChallenge(ctx, user, client, utils.AMROIDC)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCLogin{Challenge: challenge}, nil
+	}
+
+	tokenPair, err := o.tokenService.StartFamily(ctx, user, client, []string{utils.AMROIDC})
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLogin{TokenPair: tokenPair}, nil
+}
+
+// linkUser returns the account linked to the provider subject. Otherwise the
+// identity is linked to the account with the same email, or a new account
+// with the default role is created.
+func (o *OIDCService) linkUser(ctx context.Context, providerName string, claims *oidc.IDTokenClaims) (*model.User, error) {
+	user, err := o.userRepo.FindOne(ctx, bson.M{"identities": bson.M{"$elemMatch": bson.M{
+		"provider": providerName,
+		"subject":  claims.Subject,
+	}}})
+	if err == nil {
+		return user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	now := time.Now()
+	identity := model.ExternalIdentity{Provider: providerName, Subject: claims.Subject, LinkedAt: now}
+	user, err = o.userRepo.FindOne(ctx, bson.M{"email": claims.Email})
+	if err == mongo.ErrNoDocuments {
+		return o.createUser(ctx, claims.Email, claims.Name, identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$push": bson.M{"identities": identity}}
+	if !user.EmailVerified {
+		// Whoever registered this address never proved they own it while the
+		// provider did, so their password and sessions must not survive
+		update["$set"] = bson.M{"email_verified": true, "email_verified_at": now}
+		update["$unset"] = bson.M{"password": ""}
+		if err := o.tokenService.RevokeAllSessions(ctx, user.ID.Hex()); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err = o.userRepo.FindOneAndUpdate(ctx, bson.M{"_id": user.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditIdentityLinked,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]interface{}{"provider": providerName, "subject": claims.Subject},
+	})
+	return user, nil
+}
+
+// createUser registers a new account on a first login. With INVITE_ONLY
+// only existing accounts can sign in through a provider.
+func (o *OIDCService) createUser(ctx context.Context, email, name string, identity model.ExternalIdentity) (*model.User, error) {
+	if o.config.InviteOnly {
+		return nil, ErrRegistrationClosed
+	}
+
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+
+	now := identity.LinkedAt
+	user := &model.User{
+		ID:              primitive.NewObjectID(),
+		Email:           email,
+		Name:            name,
+		Roles:           []string{string(utils.UserRole)},
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		Identities:      []model.ExternalIdentity{identity},
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := o.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Created account %s on first login through %s", user.ID.Hex(), identity.Provider)
+	return user, nil
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidOrder           = errors.New("invalid order")
+	ErrInvalidOrderTransition = errors.New("order cannot move to this status")
+	ErrOrderChanged           = errors.New("order has been changed in the meantime")
+)
+
+// OrderService records sales. Creating an order sells the stock of its items
+// and saves the order in one transaction, so either both happen or neither.
+// Payment goes through the configured PaymentProvider.
+type OrderService struct {
+	orderRepo        repository.OrderRepository
+	productRepo      repository.ProductRepository
+	inventoryService *InventoryService
+	transactor       repository.Transactor
+	paymentProvider  PaymentProvider
+	auditService     *AuditService
+}
+
+func NewOrderService(orderRepo repository.OrderRepository, productRepo repository.ProductRepository, inventoryService *InventoryService, transactor repository.Transactor, paymentProvider PaymentProvider, auditService *AuditService) *OrderService {
+	return &OrderService{
+		orderRepo:        orderRepo,
+		productRepo:      productRepo,
+		inventoryService: inventoryService,
+		transactor:       transactor,
+		paymentProvider:  paymentProvider,
+		auditService:     auditService,
+	}
+}
+
+// roundPrice rounds an amount to cents
+func roundPrice(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Create places a pending order for the products in the scope of ctx. Items
+// of the same product are merged. The user's reservations of the products
+// are consumed and a product without enough free stock fails the whole order
+// with ErrInsufficientStock.
+func (o *OrderService) Create(ctx context.Context, userID primitive.ObjectID, items []dto.OrderItemRequest) (*model.Order, error) {
+	var productIDs []primitive.ObjectID
+	quantities := map[primitive.ObjectID]int{}
+	for _, item := range items {
+		productID, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid product ID %q", ErrInvalidOrder, item.ProductID)
+		}
+		if _, ok := quantities[productID]; !ok {
+			productIDs = append(productIDs, productID)
+		}
+		quantities[productID] += item.Quantity
+	}
+
+	var order *model.Order
+	err := o.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		req, _ := AuditRequestFromContext(ctx)
+		order = &model.Order{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			Status:    model.OrderPending,
+			History:   []model.OrderStatusChange{{Status: model.OrderPending, ActorID: req.ActorID, ChangedAt: now}},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if orgID, ok := repository.OrgFromContext(ctx); ok {
+			order.OrgID = &orgID
+		}
+
+		for _, productID := range productIDs {
+			product, err := o.productRepo.FindOne(ctx, bson.M{"_id": productID})
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return fmt.Errorf("%w: product %s not found", ErrInvalidOrder, productID.Hex())
+			}
+			if err != nil {
+				return err
+			}
+
+			quantity := quantities[productID]
+			if err := o.inventoryService.ConsumeReservations(ctx, userID, productID); err != nil {
+				return err
+			}
+			if _, err := o.inventoryService.Record(ctx, productID, model.StockSale, quantity, "Order", &order.ID); err != nil {
+				if errors.Is(err, ErrInsufficientStock) {
+					return fmt.Errorf("%w: %s", err, product.Name)
+				}
+				return err
+			}
+
+			subtotal := roundPrice(product.Price * float64(quantity))
+			order.Items = append(order.Items, model.OrderItem{
+				ProductID: product.ID,
+				Name:      product.Name,
+				Price:     product.Price,
+				Quantity:  quantity,
+				Subtotal:  subtotal,
+			})
+			order.Total = roundPrice(order.Total + subtotal)
+		}
+
+		return o.orderRepo.Create(ctx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrderCreated,
+		TargetID: order.ID,
+		Metadata: map[string]interface{}{"total": order.Total, "items": len(order.Items)},
+	})
+	return order, nil
+}
+
+func (o *OrderService) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Order, error) {
+	return o.orderRepo.FindOne(ctx, bson.M{"_id": id})
+}
+
+// FindAll lists a page of orders, newest first.
+func (o *OrderService) FindAll(ctx context.Context, query bson.D, page, pageSize int) ([]*model.Order, int64, error) {
+	total, err := o.orderRepo.Count(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	orders, err := o.orderRepo.FindAll(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+// Pay charges a pending order and marks it paid. When the order changed
+// while it was charged, the charge is refunded.
+func (o *OrderService) Pay(ctx context.Context, order *model.Order, source string) (*model.Order, error) {
+	if !model.CanTransition(order.Status, model.OrderPaid) {
+		return nil, ErrInvalidOrderTransition
+	}
+
+	paymentID, err := o.paymentProvider.Charge(ctx, order, source)
+	if err != nil {
+		return nil, err
+	}
+
+	paid, err := o.moveTo(ctx, order, model.OrderPaid, bson.M{"payment_id": paymentID})
+	if err != nil {
+		charged := *order
+		charged.PaymentID = paymentID
+		if refundErr := o.paymentProvider.Refund(ctx, &charged); refundErr != nil {
+			log.Printf("Failed to refund payment %s of order %s: %v", paymentID, order.ID.Hex(), refundErr)
+		}
+		return nil, err
+	}
+
+	o.auditTransition(ctx, order, model.OrderPaid)
+	return paid, nil
+}
+
+// Transition moves the order to another status, payment is left to Pay. A
+// refund goes back through the payment provider. Orders cancelled or refunded
+// before they shipped put their stock back.
+func (o *OrderService) Transition(ctx context.Context, order *model.Order, status string) (*model.Order, error) {
+	if status == model.OrderPaid || !model.CanTransition(order.Status, status) {
+		return nil, ErrInvalidOrderTransition
+	}
+	if status == model.OrderRefunded && order.PaymentID != "" {
+		return o.refund(ctx, order)
+	}
+
+	restock := status == model.OrderCancelled || (status == model.OrderRefunded && order.Status != model.OrderShipped)
+	updated, err := o.complete(ctx, order, status, restock)
+	if err != nil {
+		return nil, err
+	}
+
+	o.auditTransition(ctx, order, status)
+	return updated, nil
+}
+
+// refund claims the order by moving it to refunding, refunds the payment and
+// only then completes the refund in a transaction, which may run more than
+// once and so must not call the provider. A declined refund puts the order
+// back in the status it had. An order left refunding, because completing
+// failed after the provider refunded, is completed by refunding it again.
+func (o *OrderService) refund(ctx context.Context, order *model.Order) (*model.Order, error) {
+	claimed := order
+	if order.Status != model.OrderRefunding {
+		var err error
+		if claimed, err = o.moveTo(ctx, order, model.OrderRefunding, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := o.paymentProvider.Refund(ctx, claimed); err != nil {
+		if order.Status != model.OrderRefunding {
+			if _, moveErr := o.moveTo(ctx, claimed, order.Status, nil); moveErr != nil {
+				log.Printf("Failed to return order %s to %s after a failed refund: %v", order.ID.Hex(), order.Status, moveErr)
+			}
+		}
+		return nil, err
+	}
+
+	updated, err := o.complete(ctx, claimed, model.OrderRefunded, statusBeforeRefund(claimed) != model.OrderShipped)
+	if err != nil {
+		return nil, err
+	}
+
+	o.auditTransition(ctx, order, model.OrderRefunded)
+	return updated, nil
+}
+
+// complete moves the order to status and puts back its stock with restock,
+// both in one transaction.
+func (o *OrderService) complete(ctx context.Context, order *model.Order, status string, restock bool) (*model.Order, error) {
+	var updated *model.Order
+	err := o.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if updated, err = o.moveTo(ctx, order, status, nil); err != nil {
+			return err
+		}
+		if restock {
+			return o.restock(ctx, order, status)
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// statusBeforeRefund returns the status the order had when it last moved to
+// refunding.
+func statusBeforeRefund(order *model.Order) string {
+	for i := len(order.History) - 1; i > 0; i-- {
+		if order.History[i].Status == model.OrderRefunding {
+			return order.History[i-1].Status
+		}
+	}
+	return order.Status
+}
+
+// restock returns the items of the order to stock in the order's scope.
+func (o *OrderService) restock(ctx context.Context, order *model.Order, status string) error {
+	scoped := orgScope(ctx, order.OrgID)
+	for _, item := range order.Items {
+		_, err := o.inventoryService.Record(scoped, item.ProductID, model.StockReturn, item.Quantity, "Order "+status, &order.ID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The product is gone, there is no stock to put back
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveTo changes the status of the order as long as nobody else has changed
+// it since it was read, ErrOrderChanged otherwise.
+func (o *OrderService) moveTo(ctx context.Context, order *model.Order, status string, set bson.M) (*model.Order, error) {
+	now := time.Now()
+	req, _ := AuditRequestFromContext(ctx)
+
+	if set == nil {
+		set = bson.M{}
+	}
+	set["status"] = status
+	set["updated_at"] = now
+
+	updated, err := o.orderRepo.FindOneAndUpdate(ctx,
+		bson.M{"_id": order.ID, "status": order.Status},
+		bson.M{
+			"$set":  set,
+			"$push": bson.M{"history": model.OrderStatusChange{Status: status, ActorID: req.ActorID, ChangedAt: now}},
+		},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrOrderChanged
+	}
+	return updated, err
+}
+
+func (o *OrderService) auditTransition(ctx context.Context, order *model.Order, status string) {
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrderStatusChanged,
+		TargetID: order.ID,
+		Metadata: map[string]interface{}{"from": order.Status, "to": status},
+	})
+}
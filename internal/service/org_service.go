@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrNotOrgMember            = errors.New("you are not a member of this organization")
+	ErrInvalidOrgRole          = errors.New("role must be owner, admin or member")
+	ErrOrgAdminRequired        = errors.New("only organization admins can manage members")
+	ErrOrgOwnerRequired        = errors.New("only owners can manage owners")
+	ErrLastOrgOwner            = errors.New("an organization needs at least one owner")
+	ErrOrgNotEmpty             = errors.New("the organization still has products or files")
+	ErrAlreadyOrgMember        = errors.New("the user is already a member of this organization")
+	ErrInvalidInvitation       = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch = errors.New("the invitation was sent to a different email address")
+)
+
+// Rank of each organization role, a role includes the rights of the lower ones
+var orgRoleRank = map[string]int{
+	model.OrgRoleMember: 1,
+	model.OrgRoleAdmin:  2,
+	model.OrgRoleOwner:  3,
+}
+
+// HasOrgRole reports whether the membership has at least the given role.
+func HasOrgRole(membership *model.Membership, role string) bool {
+	return orgRoleRank[membership.Role] >= orgRoleRank[role]
+}
+
+// UserOrganization is an organization with the role the user has in it.
+type UserOrganization struct {
+	*model.Organization
+	Role string `json:"role"`
+}
+
+// OrgService manages organizations, their members and invitations. Products
+// and files are scoped to an organization by their repositories, this
+// service decides who may select one.
+type OrgService struct {
+	orgRepo        repository.OrganizationRepository
+	membershipRepo repository.MembershipRepository
+	invitationRepo repository.OrgInvitationRepository
+	userRepo       repository.UserRepository
+	productRepo    repository.ProductRepository
+	fileRepo       repository.LocalFileRepository
+	tokenService   *TokenService
+	mailService    MailService
+	auditService   *AuditService
+	config         *config.Config
+}
+
+func NewOrgService(orgRepo repository.OrganizationRepository, membershipRepo repository.MembershipRepository, invitationRepo repository.OrgInvitationRepository, userRepo repository.UserRepository, productRepo repository.ProductRepository, fileRepo repository.LocalFileRepository, tokenService *TokenService, mailService MailService, auditService *AuditService, config *config.Config) *OrgService {
+	return &OrgService{
+		orgRepo:        orgRepo,
+		membershipRepo: membershipRepo,
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		productRepo:    productRepo,
+		fileRepo:       fileRepo,
+		tokenService:   tokenService,
+		mailService:    mailService,
+		auditService:   auditService,
+		config:         config,
+	}
+}
+
+// Create makes an organization with the user as its owner.
+func (o *OrgService) Create(ctx context.Context, user *model.User, name string) (*model.Organization, error) {
+	now := time.Now()
+	org := &model.Organization{
+		Name:      name,
+		CreatedBy: user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := o.orgRepo.Create(ctx, org); err != nil {
+		return nil, err
+	}
+
+	if err := o.membershipRepo.Create(ctx, &model.Membership{
+		OrgID:     org.ID,
+		UserID:    user.ID,
+		Role:      model.OrgRoleOwner,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrgCreated,
+		ActorID:  user.ID,
+		Metadata: map[string]interface{}{"org_id": org.ID.Hex(), "name": name},
+	})
+	return org, nil
+}
+
+// Membership returns the membership of the user in the organization, or
+// ErrNotOrgMember.
+func (o *OrgService) Membership(ctx context.Context, orgID, userID primitive.ObjectID) (*model.Membership, error) {
+	membership, err := o.membershipRepo.FindOne(ctx, bson.M{"org_id": orgID, "user_id": userID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotOrgMember
+	}
+	return membership, err
+}
+
+// ListForUser returns the organizations the user belongs to.
+func (o *OrgService) ListForUser(ctx context.Context, user *model.User) ([]*UserOrganization, error) {
+	memberships, err := o.membershipRepo.FindAll(ctx, bson.M{"user_id": user.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[primitive.ObjectID]string, len(memberships))
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, m := range memberships {
+		roles[m.OrgID] = m.Role
+		ids = append(ids, m.OrgID)
+	}
+
+	orgs, err := o.orgRepo.FindAll(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*UserOrganization, 0, len(orgs))
+	for _, org := range orgs {
+		result = append(result, &UserOrganization{Organization: org, Role: roles[org.ID]})
+	}
+	return result, nil
+}
+
+func (o *OrgService) Get(ctx context.Context, orgID primitive.ObjectID) (*model.Organization, error) {
+	return o.orgRepo.FindOne(ctx, bson.M{"_id": orgID})
+}
+
+// Update renames an organization.
+func (o *OrgService) Update(ctx context.Context, orgID primitive.ObjectID, name string) (*model.Organization, error) {
+	before, err := o.orgRepo.FindOne(ctx, bson.M{"_id": orgID})
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := o.orgRepo.Update(ctx, bson.M{"name": name}, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &model.AuditEvent{Action: model.AuditOrgUpdated, TargetID: orgID}
+	event.Before, event.After = AuditDiff(before, after)
+	o.auditService.Record(ctx, event)
+	return after, nil
+}
+
+// Delete removes an organization with its members and invitations. Its
+// products and files must be deleted first, they are never removed silently.
+func (o *OrgService) Delete(ctx context.Context, actor *model.User, orgID primitive.ObjectID) error {
+	scoped := repository.WithOrg(ctx, orgID)
+	products, err := o.productRepo.Count(scoped, bson.D{})
+	if err != nil {
+		return err
+	}
+	files, err := o.fileRepo.Count(scoped, bson.D{})
+	if err != nil {
+		return err
+	}
+	if products > 0 || files > 0 {
+		return ErrOrgNotEmpty
+	}
+
+	if err := o.orgRepo.Delete(ctx, orgID); err != nil {
+		return err
+	}
+	if err := o.membershipRepo.DeleteMany(ctx, bson.M{"org_id": orgID}); err != nil {
+		return err
+	}
+	if err := o.invitationRepo.DeleteMany(ctx, bson.M{"org_id": orgID}); err != nil {
+		return err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrgDeleted,
+		ActorID:  actor.ID,
+		Metadata: map[string]interface{}{"org_id": orgID.Hex()},
+	})
+	return nil
+}
+
+// Members lists the members of an organization.
+func (o *OrgService) Members(ctx context.Context, orgID primitive.ObjectID) ([]*model.Membership, error) {
+	return o.membershipRepo.FindAll(ctx, bson.M{"org_id": orgID})
+}
+
+// UpdateMember changes the role of a member. Admins manage admins and
+// members, only owners can make or unmake an owner.
+func (o *OrgService) UpdateMember(ctx context.Context, actor *model.Membership, userID primitive.ObjectID, role string) (*model.Membership, error) {
+	if !model.IsOrgRole(role) {
+		return nil, ErrInvalidOrgRole
+	}
+
+	member, err := o.membershipRepo.FindOne(ctx, bson.M{"org_id": actor.OrgID, "user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.checkManage(ctx, actor, member, role); err != nil {
+		return nil, err
+	}
+
+	updated, err := o.membershipRepo.Update(ctx, bson.M{"_id": member.ID}, bson.M{"role": role})
+	if err != nil {
+		return nil, err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrgMemberUpdated,
+		ActorID:  actor.UserID,
+		TargetID: userID,
+		Metadata: map[string]interface{}{"org_id": actor.OrgID.Hex(), "from": member.Role, "to": role},
+	})
+	return updated, nil
+}
+
+// RemoveMember removes a member from the organization. Anyone may leave,
+// removing others follows the rules of UpdateMember.
+func (o *OrgService) RemoveMember(ctx context.Context, actor *model.Membership, userID primitive.ObjectID) error {
+	member, err := o.membershipRepo.FindOne(ctx, bson.M{"org_id": actor.OrgID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+
+	if member.UserID == actor.UserID {
+		if err := o.checkLastOwner(ctx, member, ""); err != nil {
+			return err
+		}
+	} else if err := o.checkManage(ctx, actor, member, ""); err != nil {
+		return err
+	}
+
+	if err := o.membershipRepo.Delete(ctx, bson.M{"_id": member.ID}); err != nil {
+		return err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrgMemberRemoved,
+		ActorID:  actor.UserID,
+		TargetID: userID,
+		Metadata: map[string]interface{}{"org_id": actor.OrgID.Hex(), "role": member.Role},
+	})
+	return nil
+}
+
+// checkManage checks that actor may change member to role, an empty role
+// meaning removal.
+func (o *OrgService) checkManage(ctx context.Context, actor, member *model.Membership, role string) error {
+	if !HasOrgRole(actor, model.OrgRoleAdmin) {
+		return ErrOrgAdminRequired
+	}
+	if (member.Role == model.OrgRoleOwner || role == model.OrgRoleOwner) && actor.Role != model.OrgRoleOwner {
+		return ErrOrgOwnerRequired
+	}
+	return o.checkLastOwner(ctx, member, role)
+}
+
+func (o *OrgService) checkLastOwner(ctx context.Context, member *model.Membership, role string) error {
+	if member.Role != model.OrgRoleOwner || role == model.OrgRoleOwner {
+		return nil
+	}
+
+	owners, err := o.membershipRepo.Count(ctx, bson.M{"org_id": member.OrgID, "role": model.OrgRoleOwner})
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOrgOwner
+	}
+	return nil
+}
+
+// Invite mails an invitation to join the organization with the given role.
+// Only owners can invite owners. The invitation is deleted again when the
+// mail cannot be sent.
+func (o *OrgService) Invite(ctx context.Context, actor *model.Membership, inviter *model.User, email, role string) (*model.OrgInvitation, error) {
+	if !model.IsOrgRole(role) {
+		return nil, ErrInvalidOrgRole
+	}
+	if role == model.OrgRoleOwner && actor.Role != model.OrgRoleOwner {
+		return nil, ErrOrgOwnerRequired
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	existing, err := o.userRepo.FindOne(ctx, bson.M{"email": email})
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	if existing != nil {
+		if _, err := o.Membership(ctx, actor.OrgID, existing.ID); err == nil {
+			return nil, ErrAlreadyOrgMember
+		} else if !errors.Is(err, ErrNotOrgMember) {
+			return nil, err
+		}
+	}
+
+	org, err := o.orgRepo.FindOne(ctx, bson.M{"_id": actor.OrgID})
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation := &model.OrgInvitation{
+		ID:        primitive.NewObjectID(),
+		OrgID:     org.ID,
+		Email:     email,
+		Role:      role,
+		TokenHash: utils.HashToken(token),
+		InvitedBy: inviter.ID,
+		ExpiresAt: now.Add(o.config.OrgInvitationTTL),
+		CreatedAt: now,
+	}
+	if err := o.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s/org-invitations/accept?token=%s", o.config.AppURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi,\n\n%s invited you to join %s as %s. Sign in or create an account with this email address and open the link below. It expires in %s.\n\n%s",
+		inviter.Name, org.Name, role, o.config.OrgInvitationTTL, link)
+	if err := o.mailService.Send(ctx, email, "You are invited to join "+org.Name, body); err != nil {
+		// Nobody got the token, keep the invitation out of the pending list
+		if err := o.invitationRepo.Delete(ctx, bson.M{"_id": invitation.ID}); err != nil {
+			log.Printf("Failed to delete unsent invitation %s: %v", invitation.ID.Hex(), err)
+		}
+		return nil, err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrgMemberInvited,
+		ActorID:  inviter.ID,
+		Metadata: map[string]interface{}{"org_id": org.ID.Hex(), "email": email, "role": role},
+	})
+	return invitation, nil
+}
+
+// Invitations lists the pending invitations of an organization.
+func (o *OrgService) Invitations(ctx context.Context, orgID primitive.ObjectID) ([]*model.OrgInvitation, error) {
+	return o.invitationRepo.FindAll(ctx, bson.M{
+		"org_id":      orgID,
+		"accepted_at": bson.M{"$exists": false},
+		"expires_at":  bson.M{"$gt": time.Now()},
+	})
+}
+
+// RevokeInvitation deletes a pending invitation, its link stops working.
+func (o *OrgService) RevokeInvitation(ctx context.Context, orgID, id primitive.ObjectID) error {
+	return o.invitationRepo.Delete(ctx, bson.M{
+		"_id":         id,
+		"org_id":      orgID,
+		"accepted_at": bson.M{"$exists": false},
+	})
+}
+
+// AcceptInvitation adds the user to the organization of the invitation. The
+// invitation is single use and only valid for the address it was sent to.
+func (o *OrgService) AcceptInvitation(ctx context.Context, user *model.User, token string) (*model.Membership, error) {
+	now := time.Now()
+	query := bson.M{
+		"token_hash":  utils.HashToken(token),
+		"accepted_at": bson.M{"$exists": false},
+		"expires_at":  bson.M{"$gt": now},
+	}
+
+	// Claim the invitation first, so two accepts of one token cannot both win
+	invitation, err := o.invitationRepo.FindOneAndUpdate(ctx,
+		bson.M{"$and": []bson.M{query, {"email": strings.ToLower(user.Email)}}},
+		bson.M{"$set": bson.M{"accepted_at": now}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// An invitation for another address stays pending for its invitee
+		if pending, err := o.invitationRepo.FindAll(ctx, query); err == nil && len(pending) > 0 {
+			return nil, ErrInvitationEmailMismatch
+		}
+		return nil, ErrInvalidInvitation
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	membership := &model.Membership{
+		OrgID:     invitation.OrgID,
+		UserID:    user.ID,
+		Role:      invitation.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := o.membershipRepo.Create(ctx, membership); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAlreadyOrgMember
+		}
+		return nil, err
+	}
+
+	o.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditOrgMemberJoined,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]interface{}{"org_id": invitation.OrgID.Hex(), "role": invitation.Role, "invitation_id": invitation.ID.Hex()},
+	})
+	return membership, nil
+}
+
+// SwitchOrg rotates a refresh token into a token pair for the organization,
+// or for the personal space when orgID is empty. The refresh token is used
+// up like on a normal refresh.
+func (o *OrgService) SwitchOrg(ctx context.Context, refreshToken, orgID string, client model.ClientInfo) (*utils.TokenPair, error) {
+	claims, err := o.tokenService.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	user, err := o.userRepo.FindOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if orgID != "" {
+		orgObjectID, err := primitive.ObjectIDFromHex(orgID)
+		if err != nil {
+			return nil, ErrNotOrgMember
+		}
+		if _, err := o.Membership(ctx, orgObjectID, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	claims.OrgID = orgID
+	return o.tokenService.RotateFamily(ctx, claims, user, client)
+}
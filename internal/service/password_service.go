@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidResetToken   = errors.New("invalid or expired reset token")
+	ErrWrongPassword       = errors.New("current password is incorrect")
+	ErrPasswordUnavailable = errors.New("password login is not available for this account")
+)
+
+// PasswordService handles forgotten and changed passwords. Reset tokens are
+// single use, expire after PASSWORD_RESET_TTL and are stored only as hashes.
+type PasswordService struct {
+	userRepo     repository.UserRepository
+	redisClient  *redis.Client
+	tokenService *TokenService
+	mailService  MailService
+	auditService *AuditService
+	config       *config.Config
+}
+
+func NewPasswordService(userRepo repository.UserRepository, redisClient *redis.Client, tokenService *TokenService, mailService MailService, auditService *AuditService, config *config.Config) *PasswordService {
+	return &PasswordService{
+		userRepo:     userRepo,
+		redisClient:  redisClient,
+		tokenService: tokenService,
+		mailService:  mailService,
+		auditService: auditService,
+		config:       config,
+	}
+}
+
+func resetTokenKey(hash string) string {
+	return "password_reset:" + hash
+}
+
+func userResetTokenKey(userID string) string {
+	return "password_reset_user:" + userID
+}
+
+// ForgotPassword mails a reset link in the background. Unknown emails
+// succeed without a mail.
+func (p *PasswordService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := p.userRepo.FindOne(ctx, bson.M{"email": email})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	hash := utils.HashToken(token)
+
+	// Only the newest link works, requesting another one kills the previous
+	previous, err := p.redisClient.Get(ctx, userResetTokenKey(user.ID.Hex())).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := p.redisClient.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, resetTokenKey(previous))
+	}
+	pipe.Set(ctx, resetTokenKey(hash), user.ID.Hex(), p.config.PasswordResetTTL)
+	pipe.Set(ctx, userResetTokenKey(user.ID.Hex()), hash, p.config.PasswordResetTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", p.config.AppURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for this, you can ignore this email.",
+		user.Name, p.config.PasswordResetTTL, link)
+
+	sendInBackground(ctx, p.mailService, user.Email, "Reset your password", body)
+	return nil
+}
+
+// ResetPassword consumes a reset token and sets the new password.
+func (p *PasswordService) ResetPassword(ctx context.Context, token, password string) error {
+	hash := utils.HashToken(token)
+	userID, err := p.redisClient.GetDel(ctx, resetTokenKey(hash)).Result()
+	if err == redis.Nil {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	p.redisClient.Del(ctx, userResetTokenKey(userID))
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	return p.setPassword(ctx, objectID, password, model.AuditPasswordReset)
+}
+
+// ChangePassword sets a new password for a logged in user after checking
+// the current one.
+func (p *PasswordService) ChangePassword(ctx context.Context, user *model.User, currentPassword, password string) error {
+	if user.Password == "" {
+		return ErrPasswordUnavailable
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return ErrWrongPassword
+	}
+
+	return p.setPassword(ctx, user.ID, password, model.AuditPasswordChanged)
+}
+
+// setPassword stores the new hash and logs the user out everywhere, so a
+// session opened with the old password does not survive the change. The
+// change is audited as action, a reset or a change by the user.
+func (p *PasswordService) setPassword(ctx context.Context, userID primitive.ObjectID, password, action string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.userRepo.Update(ctx, bson.M{"password": string(hashedPassword)}, userID); err != nil {
+		return err
+	}
+	p.auditService.Record(ctx, &model.AuditEvent{Action: action, TargetID: userID})
+
+	if err := p.tokenService.RevokeAllSessions(ctx, userID.Hex()); err != nil {
+		log.Printf("Failed to revoke sessions of user %s after password change: %v", userID.Hex(), err)
+		return err
+	}
+	return nil
+}
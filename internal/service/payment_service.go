@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"sync"
+)
+
+var ErrPaymentDeclined = errors.New("payment declined")
+
+// Payment source the fake provider declines
+const FakeDeclinedSource = "decline"
+
+// PaymentProvider charges orders with a payment service and refunds them.
+// source is the client's token for the payment method, as issued by the
+// provider. Refunding a payment that was already refunded succeeds, so an
+// interrupted refund can be tried again.
+type PaymentProvider interface {
+	Charge(ctx context.Context, order *model.Order, source string) (string, error)
+	Refund(ctx context.Context, order *model.Order) error
+}
+
+// NewPaymentProvider returns the provider named in PAYMENT_PROVIDER.
+func NewPaymentProvider(cfg *config.Config) (PaymentProvider, error) {
+	switch cfg.PaymentProvider {
+	case "", "fake":
+		return NewFakePaymentProvider(), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", cfg.PaymentProvider)
+}
+
+// FakePaymentProvider keeps charges in memory and accepts every source but
+// FakeDeclinedSource. It is meant for development and tests.
+type FakePaymentProvider struct {
+	mu       sync.Mutex
+	charges  map[string]float64
+	refunded map[string]bool
+}
+
+func NewFakePaymentProvider() *FakePaymentProvider {
+	return &FakePaymentProvider{
+		charges:  map[string]float64{},
+		refunded: map[string]bool{},
+	}
+}
+
+func (f *FakePaymentProvider) Charge(ctx context.Context, order *model.Order, source string) (string, error) {
+	if source == FakeDeclinedSource {
+		return "", ErrPaymentDeclined
+	}
+
+	token, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return "", err
+	}
+	paymentID := "fake_" + token
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.charges[paymentID] = order.Total
+	return paymentID, nil
+}
+
+func (f *FakePaymentProvider) Refund(ctx context.Context, order *model.Order) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.refunded[order.PaymentID] {
+		return nil
+	}
+	if _, ok := f.charges[order.PaymentID]; !ok {
+		return fmt.Errorf("unknown payment %q", order.PaymentID)
+	}
+	delete(f.charges, order.PaymentID)
+	f.refunded[order.PaymentID] = true
+	return nil
+}
+
+// Charged returns the amount of a payment that has not been refunded.
+func (f *FakePaymentProvider) Charged(paymentID string) (float64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	amount, ok := f.charges[paymentID]
+	return amount, ok
+}
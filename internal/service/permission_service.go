@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleBuiltIn       = errors.New("built-in roles cannot be deleted")
+	ErrAdminRoleFixed    = errors.New("the admin role always has every permission")
+	ErrRoleInUse         = errors.New("role is still assigned to users")
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+const (
+	rolesChannel = "roles:invalidate"
+
+	// The cache is also dropped after this long in case an invalidation
+	// message was missed while the subscriber reconnected
+	permissionCacheTTL = time.Minute
+)
+
+// PermissionService resolves the permissions granted by a user's roles. Role
+// definitions live in the roles collection and are cached in memory; every
+// change is published on Redis so all instances drop their cache.
+type PermissionService struct {
+	roleRepo     repository.RoleRepository
+	userRepo     repository.UserRepository
+	redisClient  *redis.Client
+	auditService *AuditService
+
+	mu       sync.RWMutex
+	cache    map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func NewPermissionService(roleRepo repository.RoleRepository, userRepo repository.UserRepository, redisClient *redis.Client, auditService *AuditService) *PermissionService {
+	return &PermissionService{
+		roleRepo:     roleRepo,
+		userRepo:     userRepo,
+		redisClient:  redisClient,
+		auditService: auditService,
+	}
+}
+
+// EnsureDefaults creates the built-in roles. The admin role is given every
+// permission on each start, so permissions added by new routes reach it.
+func (p *PermissionService) EnsureDefaults(ctx context.Context) error {
+	all := make([]string, len(utils.AllPermissions))
+	for i, perm := range utils.AllPermissions {
+		all[i] = string(perm)
+	}
+
+	defaults := []*model.RoleDefinition{
+		{Name: string(utils.AdminRole), Description: "Full access", Permissions: all},
+		{Name: string(utils.UserRole), Description: "Default role of registered users", Permissions: []string{}},
+	}
+
+	for _, role := range defaults {
+		existing, err := p.roleRepo.FindOne(ctx, role.Name)
+		if err == mongo.ErrNoDocuments {
+			now := time.Now()
+			role.BuiltIn = true
+			role.CreatedAt = now
+			role.UpdatedAt = now
+			if err := p.roleRepo.Create(ctx, role); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if existing.Name == string(utils.AdminRole) && len(existing.Permissions) != len(all) {
+			if _, err := p.roleRepo.Update(ctx, existing.Name, bson.M{"permissions": all}); err != nil {
+				return err
+			}
+		}
+	}
+
+	p.Invalidate()
+	return nil
+}
+
+// HasPermissions reports whether the roles together grant every given permission.
+func (p *PermissionService) HasPermissions(ctx context.Context, roles []string, perms ...utils.Permission) (bool, error) {
+	granted, err := p.rolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, perm := range perms {
+		found := false
+		for _, role := range roles {
+			if granted[role][string(perm)] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Allowed reports whether the principal holds every given permission. API
+// keys are limited to their scopes and never exceed the current permissions
+// of their owner. A service key also lapses once its owner loses
+// apikey:manage, the permission it was created with.
+func (p *PermissionService) Allowed(ctx context.Context, principal *model.Principal, perms ...utils.Permission) (bool, error) {
+	if key := principal.APIKey; key != nil {
+		if key.Scoped() && !hasScopes(key.Scopes, perms) {
+			return false, nil
+		}
+		if key.Type == model.APIKeyService {
+			perms = append(perms[:len(perms):len(perms)], utils.PermAPIKeyManage)
+		}
+	}
+	return p.HasPermissions(ctx, principal.User.Roles, perms...)
+}
+
+// Covers reports whether roles grant at least every permission that other
+// grants, so acting with other's rights gives nothing new.
+func (p *PermissionService) Covers(ctx context.Context, roles, other []string) (bool, error) {
+	granted, err := p.rolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range other {
+		for perm := range granted[role] {
+			found := false
+			for _, own := range roles {
+				if granted[own][perm] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// IsOwnerOrPermitted is the ownership policy: the owner of a resource may
+// act on it, anyone else needs the given permissions. A scoped API key acts
+// on its owner's resources only within its scopes.
+func (p *PermissionService) IsOwnerOrPermitted(ctx context.Context, principal *model.Principal, ownerID primitive.ObjectID, perms ...utils.Permission) (bool, error) {
+	ownsIt := !ownerID.IsZero() && ownerID == principal.User.ID
+	if ownsIt && (principal.APIKey == nil || !principal.APIKey.Scoped()) {
+		return true, nil
+	}
+	return p.Allowed(ctx, principal, perms...)
+}
+
+func (p *PermissionService) rolePermissions(ctx context.Context) (map[string]map[string]bool, error) {
+	p.mu.RLock()
+	cache, loadedAt := p.cache, p.loadedAt
+	p.mu.RUnlock()
+	if cache != nil && time.Since(loadedAt) < permissionCacheTTL {
+		return cache, nil
+	}
+
+	roles, err := p.roleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache = make(map[string]map[string]bool, len(roles))
+	for _, role := range roles {
+		perms := make(map[string]bool, len(role.Permissions))
+		for _, perm := range role.Permissions {
+			perms[perm] = true
+		}
+		cache[role.Name] = perms
+	}
+
+	p.mu.Lock()
+	p.cache, p.loadedAt = cache, time.Now()
+	p.mu.Unlock()
+	return cache, nil
+}
+
+// Invalidate drops the cached role definitions of this instance.
+func (p *PermissionService) Invalidate() {
+	p.mu.Lock()
+	p.cache = nil
+	p.mu.Unlock()
+}
+
+// Subscribe drops the cache whenever another instance changes a role,
+// until ctx is cancelled.
+func (p *PermissionService) Subscribe(ctx context.Context) {
+	pubsub := p.redisClient.Subscribe(ctx, rolesChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			p.Invalidate()
+		}
+	}
+}
+
+func (p *PermissionService) publishChange(ctx context.Context) {
+	p.Invalidate()
+	if err := p.redisClient.Publish(ctx, rolesChannel, "changed").Err(); err != nil {
+		log.Printf("Failed to publish role change: %v", err)
+	}
+}
+
+func (p *PermissionService) ListRoles(ctx context.Context) ([]*model.RoleDefinition, error) {
+	return p.roleRepo.FindAll(ctx)
+}
+
+// RoleExists reports whether a role definition with the name exists.
+func (p *PermissionService) RoleExists(ctx context.Context, name string) (bool, error) {
+	granted, err := p.rolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := granted[name]
+	return ok, nil
+}
+
+func (p *PermissionService) CreateRole(ctx context.Context, actor *model.User, name, description string, permissions []string) (*model.RoleDefinition, error) {
+	if err := validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	now := time.Now()
+	role := &model.RoleDefinition{
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := p.roleRepo.Create(ctx, role); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrRoleExists
+		}
+		return nil, err
+	}
+
+	p.publishChange(ctx)
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditRoleCreated,
+		ActorID:  actor.ID,
+		Metadata: map[string]interface{}{"role": name, "permissions": permissions},
+	})
+	return role, nil
+}
+
+func (p *PermissionService) UpdateRole(ctx context.Context, actor *model.User, name, description string, permissions []string) (*model.RoleDefinition, error) {
+	if name == string(utils.AdminRole) {
+		return nil, ErrAdminRoleFixed
+	}
+	if err := validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	role, err := p.roleRepo.Update(ctx, name, bson.M{
+		"description": description,
+		"permissions": permissions,
+	})
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.publishChange(ctx)
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditRoleUpdated,
+		ActorID:  actor.ID,
+		Metadata: map[string]interface{}{"role": name, "permissions": permissions},
+	})
+	return role, nil
+}
+
+// DeleteRole removes a role that is neither built in nor held by any user.
+func (p *PermissionService) DeleteRole(ctx context.Context, actor *model.User, name string) error {
+	role, err := p.roleRepo.FindOne(ctx, name)
+	if err == mongo.ErrNoDocuments {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrRoleBuiltIn
+	}
+
+	holders, err := p.userRepo.Count(ctx, bson.D{{Key: "roles", Value: name}})
+	if err != nil {
+		return err
+	}
+	if holders > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := p.roleRepo.Delete(ctx, name); err != nil {
+		return err
+	}
+
+	p.publishChange(ctx)
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditRoleDeleted,
+		ActorID:  actor.ID,
+		Metadata: map[string]interface{}{"role": name},
+	})
+	return nil
+}
+
+func hasScopes(scopes []string, perms []utils.Permission) bool {
+	for _, perm := range perms {
+		found := false
+		for _, scope := range scopes {
+			if scope == string(perm) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func validatePermissions(permissions []string) error {
+	for _, perm := range permissions {
+		if !utils.IsKnownPermission(perm) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, perm)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,576 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrErasureScheduled    = errors.New("erasure is already scheduled")
+	ErrErasureNotScheduled = errors.New("no erasure is scheduled")
+	ErrUserNotDeleted      = errors.New("only deleted users can be purged")
+	ErrLastAdminErasure    = errors.New("the last admin cannot be erased")
+)
+
+const (
+	// How often the cleanup worker erases accounts past their grace period and
+	// purges deleted ones
+	cleanupInterval = time.Hour
+	// Deleted users purged by one run of the cleanup worker
+	purgeBatchSize = 100
+	// How long one erasure attempt holds an account before it is retried
+	erasureLease = time.Hour
+
+	exportBatchSize = 500
+)
+
+// PrivacyService answers data subject requests: an export of everything
+// stored about a user, and the erasure of the account after a grace period
+// during which the user can change their mind. It also purges soft-deleted
+// users for good.
+type PrivacyService struct {
+	userRepo       repository.UserRepository
+	productRepo    repository.ProductRepository
+	fileRepo       repository.LocalFileRepository
+	membershipRepo repository.MembershipRepository
+	orgRepo        repository.OrganizationRepository
+	apiKeyRepo     repository.APIKeyRepository
+	orderRepo      repository.OrderRepository
+	cartRepo       repository.CartRepository
+	tokenService   *TokenService
+	auditService   *AuditService
+	mailService    MailService
+	config         *config.Config
+}
+
+func NewPrivacyService(userRepo repository.UserRepository, productRepo repository.ProductRepository, fileRepo repository.LocalFileRepository, membershipRepo repository.MembershipRepository, orgRepo repository.OrganizationRepository, apiKeyRepo repository.APIKeyRepository, orderRepo repository.OrderRepository, cartRepo repository.CartRepository, tokenService *TokenService, auditService *AuditService, mailService MailService, config *config.Config) *PrivacyService {
+	return &PrivacyService{
+		userRepo:       userRepo,
+		productRepo:    productRepo,
+		fileRepo:       fileRepo,
+		membershipRepo: membershipRepo,
+		orgRepo:        orgRepo,
+		apiKeyRepo:     apiKeyRepo,
+		orderRepo:      orderRepo,
+		cartRepo:       cartRepo,
+		tokenService:   tokenService,
+		auditService:   auditService,
+		mailService:    mailService,
+		config:         config,
+	}
+}
+
+// Export writes a ZIP archive of the user's profile, organization
+// memberships, products, orders, file metadata with the files themselves,
+// and audit history. Products and files are collected from the personal
+// space and every organization of the user.
+func (p *PrivacyService) Export(ctx context.Context, user *model.User, w io.Writer) error {
+	memberships, err := p.membershipRepo.FindAll(ctx, bson.M{"user_id": user.ID})
+	if err != nil {
+		return err
+	}
+
+	scopes := []context.Context{ctx}
+	for _, membership := range memberships {
+		scopes = append(scopes, repository.WithOrg(ctx, membership.OrgID))
+	}
+
+	products := []*model.Product{}
+	files := []*model.FileStorage{}
+	for _, scoped := range scopes {
+		found, err := p.userProducts(scoped, user.ID)
+		if err != nil {
+			return err
+		}
+		products = append(products, found...)
+
+		stored, err := p.fileRepo.FindAll(scoped, bson.D{{Key: "user_id", Value: user.ID}}, nil)
+		if err != nil {
+			return err
+		}
+		files = append(files, stored...)
+	}
+
+	orders, err := p.userOrders(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for name, data := range map[string]interface{}{
+		"profile.json":       user,
+		"organizations.json": memberships,
+		"products.json":      products,
+		"orders.json":        orders,
+		"files.json":         files,
+	} {
+		if err := writeArchiveJSON(archive, name, data); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		if err := writeArchiveFile(archive, file); err != nil {
+			return err
+		}
+	}
+
+	entry, err := archive.Create("audit.ndjson")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	if err := p.auditService.ForUser(ctx, user.ID, func(event *model.AuditEvent) error {
+		return encoder.Encode(event)
+	}); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditDataExported,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]interface{}{"products": len(products), "orders": len(orders), "files": len(files)},
+	})
+	return nil
+}
+
+// userProducts pages through the user's products in the scope of ctx.
+func (p *PrivacyService) userProducts(ctx context.Context, userID primitive.ObjectID) ([]*model.Product, error) {
+	var products []*model.Product
+	for skip := int64(0); ; skip += exportBatchSize {
+		opts := options.Find().SetSkip(skip).SetLimit(exportBatchSize)
+		page, err := p.productRepo.FindAll(ctx, bson.D{{Key: "user_id", Value: userID}}, opts)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, page...)
+		if len(page) < exportBatchSize {
+			return products, nil
+		}
+	}
+}
+
+// userOrders pages through the user's orders, oldest first.
+func (p *PrivacyService) userOrders(ctx context.Context, userID primitive.ObjectID) ([]*model.Order, error) {
+	orders := []*model.Order{}
+	for skip := int64(0); ; skip += exportBatchSize {
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetSkip(skip).SetLimit(exportBatchSize)
+		page, err := p.orderRepo.FindAll(ctx, bson.D{{Key: "user_id", Value: userID}}, opts)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, page...)
+		if len(page) < exportBatchSize {
+			return orders, nil
+		}
+	}
+}
+
+func writeArchiveJSON(archive *zip.Writer, name string, data interface{}) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// writeArchiveFile copies an uploaded file into files/ of the archive. Files
+// missing from disk are skipped, their metadata is still exported.
+func writeArchiveFile(archive *zip.Writer, file *model.FileStorage) error {
+	src, err := os.Open(filepath.Join(file.Dir, file.Name))
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("export: file %s is missing from %s", file.ID.Hex(), file.Dir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	entry, err := archive.Create(fmt.Sprintf("files/%s-%s", file.ID.Hex(), filepath.Base(file.Original)))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, src)
+	return err
+}
+
+// RequestErasure schedules the erasure of the account after
+// ERASURE_GRACE_PERIOD. Accounts with a password must confirm it. The last
+// admin not scheduled for erasure themselves must hand the role on first.
+func (p *PrivacyService) RequestErasure(ctx context.Context, user *model.User, password string) (*model.User, error) {
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return nil, ErrInvalidCredentials
+		}
+	}
+
+	last, err := p.isLastAdmin(ctx, user, bson.D{{Key: "erasure_scheduled_at", Value: bson.M{"$exists": false}}})
+	if err != nil {
+		return nil, err
+	}
+	if last {
+		return nil, ErrLastAdminErasure
+	}
+
+	scheduledAt := time.Now().Add(p.config.ErasureGracePeriod)
+	updated, err := p.userRepo.FindOneAndUpdate(ctx,
+		bson.M{"_id": user.ID, "erasure_scheduled_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"erasure_scheduled_at": scheduledAt}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrErasureScheduled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour account and its data will be erased on %s. Until then you can sign in and cancel the erasure.\n\nIf you did not ask for this, sign in, cancel it and change your password.",
+		user.Name, scheduledAt.Format(time.RFC1123))
+	if err := p.mailService.Send(ctx, user.Email, "Your account will be erased", body); err != nil {
+		log.Printf("Failed to send erasure notice to user %s: %v", user.ID.Hex(), err)
+	}
+
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditErasureRequested,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Metadata: map[string]interface{}{"scheduled_at": scheduledAt},
+	})
+	return updated, nil
+}
+
+// CancelErasure keeps the account, it is only possible during the grace period.
+func (p *PrivacyService) CancelErasure(ctx context.Context, user *model.User) error {
+	_, err := p.userRepo.FindOneAndUpdate(ctx,
+		bson.M{"_id": user.ID, "erasure_scheduled_at": bson.M{"$exists": true}, "erased_at": bson.M{"$exists": false}},
+		bson.M{"$unset": bson.M{"erasure_scheduled_at": ""}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrErasureNotScheduled
+	}
+	if err != nil {
+		return err
+	}
+
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditErasureCancelled,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+	})
+	return nil
+}
+
+// StartCleanup erases the accounts whose grace period has ended and purges
+// users deleted longer than USER_PURGE_AFTER ago, every cleanupInterval until
+// ctx is done.
+func (p *PrivacyService) StartCleanup(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		p.EraseDue(ctx)
+		p.PurgeDeleted(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EraseDue erases every account past its grace period. An account is claimed
+// by moving its schedule erasureLease ahead, so two instances never erase it
+// at once and a failed attempt is retried later.
+func (p *PrivacyService) EraseDue(ctx context.Context) {
+	for {
+		now := time.Now()
+		user, err := p.userRepo.FindOneAndUpdate(ctx,
+			bson.M{"erasure_scheduled_at": bson.M{"$lte": now}, "erased_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"erasure_scheduled_at": now.Add(erasureLease)}},
+		)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to claim an account for erasure: %v", err)
+			return
+		}
+
+		if err := p.Erase(ctx, user); err != nil {
+			log.Printf("Failed to erase user %s, retrying in %s: %v", user.ID.Hex(), erasureLease, err)
+		}
+	}
+}
+
+// Erase removes the user's content as described in removeContent and
+// anonymises the account. The account document stays so audit events keep
+// pointing at it. Every step can run again after a failure.
+func (p *PrivacyService) Erase(ctx context.Context, user *model.User) error {
+	summary, err := p.removeContent(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.userRepo.FindOneAndUpdate(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"name":               "Deleted user",
+			"email":              fmt.Sprintf("erased-%s@invalid", user.ID.Hex()),
+			"password":           "",
+			"roles":              []string{},
+			"email_verified":     false,
+			"two_factor_enabled": false,
+			"erased_at":          time.Now(),
+		},
+		"$unset": bson.M{
+			"email_verified_at":    "",
+			"totp_secret":          "",
+			"recovery_codes":       "",
+			"identities":           "",
+			"products":             "",
+			"erasure_scheduled_at": "",
+		},
+	}); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nAs you asked, your account and its data have been erased.", user.Name)
+	if err := p.mailService.Send(ctx, user.Email, "Your account has been erased", body); err != nil {
+		log.Printf("Failed to send erasure confirmation to user %s: %v", user.ID.Hex(), err)
+	}
+
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditUserErased,
+		TargetID: user.ID,
+		Metadata: summary,
+	})
+	return nil
+}
+
+// Purge removes a soft-deleted user for good, together with their content
+// as described in removeContent. A deleted admin is kept while no other
+// admin is left, restoring them is the way back in.
+func (p *PrivacyService) Purge(ctx context.Context, id primitive.ObjectID) error {
+	user, err := p.userRepo.FindOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrUserNotDeleted
+	}
+	if err != nil {
+		return err
+	}
+
+	last, err := p.isLastAdmin(ctx, user, nil)
+	if err != nil {
+		return err
+	}
+	if last {
+		return ErrLastAdminErasure
+	}
+
+	summary, err := p.removeContent(ctx, user)
+	if err != nil {
+		return err
+	}
+	if err := p.userRepo.Purge(ctx, user.ID); err != nil {
+		return err
+	}
+
+	summary["email"] = user.Email
+	p.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditUserPurged,
+		TargetID: user.ID,
+		Metadata: summary,
+	})
+	return nil
+}
+
+// isLastAdmin reports whether the user is an admin and no other active user
+// matching query is one.
+func (p *PrivacyService) isLastAdmin(ctx context.Context, user *model.User, query bson.D) (bool, error) {
+	if !hasRole(user, string(utils.AdminRole)) {
+		return false, nil
+	}
+	others, err := p.userRepo.Count(ctx, append(bson.D{
+		{Key: "_id", Value: bson.M{"$ne": user.ID}},
+		{Key: "roles", Value: string(utils.AdminRole)},
+	}, query...))
+	if err != nil {
+		return false, err
+	}
+	return others == 0, nil
+}
+
+// PurgeDeleted purges up to purgeBatchSize users deleted longer than
+// USER_PURGE_AFTER ago, 0 keeps deleted users until purged by an admin.
+func (p *PrivacyService) PurgeDeleted(ctx context.Context) {
+	if p.config.UserPurgeAfter <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-p.config.UserPurgeAfter)
+	users, err := p.userRepo.FindAll(ctx, bson.D{{Key: "deleted_at", Value: bson.M{"$lte": cutoff}}}, options.Find().SetLimit(purgeBatchSize))
+	if err != nil {
+		log.Printf("Failed to find deleted users to purge: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if err := p.Purge(ctx, user.ID); err != nil {
+			log.Printf("Failed to purge user %s: %v", user.ID.Hex(), err)
+		}
+	}
+}
+
+// removeContent revokes the user's sessions and API keys, hands their
+// content in organizations over to another member and deletes their cart and
+// their personal products and files. It returns what was done for the audit
+// event.
+//
+// Orders are kept: sales records must be retained for tax and accounting.
+// They hold no personal data of their own, only the user ID, which points
+// at the anonymised account or at nothing once the user is purged.
+func (p *PrivacyService) removeContent(ctx context.Context, user *model.User) (map[string]interface{}, error) {
+	if err := p.tokenService.RevokeAllSessions(ctx, user.ID.Hex()); err != nil {
+		return nil, err
+	}
+
+	keys, err := p.apiKeyRepo.FindAll(ctx, bson.M{"user_id": user.ID, "revoked_at": bson.M{"$exists": false}})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if _, err := p.apiKeyRepo.Update(ctx, bson.M{"revoked_at": time.Now()}, key.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	summary := map[string]interface{}{}
+	memberships, err := p.membershipRepo.FindAll(ctx, bson.M{"user_id": user.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		if err := p.leaveOrg(ctx, user, membership, summary); err != nil {
+			return nil, err
+		}
+	}
+
+	products, err := p.productRepo.DeleteMany(ctx, bson.D{{Key: "user_id", Value: user.ID}})
+	if err != nil {
+		return nil, err
+	}
+	files, err := p.deleteFiles(ctx, bson.D{{Key: "user_id", Value: user.ID}})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.cartRepo.Delete(ctx, bson.M{"user_id": user.ID}); err != nil {
+		return nil, err
+	}
+	summary["products_deleted"] = products
+	summary["files_deleted"] = files
+	return summary, nil
+}
+
+// leaveOrg removes the user from an organization. Their products and files
+// there go to an owner, or to the oldest admin or member who is promoted to
+// owner when the user was the last one. An organization without other
+// members is deleted together with its content.
+func (p *PrivacyService) leaveOrg(ctx context.Context, user *model.User, membership *model.Membership, summary map[string]interface{}) error {
+	orgCtx := repository.WithOrg(ctx, membership.OrgID)
+	members, err := p.membershipRepo.FindAll(ctx, bson.M{"org_id": membership.OrgID})
+	if err != nil {
+		return err
+	}
+
+	var heir *model.Membership
+	for _, role := range []string{model.OrgRoleOwner, model.OrgRoleAdmin, model.OrgRoleMember} {
+		for _, member := range members {
+			if member.UserID != user.ID && member.Role == role {
+				heir = member
+				break
+			}
+		}
+		if heir != nil {
+			break
+		}
+	}
+
+	if heir == nil {
+		if _, err := p.productRepo.DeleteMany(orgCtx, bson.D{}); err != nil {
+			return err
+		}
+		if _, err := p.deleteFiles(orgCtx, bson.D{}); err != nil {
+			return err
+		}
+		if err := p.orgRepo.Delete(ctx, membership.OrgID); err != nil {
+			return err
+		}
+		summary["orgs_deleted"] = countOf(summary["orgs_deleted"]) + 1
+	} else {
+		if heir.Role != model.OrgRoleOwner {
+			if _, err := p.membershipRepo.Update(ctx, bson.M{"_id": heir.ID}, bson.M{"role": model.OrgRoleOwner}); err != nil {
+				return err
+			}
+		}
+
+		reassign := bson.M{"$set": bson.M{"user_id": heir.UserID}}
+		products, err := p.productRepo.UpdateMany(orgCtx, bson.D{{Key: "user_id", Value: user.ID}}, reassign)
+		if err != nil {
+			return err
+		}
+		files, err := p.fileRepo.UpdateMany(orgCtx, bson.D{{Key: "user_id", Value: user.ID}}, reassign)
+		if err != nil {
+			return err
+		}
+		summary["products_reassigned"] = countOf(summary["products_reassigned"]) + products
+		summary["files_reassigned"] = countOf(summary["files_reassigned"]) + files
+	}
+
+	return p.membershipRepo.Delete(ctx, bson.M{"_id": membership.ID})
+}
+
+// deleteFiles removes the matching files of the scope of ctx from disk and
+// from the files collection.
+func (p *PrivacyService) deleteFiles(ctx context.Context, query bson.D) (int64, error) {
+	files, err := p.fileRepo.FindAll(ctx, query, nil)
+	if err != nil {
+		return 0, err
+	}
+	for _, file := range files {
+		if err := p.fileRepo.Delete(ctx, file.ID); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(files)), nil
+}
+
+func countOf(value interface{}) int64 {
+	count, _ := value.(int64)
+	return count
+}
@@ -2,40 +2,97 @@ package service
 
 import (
 	"context"
+	"errors"
 	"example-go-project/internal/dto"
 	"example-go-project/internal/model"
 	"example-go-project/internal/repository"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrProductVersionMismatch = errors.New("product has been changed since it was read")
+
+// ProductService manages products. Every change increments the version of the
+// product, callers pass the versions they expect to find to detect changes
+// made in the meantime. Stock is left to InventoryService.
 type ProductService struct {
-	productRepo repository.ProductRepository
+	productRepo      repository.ProductRepository
+	inventoryService *InventoryService
+	categoryService  *CategoryService
+	auditService     *AuditService
 }
 
-func NewProductService(productRepo repository.ProductRepository) *ProductService {
+func NewProductService(productRepo repository.ProductRepository, inventoryService *InventoryService, categoryService *CategoryService, auditService *AuditService) *ProductService {
 	return &ProductService{
-		productRepo: productRepo,
+		productRepo:      productRepo,
+		inventoryService: inventoryService,
+		categoryService:  categoryService,
+		auditService:     auditService,
+	}
+}
+
+// categoryRef resolves the category ID of a product, nil for none. It fails
+// with ErrUnknownCategory when no such category is in the scope of ctx.
+func (p *ProductService) categoryRef(ctx context.Context, id string) (*primitive.ObjectID, error) {
+	if id == "" {
+		return nil, nil
+	}
+	categoryID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrUnknownCategory
+	}
+	if _, err := p.categoryService.FindByID(ctx, categoryID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUnknownCategory
+		}
+		return nil, err
 	}
+	return &categoryID, nil
 }
 
+// CreateProduct saves a product without stock and books the requested stock
+// as its first receipt, so the ledger accounts for all of it.
 func (p *ProductService) CreateProduct(ctx context.Context, payload *dto.CreateProductRequest, userId primitive.ObjectID) (*model.Product, error) {
+	categoryID, err := p.categoryRef(ctx, payload.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	req := &model.Product{
-		Name:      payload.Name,
-		Price:     payload.Price,
-		Stock:     payload.Stock,
-		UserID:    userId,
-		CreatedAt: now,
-		UpdatedAt: now,
+		Name:       payload.Name,
+		Price:      payload.Price,
+		Version:    1,
+		CategoryID: categoryID,
+		Tags:       NormalizeTags(payload.Tags),
+		UserID:     userId,
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
 	res, err := p.productRepo.Create(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+
+	if *payload.Stock > 0 {
+		movement, err := p.inventoryService.Record(ctx, res.ID, model.StockReceipt, *payload.Stock, "Opening stock", nil)
+		if err != nil {
+			if deleteErr := p.productRepo.Delete(ctx, bson.M{"_id": res.ID}); deleteErr != nil {
+				log.Printf("Failed to remove product %s without its opening stock: %v", res.ID.Hex(), deleteErr)
+			}
+			return nil, err
+		}
+		res.Stock = movement.StockAfter
+	}
+
+	event := &model.AuditEvent{Action: model.AuditProductCreated, TargetID: res.ID}
+	_, event.After = AuditDiff(nil, res)
+	p.auditService.Record(ctx, event)
 	return res, nil
 }
 
@@ -50,3 +107,110 @@ func (p *ProductService) FindAll(ctx context.Context, query bson.D, opts *option
 func (p *ProductService) Count(ctx context.Context, query bson.D) (int64, error) {
 	return p.productRepo.Count(ctx, query)
 }
+
+func (p *ProductService) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Product, error) {
+	return p.productRepo.FindOne(ctx, bson.M{"_id": id})
+}
+
+// versionQuery matches the product in one of the expected versions, any
+// version when none is expected. Products created before versioning have no
+// version field and count as version 0.
+func versionQuery(id primitive.ObjectID, versions []int64) bson.M {
+	query := bson.M{"_id": id}
+	if versions == nil {
+		return query
+	}
+
+	in := bson.A{}
+	for _, version := range versions {
+		in = append(in, version)
+		if version == 0 {
+			in = append(in, nil)
+		}
+	}
+	query["version"] = bson.M{"$in": in}
+	return query
+}
+
+// Update replaces the editable fields of the product. With expected versions
+// it fails with ErrProductVersionMismatch when the product has another one.
+func (p *ProductService) Update(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateProductRequest, versions []int64) (*model.Product, error) {
+	before, err := p.productRepo.FindOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	var category string
+	if payload.CategoryID != nil {
+		category = *payload.CategoryID
+	}
+	categoryID, err := p.categoryRef(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{
+		"name":       payload.Name,
+		"price":      *payload.Price,
+		"updated_at": time.Now(),
+	}
+	unset := bson.M{}
+	if categoryID != nil {
+		set["category_id"] = *categoryID
+	} else {
+		unset["category_id"] = ""
+	}
+	if tags := NormalizeTags(payload.Tags); len(tags) > 0 {
+		set["tags"] = tags
+	} else {
+		unset["tags"] = ""
+	}
+
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	after, err := p.productRepo.FindOneAndUpdate(ctx, versionQuery(id, versions), update)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, p.missingOrChanged(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	event := &model.AuditEvent{Action: model.AuditProductUpdated, TargetID: id}
+	event.Before, event.After = AuditDiff(before, after)
+	p.auditService.Record(ctx, event)
+	return after, nil
+}
+
+// Delete removes the product. With expected versions it fails with
+// ErrProductVersionMismatch when the product has another one.
+func (p *ProductService) Delete(ctx context.Context, id primitive.ObjectID, versions []int64) error {
+	before, err := p.productRepo.FindOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	err = p.productRepo.Delete(ctx, versionQuery(id, versions))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return p.missingOrChanged(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	event := &model.AuditEvent{Action: model.AuditProductDeleted, TargetID: id}
+	event.Before, _ = AuditDiff(before, nil)
+	p.auditService.Record(ctx, event)
+	return nil
+}
+
+// missingOrChanged tells why a conditional write matched nothing: the
+// product is gone, or it has a version the caller did not expect.
+func (p *ProductService) missingOrChanged(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := p.productRepo.FindOne(ctx, bson.M{"_id": id}); err != nil {
+		return err
+	}
+	return ErrProductVersionMismatch
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/utils"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrLastAdmin = errors.New("the last admin cannot be demoted")
+
+// RoleService grants and revokes user roles. Roles are read from the stored
+// user on every request, so a change applies to existing sessions at once.
+type RoleService struct {
+	userRepo          repository.UserRepository
+	permissionService *PermissionService
+	auditService      *AuditService
+}
+
+func NewRoleService(userRepo repository.UserRepository, permissionService *PermissionService, auditService *AuditService) *RoleService {
+	return &RoleService{
+		userRepo:          userRepo,
+		permissionService: permissionService,
+		auditService:      auditService,
+	}
+}
+
+// GrantRole adds a role to the user. Granting a role the user already has
+// changes nothing and is not audited.
+func (r *RoleService) GrantRole(ctx context.Context, actor *model.User, userID primitive.ObjectID, role string, ip string) (*model.User, error) {
+	exists, err := r.permissionService.RoleExists(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+
+	user, err := r.userRepo.FindOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	if hasRole(user, role) {
+		return user, nil
+	}
+
+	user, err = r.userRepo.FindOneAndUpdate(ctx, bson.M{"_id": userID}, bson.M{
+		"$addToSet": bson.M{"roles": role},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditRoleGranted,
+		ActorID:  actor.ID,
+		TargetID: userID,
+		Metadata: map[string]interface{}{"role": role},
+		IP:       ip,
+	})
+	return user, nil
+}
+
+// RevokeRole removes a role from the user, refusing to remove the admin role
+// from the only remaining admin.
+func (r *RoleService) RevokeRole(ctx context.Context, actor *model.User, userID primitive.ObjectID, role string, ip string) (*model.User, error) {
+	user, err := r.userRepo.FindOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	if !hasRole(user, role) {
+		return user, nil
+	}
+
+	isAdminRole := role == string(utils.AdminRole)
+	if isAdminRole {
+		admins, err := r.countAdmins(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			return nil, ErrLastAdmin
+		}
+	}
+
+	user, err = r.userRepo.FindOneAndUpdate(ctx, bson.M{"_id": userID}, bson.M{
+		"$pull": bson.M{"roles": role},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Two admins demoting each other at the same time both pass the count
+	// above, so check again and undo if no admin is left
+	if isAdminRole {
+		admins, err := r.countAdmins(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if admins == 0 {
+			if _, err := r.userRepo.FindOneAndUpdate(ctx, bson.M{"_id": userID}, bson.M{
+				"$addToSet": bson.M{"roles": role},
+			}); err != nil {
+				return nil, err
+			}
+			return nil, ErrLastAdmin
+		}
+	}
+
+	r.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditRoleRevoked,
+		ActorID:  actor.ID,
+		TargetID: userID,
+		Metadata: map[string]interface{}{"role": role},
+		IP:       ip,
+	})
+	return user, nil
+}
+
+// EnsureAdmin grants the admin role to the account with the given email while
+// no admin exists, so a fresh install can get its first admin without
+// self-assigned roles.
+func (r *RoleService) EnsureAdmin(ctx context.Context, email string) error {
+	if email == "" {
+		return nil
+	}
+
+	admins, err := r.countAdmins(ctx)
+	if err != nil || admins > 0 {
+		return err
+	}
+
+	user, err := r.userRepo.FindOneAndUpdate(ctx, bson.M{"email": email}, bson.M{
+		"$addToSet": bson.M{"roles": string(utils.AdminRole)},
+	})
+	if err == mongo.ErrNoDocuments {
+		log.Printf("No admin exists and %s is not registered yet, register it and restart", email)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditRoleGranted,
+		TargetID: user.ID,
+		Metadata: map[string]interface{}{"role": string(utils.AdminRole), "reason": "bootstrap"},
+	})
+	log.Printf("Granted admin to %s", email)
+	return nil
+}
+
+func (r *RoleService) countAdmins(ctx context.Context) (int64, error) {
+	return r.userRepo.Count(ctx, bson.D{{Key: "roles", Value: string(utils.AdminRole)}})
+}
+
+func hasRole(user *model.User, role string) bool {
+	for _, r := range user.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// rotateFamilyScript swaps the current refresh token of a family only when
+// the presented one is still current, so two refreshes cannot both succeed.
+// It returns 1 on success, 0 on reuse and -1 when the family is gone.
+var rotateFamilyScript = redis.NewScript(`
+local current = redis.call("HGET", KEYS[1], "current")
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	return 0
+end
+redis.call("HSET", KEYS[1], "current", ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+return 1
+`)
+
+// touchSessionScript updates last_seen_at without recreating a revoked family.
+var touchSessionScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1])
+end
+return 1
+`)
+
+// TokenService keeps the Redis bookkeeping of issued tokens. Every login
+// starts a refresh token family; a refresh rotates the family's current
+// refresh token, and presenting an already rotated one revokes the family
+// together with every access token issued from it. A family is what the API
+// exposes as a session, so its hash also carries the device details.
+type TokenService struct {
+	redisClient  *redis.Client
+	auth         *utils.AuthHandler
+	auditService *AuditService
+	config       *config.Config
+}
+
+func NewTokenService(redisClient *redis.Client, auth *utils.AuthHandler, auditService *AuditService, config *config.Config) *TokenService {
+	return &TokenService{
+		redisClient:  redisClient,
+		auth:         auth,
+		auditService: auditService,
+		config:       config,
+	}
+}
+
+func familyKey(familyID string) string {
+	return "token_family:" + familyID
+}
+
+func familyAccessKey(familyID string) string {
+	return "token_family:" + familyID + ":access"
+}
+
+func userSessionsKey(userID string) string {
+	return "user_sessions:" + userID
+}
+
+// StartFamily issues the first token pair of a new family. amr lists how the
+// user authenticated and is carried over to every token of the family.
+func (t *TokenService) StartFamily(ctx context.Context, user *model.User, client model.ClientInfo, amr []string) (*utils.TokenPair, error) {
+	familyID := uuid.New().String()
+	tokenPair, err := t.auth.GenerateTokenPair(utils.JWTClaims{
+		UserID:   user.ID.Hex(),
+		Roles:    user.Roles,
+		FamilyID: familyID,
+		AMR:      amr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := t.auth.RefreshExpiry()
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	pipe := t.redisClient.TxPipeline()
+	pipe.HSet(ctx, familyKey(familyID),
+		"user_id", user.ID.Hex(),
+		"current", tokenPair.RefreshTokenID,
+		"amr", strings.Join(amr, ","),
+		"device_name", client.DeviceName,
+		"user_agent", client.UserAgent,
+		"ip", client.IP,
+		"created_at", now,
+		"last_seen_at", now,
+	)
+	pipe.Expire(ctx, familyKey(familyID), lifetime)
+	pipe.SAdd(ctx, userSessionsKey(user.ID.Hex()), familyID)
+	pipe.Expire(ctx, userSessionsKey(user.ID.Hex()), lifetime)
+	t.trackAccessToken(ctx, pipe, familyID, user, tokenPair.AccessToken)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	t.auditService.Record(ctx, &model.AuditEvent{
+		Action:    model.AuditLogin,
+		ActorID:   user.ID,
+		TargetID:  user.ID,
+		Metadata:  map[string]interface{}{"session_id": familyID, "amr": amr, "device_name": client.DeviceName},
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	})
+	return tokenPair, nil
+}
+
+// IssueImpersonationToken signs an access token for target that names the
+// impersonator in its act claim. It belongs to no family, so it cannot be
+// refreshed and ends after ttl.
+func (t *TokenService) IssueImpersonationToken(ctx context.Context, impersonator, target *model.User, amr []string, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	token, err := t.auth.GenerateToken(utils.JWTClaims{
+		UserID: target.ID.Hex(),
+		Roles:  target.Roles,
+		AMR:    amr,
+		Act:    &utils.Actor{Subject: impersonator.ID.Hex()},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if err := t.redisClient.Set(ctx, token, target.ID.Hex(), ttl).Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// RotateFamily exchanges a validated refresh token for a new token pair of
+// the same family. The pair is issued for claims.OrgID, callers switching
+// organization set it after checking the membership.
+func (t *TokenService) RotateFamily(ctx context.Context, claims *utils.JWTClaims, user *model.User, client model.ClientInfo) (*utils.TokenPair, error) {
+	if claims.FamilyID == "" || claims.UserID != user.ID.Hex() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	amr, err := t.redisClient.HGet(ctx, familyKey(claims.FamilyID), "amr").Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	tokenPair, err := t.auth.GenerateTokenPair(utils.JWTClaims{
+		UserID:   user.ID.Hex(),
+		Roles:    user.Roles,
+		FamilyID: claims.FamilyID,
+		AMR:      splitList(amr),
+		OrgID:    claims.OrgID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := t.auth.RefreshExpiry()
+	result, err := rotateFamilyScript.Run(ctx, t.redisClient,
+		[]string{familyKey(claims.FamilyID), familyAccessKey(claims.FamilyID)},
+		claims.ID, tokenPair.RefreshTokenID, lifetime.Milliseconds(),
+	).Int()
+	if err != nil {
+		return nil, err
+	}
+
+	switch result {
+	case -1:
+		return nil, ErrInvalidRefreshToken
+	case 0:
+		log.Printf("security: refresh token reuse detected for user %s, revoking token family %s", claims.UserID, claims.FamilyID)
+		if err := t.RevokeFamily(ctx, claims.FamilyID); err != nil {
+			return nil, err
+		}
+		t.auditService.Record(ctx, &model.AuditEvent{
+			Action:    model.AuditRefreshTokenReused,
+			ActorID:   user.ID,
+			TargetID:  user.ID,
+			Metadata:  map[string]interface{}{"session_id": claims.FamilyID},
+			IP:        client.IP,
+			UserAgent: client.UserAgent,
+		})
+		return nil, ErrRefreshTokenReused
+	}
+
+	pipe := t.redisClient.TxPipeline()
+	pipe.HSet(ctx, familyKey(claims.FamilyID),
+		"user_agent", client.UserAgent,
+		"ip", client.IP,
+		"last_seen_at", strconv.FormatInt(time.Now().Unix(), 10),
+	)
+	pipe.Expire(ctx, userSessionsKey(user.ID.Hex()), lifetime)
+	t.trackAccessToken(ctx, pipe, claims.FamilyID, user, tokenPair.AccessToken)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	t.auditService.Record(ctx, &model.AuditEvent{
+		Action:    model.AuditTokenRefreshed,
+		ActorID:   user.ID,
+		TargetID:  user.ID,
+		Metadata:  map[string]interface{}{"session_id": claims.FamilyID},
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	})
+	return tokenPair, nil
+}
+
+// RevokeFamily ends a family: its refresh token stops working and every
+// access token issued from it is revoked.
+func (t *TokenService) RevokeFamily(ctx context.Context, familyID string) error {
+	accessTokens, err := t.redisClient.SMembers(ctx, familyAccessKey(familyID)).Result()
+	if err != nil {
+		return err
+	}
+
+	userID, err := t.redisClient.HGet(ctx, familyKey(familyID), "user_id").Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := t.redisClient.TxPipeline()
+	if userID != "" {
+		pipe.SRem(ctx, userSessionsKey(userID), familyID)
+	}
+	expires := t.auth.AccessExpiry()
+	for _, accessToken := range accessTokens {
+		pipe.Set(ctx, "blacklist:"+accessToken, "true", expires)
+		pipe.Del(ctx, accessToken)
+	}
+	pipe.Del(ctx, familyKey(familyID), familyAccessKey(familyID))
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ListSessions returns the live sessions of a user, newest first. Families
+// that expired on their own are dropped from the user's index on the way.
+func (t *TokenService) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	familyIDs, err := t.redisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*model.Session, 0, len(familyIDs))
+	for _, familyID := range familyIDs {
+		session, err := t.FindSession(ctx, familyID)
+		if err == ErrSessionNotFound {
+			t.redisClient.SRem(ctx, userSessionsKey(userID), familyID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (t *TokenService) FindSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	values, err := t.redisClient.HGetAll(ctx, familyKey(sessionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	return &model.Session{
+		ID:         sessionID,
+		UserID:     values["user_id"],
+		DeviceName: values["device_name"],
+		UserAgent:  values["user_agent"],
+		IP:         values["ip"],
+		CreatedAt:  parseUnix(values["created_at"]),
+		LastSeenAt: parseUnix(values["last_seen_at"]),
+	}, nil
+}
+
+// RevokeSession revokes one session of a user.
+func (t *TokenService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	session, err := t.FindSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	if err := t.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+
+	targetID, _ := primitive.ObjectIDFromHex(userID)
+	t.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditSessionRevoked,
+		TargetID: targetID,
+		Metadata: map[string]interface{}{"session_id": sessionID},
+	})
+	return nil
+}
+
+// RevokeAllSessions logs a user out everywhere. It is audited on every call,
+// including the logouts that follow a password, email or role change.
+func (t *TokenService) RevokeAllSessions(ctx context.Context, userID string) error {
+	familyIDs, err := t.redisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, familyID := range familyIDs {
+		if err := t.RevokeFamily(ctx, familyID); err != nil {
+			return err
+		}
+	}
+	if err := t.redisClient.Del(ctx, userSessionsKey(userID)).Err(); err != nil {
+		return err
+	}
+
+	targetID, _ := primitive.ObjectIDFromHex(userID)
+	t.auditService.Record(ctx, &model.AuditEvent{
+		Action:   model.AuditSessionsRevoked,
+		TargetID: targetID,
+		Metadata: map[string]interface{}{"sessions": len(familyIDs)},
+	})
+	return nil
+}
+
+// TouchSession records activity on a session without extending its lifetime.
+func (t *TokenService) TouchSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	return touchSessionScript.Run(ctx, t.redisClient,
+		[]string{familyKey(sessionID)},
+		strconv.FormatInt(time.Now().Unix(), 10),
+	).Err()
+}
+
+func (t *TokenService) ValidateAccessToken(token string) (*utils.JWTClaims, error) {
+	return t.auth.ValidateToken(token)
+}
+
+func (t *TokenService) ValidateRefreshToken(token string) (*utils.JWTClaims, error) {
+	claims, err := t.auth.ValidateRefreshToken(token)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+func (t *TokenService) ValidateTokenWithRedis(ctx context.Context, token string) error {
+	// Check blacklist with 24h window
+	blacklisted, err := t.redisClient.Get(ctx, "blacklist:"+token).Result()
+	if err != redis.Nil || blacklisted != "" {
+		return gin.Error{
+			Err:  err,
+			Type: gin.ErrorTypePublic,
+			Meta: gin.H{
+				"status": http.StatusUnauthorized,
+			},
+		}
+	}
+
+	// Check active tokens
+	_, err = t.redisClient.Get(ctx, token).Result()
+	if err == redis.Nil {
+		return gin.Error{
+			Err:  err,
+			Type: gin.ErrorTypePublic,
+			Meta: gin.H{
+				"status": http.StatusUnauthorized,
+			},
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Logout revokes the family of the refresh token and blacklists the access
+// token in case it was issued outside that family.
+func (t *TokenService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	accessClaims, err := t.auth.ValidateToken(accessToken)
+	if err != nil {
+		return err
+	}
+
+	refreshClaims, err := t.auth.ValidateRefreshToken(refreshToken)
+	if err == nil && refreshClaims.FamilyID != "" && refreshClaims.UserID == accessClaims.UserID {
+		if err := t.RevokeFamily(ctx, refreshClaims.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	pipe := t.redisClient.Pipeline()
+
+	// Blacklist access token for its remaining lifetime
+	pipe.Set(ctx,
+		"blacklist:"+accessToken,
+		"true",
+		t.auth.AccessExpiry())
+
+	// Remove active access token
+	pipe.Del(ctx, accessToken)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func parseUnix(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+func (t *TokenService) trackAccessToken(ctx context.Context, pipe redis.Pipeliner, familyID string, user *model.User, accessToken string) {
+	pipe.Set(ctx, accessToken, user.ID.Hex(), t.auth.AccessExpiry())
+	pipe.SAdd(ctx, familyAccessKey(familyID), accessToken)
+	pipe.Expire(ctx, familyAccessKey(familyID), t.auth.RefreshExpiry())
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrTwoFactorRequired       = errors.New("two-factor authentication required")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupExpired   = errors.New("two-factor setup expired, start again")
+	ErrTwoFactorEnforced       = errors.New("two-factor authentication is required for your role")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidChallenge        = errors.New("invalid or expired login challenge")
+)
+
+const (
+	twoFactorChallengeTTL      = 5 * time.Minute
+	twoFactorSetupTTL          = 10 * time.Minute
+	twoFactorChallengeAttempts = 5
+	recoveryCodeCount          = 10
+	twoFactorPolicyKey         = "settings:2fa_required_roles"
+)
+
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorService handles TOTP enrollment, recovery codes, the second step
+// of the login and the policy of which roles must use a second factor.
+type TwoFactorService struct {
+	userRepo      repository.UserRepository
+	redisClient   *redis.Client
+	tokenService  *TokenService
+	loginThrottle *LoginThrottleService
+	auditService  *AuditService
+	config        *config.Config
+}
+
+func NewTwoFactorService(userRepo repository.UserRepository, redisClient *redis.Client, tokenService *TokenService, loginThrottle *LoginThrottleService, auditService *AuditService, config *config.Config) *TwoFactorService {
+	return &TwoFactorService{
+		userRepo:      userRepo,
+		redisClient:   redisClient,
+		tokenService:  tokenService,
+		loginThrottle: loginThrottle,
+		auditService:  auditService,
+		config:        config,
+	}
+}
+
+// Setup creates a pending secret. It only becomes active once Enable
+// receives a valid code for it, so a half finished setup never locks a user out.
+func (t *TwoFactorService) Setup(ctx context.Context, user *model.User) (*TwoFactorSetup, error) {
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.redisClient.Set(ctx, "2fa_setup:"+user.ID.Hex(), secret, twoFactorSetupTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(t.config.AppName, user.Email, secret),
+	}, nil
+}
+
+// Enable verifies the first code of the pending secret, turns two-factor
+// authentication on and returns the recovery codes, which are shown only once.
+func (t *TwoFactorService) Enable(ctx context.Context, user *model.User, code string) ([]string, error) {
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := t.redisClient.Get(ctx, "2fa_setup:"+user.ID.Hex()).Result()
+	if err == redis.Nil {
+		return nil, ErrTwoFactorSetupExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err := t.markStepUsed(ctx, user.ID, step); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = t.userRepo.Update(ctx, bson.M{
+		"two_factor_enabled": true,
+		"totp_secret":        secret,
+		"recovery_codes":     hashes,
+	}, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	t.redisClient.Del(ctx, "2fa_setup:"+user.ID.Hex())
+	t.auditService.Record(ctx, &model.AuditEvent{Action: model.AuditTwoFactorEnabled, TargetID: user.ID})
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off after checking a current code.
+func (t *TwoFactorService) Disable(ctx context.Context, user *model.User, code string) error {
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	required, err := t.IsRequired(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorEnforced
+	}
+
+	if err := t.Verify(ctx, user, code); err != nil {
+		return err
+	}
+
+	_, err = t.userRepo.FindOneAndUpdate(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set":   bson.M{"two_factor_enabled": false},
+		"$unset": bson.M{"totp_secret": "", "recovery_codes": ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	t.auditService.Record(ctx, &model.AuditEvent{Action: model.AuditTwoFactorDisabled, TargetID: user.ID})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking a
+// current code.
+func (t *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, user *model.User, code string) ([]string, error) {
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	if err := t.Verify(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := t.userRepo.Update(ctx, bson.M{"recovery_codes": hashes}, user.ID); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts a TOTP code that was not used before or an unused recovery
+// code, which is consumed.
+func (t *TwoFactorService) Verify(ctx context.Context, user *model.User, code string) error {
+	if !user.TwoFactorEnabled || user.TOTPSecret == "" {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		return t.markStepUsed(ctx, user.ID, step)
+	}
+
+	hash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	_, err := t.userRepo.FindOneAndUpdate(ctx,
+		bson.M{"_id": user.ID, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+	if err == mongo.ErrNoDocuments {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// CreateChallenge stores the first login step of a user whose first factor
+// (utils.AMRPassword or utils.AMROIDC) was accepted and returns the token
+// that identifies it.
+func (t *TwoFactorService) CreateChallenge(ctx context.Context, user *model.User, client model.ClientInfo, firstFactor string) (*TwoFactorChallenge, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	key := "2fa_challenge:" + utils.HashToken(token)
+	pipe := t.redisClient.TxPipeline()
+	pipe.HSet(ctx, key,
+		"user_id", user.ID.Hex(),
+		"device_name", client.DeviceName,
+		"first_factor", firstFactor,
+		"attempts", 0,
+	)
+	pipe.Expire(ctx, key, twoFactorChallengeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int(twoFactorChallengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteChallenge checks the code for a login challenge and starts the
+// session. A challenge is single use and dies after a few wrong codes. Wrong
+// codes also count as failed logins of the account, so starting new
+// challenges leads to the same lockout as guessing passwords.
+func (t *TwoFactorService) CompleteChallenge(ctx context.Context, challengeToken, code string, client model.ClientInfo) (*utils.TokenPair, error) {
+	key := "2fa_challenge:" + utils.HashToken(challengeToken)
+	values, err := t.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrInvalidChallenge
+	}
+
+	attempts, err := t.redisClient.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if attempts > twoFactorChallengeAttempts {
+		t.redisClient.Del(ctx, key)
+		return nil, ErrInvalidChallenge
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(values["user_id"])
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := t.userRepo.FindOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	if _, err := t.loginThrottle.Check(ctx, user.Email, client.IP); err != nil {
+		t.redisClient.Del(ctx, key)
+		return nil, err
+	}
+
+	if err := t.Verify(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.loginThrottle.RecordFailure(ctx, user.Email, client.IP, user.ID)
+		}
+		return nil, err
+	}
+
+	// Only one request may turn the challenge into a session
+	deleted, err := t.redisClient.Del(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, ErrInvalidChallenge
+	}
+	t.loginThrottle.RecordSuccess(ctx, user.Email)
+
+	if client.DeviceName == "" {
+		client.DeviceName = values["device_name"]
+	}
+	firstFactor := values["first_factor"]
+	if firstFactor == "" {
+		firstFactor = utils.AMRPassword
+	}
+	return t.tokenService.StartFamily(ctx, user, client, []string{firstFactor, utils.AMROTP})
+}
+
+// RequiredRoles returns the roles that must use two-factor authentication.
+// Until an admin changes it, the policy comes from TWO_FACTOR_REQUIRED_ROLES.
+func (t *TwoFactorService) RequiredRoles(ctx context.Context) ([]string, error) {
+	value, err := t.redisClient.Get(ctx, twoFactorPolicyKey).Result()
+	if err == redis.Nil {
+		return t.config.TwoFactorRequiredRoles, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return splitList(value), nil
+}
+
+// SetRequiredRoles replaces the policy, the audit event keeps the roles
+// required before and after the change.
+func (t *TwoFactorService) SetRequiredRoles(ctx context.Context, roles []string) error {
+	before, err := t.RequiredRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := t.redisClient.Set(ctx, twoFactorPolicyKey, strings.Join(roles, ","), 0).Err(); err != nil {
+		return err
+	}
+
+	t.auditService.Record(ctx, &model.AuditEvent{
+		Action: model.AuditTwoFactorPolicyUpdated,
+		Before: map[string]interface{}{"roles": before},
+		After:  map[string]interface{}{"roles": roles},
+	})
+	return nil
+}
+
+// IsRequired reports whether any role of the user requires a second factor.
+func (t *TwoFactorService) IsRequired(ctx context.Context, user *model.User) (bool, error) {
+	required, err := t.RequiredRoles(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range required {
+		for _, userRole := range user.Roles {
+			if role == userRole {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// markStepUsed rejects a TOTP code that was already accepted, so a code seen
+// over someone's shoulder cannot be replayed within its time window.
+func (t *TwoFactorService) markStepUsed(ctx context.Context, userID primitive.ObjectID, step int64) error {
+	key := fmt.Sprintf("2fa_used:%s:%d", userID.Hex(), step)
+	fresh, err := t.redisClient.SetNX(ctx, key, "1", 3*utils.TOTPPeriod).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+	return codes, hashes, nil
+}
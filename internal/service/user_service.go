@@ -2,33 +2,50 @@ package service
 
 import (
 	"context"
+	"errors"
 	"example-go-project/internal/dto"
 	"example-go-project/internal/model"
 	"example-go-project/internal/repository"
 	"example-go-project/pkg/config"
 	"example-go-project/pkg/utils"
-	"net/http"
+	"sync"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrRegistrationClosed = errors.New("registration is by invitation only")
+)
+
+// dummyPasswordHash is compared against when the email is unknown, so a
+// login takes as long whether or not the account exists
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
 type UserService struct {
-	userRepo    repository.UserRepository
-	redisClient *redis.Client
-	config      *config.Config
+	userRepo     repository.UserRepository
+	redisClient  *redis.Client
+	tokenService *TokenService
+	auditService *AuditService
+	config       *config.Config
 }
 
-func NewUserService(userRepo repository.UserRepository, redisClient *redis.Client, config *config.Config) *UserService {
+func NewUserService(userRepo repository.UserRepository, redisClient *redis.Client, tokenService *TokenService, auditService *AuditService, config *config.Config) *UserService {
 	return &UserService{
-		userRepo:    userRepo,
-		redisClient: redisClient,
-		config:      config,
+		userRepo:     userRepo,
+		redisClient:  redisClient,
+		tokenService: tokenService,
+		auditService: auditService,
+		config:       config,
 	}
 }
 
@@ -55,19 +72,35 @@ func (u *UserService) FindByID(ctx context.Context, userID string) (*model.User,
 	return user, nil
 }
 
-func (u *UserService) Create(ctx context.Context, payload *dto.RegisterRequest) (*model.User, error) {
+// SelfRegistration reports whether anyone may register. With INVITE_ONLY
+// accounts are only created from invitations.
+func (u *UserService) SelfRegistration() bool {
+	return !u.config.InviteOnly
+}
+
+// Create saves a new account with the default role, or with the given roles
+// for an accepted invitation. Roles never come from the client, they are
+// otherwise only changed by admins through RoleService. The unique email
+// index turns a taken email into ErrEmailExists.
+func (u *UserService) Create(ctx context.Context, payload *dto.RegisterRequest, roles ...string) (*model.User, error) {
+	if len(roles) == 0 {
+		roles = []string{string(utils.UserRole)}
+	}
+
 	now := time.Now()
 	user := &model.User{
 		ID:        primitive.NewObjectID(),
 		Name:      payload.Name,
 		Email:     payload.Email,
 		Password:  payload.Password,
-		Roles:     payload.Roles,
+		Roles:     roles,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	if err := u.userRepo.Create(ctx, user); err != nil {
+	if err := u.userRepo.Create(ctx, user); mongo.IsDuplicateKeyError(err) {
+		return nil, ErrEmailExists
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -82,6 +115,11 @@ func (u *UserService) Create(ctx context.Context, payload *dto.RegisterRequest)
 	return res, nil
 }
 func (u *UserService) Update(ctx context.Context, payload *dto.UpdateProfileRequest, id primitive.ObjectID) (*model.User, error) {
+	before, err := u.userRepo.FindOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+
 	req := bson.M{
 		"name": payload.Name,
 	}
@@ -90,6 +128,10 @@ func (u *UserService) Update(ctx context.Context, payload *dto.UpdateProfileRequ
 		return nil, err
 	}
 
+	event := &model.AuditEvent{Action: model.AuditUserUpdated, TargetID: user.ID}
+	event.Before, event.After = AuditDiff(before, user)
+	u.auditService.Record(ctx, event)
+
 	res := &model.User{
 		ID:        user.ID,
 		Name:      user.Name,
@@ -101,12 +143,55 @@ func (u *UserService) Update(ctx context.Context, payload *dto.UpdateProfileRequ
 	return res, nil
 }
 
+// Delete soft-deletes the user and revokes their sessions. The user can be
+// restored until an admin or the cleanup worker purges them.
 func (u *UserService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return u.userRepo.Delete(ctx, id)
+	user, err := u.userRepo.FindOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := u.tokenService.RevokeAllSessions(ctx, user.ID.Hex()); err != nil {
+		return err
+	}
+
+	event := &model.AuditEvent{Action: model.AuditUserDeleted, TargetID: user.ID}
+	event.Before, _ = AuditDiff(user, nil)
+	u.auditService.Record(ctx, event)
+	return nil
+}
+
+// Restore brings back a soft-deleted user. It fails with ErrEmailExists when
+// the email has been registered again in the meantime.
+func (u *UserService) Restore(ctx context.Context, id primitive.ObjectID) (*model.User, error) {
+	user, err := u.userRepo.FindOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := u.userRepo.FindOne(ctx, bson.M{"email": user.Email}); err == nil {
+		return nil, ErrEmailExists
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	if err := u.userRepo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	user.DeletedAt = nil
+
+	u.auditService.Record(ctx, &model.AuditEvent{Action: model.AuditUserRestored, TargetID: user.ID})
+	return user, nil
 }
 
 func (u *UserService) FindAll(ctx context.Context, filter dto.UserFilter, page, pageSize int) ([]model.User, int64, error) {
 	mongoFilter := bson.D{}
+	if filter.Deleted {
+		mongoFilter = append(mongoFilter, bson.E{Key: "deleted_at", Value: bson.M{"$exists": true}})
+	}
 	if filter.Name != "" {
 		mongoFilter = append(mongoFilter, bson.E{
 			Key: "name",
@@ -135,118 +220,40 @@ func (u *UserService) FindAll(ctx context.Context, filter dto.UserFilter, page,
 	return users, total, nil
 }
 
-func (u *UserService) Login(ctx context.Context, password string, user *model.User) (*utils.TokenPair, error) {
+// Login checks the password and starts a session. user is nil for an unknown
+// email, which fails like a wrong password. Users with two-factor
+// authentication get ErrTwoFactorRequired instead and must finish the login
+// through a TwoFactorService challenge.
+func (u *UserService) Login(ctx context.Context, password string, user *model.User, client model.ClientInfo) (*utils.TokenPair, error) {
+	if user == nil || user.Password == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, err
+		return nil, ErrInvalidCredentials
 	}
 
-	auth := utils.NewAuthHandler(u.config.JWTSecretKey, u.config.JWTRefreshKey, u.config.JWTExpiresIn, u.config.JWTRefreshIn)
-	tokenPair, err := auth.GenerateTokenPair(user.ID.Hex(), user.Roles)
-	if err != nil {
-		return nil, err
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorRequired
 	}
 
-	expires, _ := time.ParseDuration(u.config.JWTExpiresIn)
-	if err := u.redisClient.Set(ctx,
-		tokenPair.AccessToken,
-		user.ID.Hex(),
-		expires).Err(); err != nil {
-		return nil, err
-	}
-	return tokenPair, nil
+	return u.tokenService.StartFamily(ctx, user, client, []string{utils.AMRPassword})
 }
 
-func (s *UserService) RefreshToken(ctx context.Context, refreshToken string) (*utils.TokenPair, error) {
-	auth := utils.NewAuthHandler(s.config.JWTSecretKey, s.config.JWTRefreshKey, s.config.JWTExpiresIn, s.config.JWTRefreshIn)
-	claims, err := auth.ValidateRefreshToken(refreshToken)
+func (s *UserService) RefreshToken(ctx context.Context, refreshToken string, client model.ClientInfo) (*utils.TokenPair, error) {
+	claims, err := s.tokenService.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
-	blacklisted, err := s.redisClient.Get(ctx, "blacklist:"+refreshToken).Result()
-	if blacklisted != "" || err != redis.Nil {
-		return nil, err
-	}
-
 	user, err := s.FindByID(ctx, claims.UserID)
 	if err != nil {
-		return nil, err
-	}
-
-	tokenPair, err := auth.GenerateTokenPair(user.ID.Hex(), user.Roles)
-	if err != nil {
-		return nil, err
-	}
-
-	expires, _ := time.ParseDuration(s.config.JWTExpiresIn)
-	if err := s.redisClient.Set(ctx,
-		tokenPair.AccessToken,
-		user.ID.Hex(),
-		expires).Err(); err != nil {
-		return nil, err
-	}
-
-	// Keep blacklist for 48h to prevent reuse
-	if err := s.redisClient.Set(ctx,
-		"blacklist:"+refreshToken,
-		"true",
-		48*time.Hour).Err(); err != nil {
-		return nil, err
-	}
-
-	return tokenPair, nil
-}
-
-func (s *UserService) ValidateTokenWithRedis(ctx context.Context, token string) error {
-	// Check blacklist with 24h window
-	blacklisted, err := s.redisClient.Get(ctx, "blacklist:"+token).Result()
-	if err != redis.Nil || blacklisted != "" {
-		return gin.Error{
-			Err:  err,
-			Type: gin.ErrorTypePublic,
-			Meta: gin.H{
-				"status": http.StatusUnauthorized,
-			},
-		}
-	}
-
-	// Check active tokens
-	_, err = s.redisClient.Get(ctx, token).Result()
-	if err == redis.Nil {
-		return gin.Error{
-			Err:  err,
-			Type: gin.ErrorTypePublic,
-			Meta: gin.H{
-				"status": http.StatusUnauthorized,
-			},
-		}
-	}
-	if err != nil {
-		return err
+		return nil, ErrInvalidRefreshToken
 	}
 
-	return nil
+	return s.tokenService.RotateFamily(ctx, claims, user, client)
 }
 
 func (s *UserService) Logout(ctx context.Context, accessToken, refreshToken string) error {
-	pipe := s.redisClient.Pipeline()
-
-	// Blacklist access token for 24h
-	expires, _ := time.ParseDuration(s.config.JWTExpiresIn)
-	pipe.Set(ctx,
-		"blacklist:"+accessToken,
-		"true",
-		expires)
-
-	// Blacklist refresh token for 48h
-	pipe.Set(ctx,
-		"blacklist:"+refreshToken,
-		"true",
-		48*time.Hour)
-
-	// Remove active access token
-	pipe.Del(ctx, accessToken)
-
-	_, err := pipe.Exec(ctx)
-	return err
+	return s.tokenService.Logout(ctx, accessToken, refreshToken)
 }
@@ -0,0 +1,222 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryAPIKeyRepository keeps keys in a map and looks them up by hash or ID
+type memoryAPIKeyRepository struct {
+	repository.APIKeyRepository
+	keys map[primitive.ObjectID]*model.APIKey
+}
+
+func (m *memoryAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *memoryAPIKeyRepository) FindOne(ctx context.Context, query bson.M) (*model.APIKey, error) {
+	for _, key := range m.keys {
+		if key.Hash == query["hash"] || key.ID == query["_id"] {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *memoryAPIKeyRepository) Update(ctx context.Context, payload bson.M, id primitive.ObjectID) (*model.APIKey, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	if revokedAt, ok := payload["revoked_at"].(time.Time); ok {
+		key.RevokedAt = &revokedAt
+	}
+	return key, nil
+}
+
+// memoryUserRepository finds users by ID
+type memoryUserRepository struct {
+	repository.UserRepository
+	users map[primitive.ObjectID]*model.User
+}
+
+func (m *memoryUserRepository) FindOne(ctx context.Context, query bson.M) (*model.User, error) {
+	user, ok := m.users[query["_id"].(primitive.ObjectID)]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return user, nil
+}
+
+func newPermissionService(t *testing.T) *service.PermissionService {
+	repo := &memoryRoleRepository{roles: map[string]*model.RoleDefinition{}}
+	permissionService := service.NewPermissionService(repo, nil, redis.NewClient(&redis.Options{}), service.NewAuditService(discardAuditRepository{}))
+	assert.NoError(t, permissionService.EnsureDefaults(context.Background()))
+	return permissionService
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	auditService := service.NewAuditService(discardAuditRepository{})
+	permissionService := newPermissionService(t)
+	keys := &memoryAPIKeyRepository{keys: map[primitive.ObjectID]*model.APIKey{}}
+	apiKeyService := service.NewAPIKeyService(keys, permissionService, auditService)
+
+	owner := &model.User{ID: primitive.NewObjectID(), Roles: []string{"admin"}, EmailVerified: true}
+	users := &memoryUserRepository{users: map[primitive.ObjectID]*model.User{owner.ID: owner}}
+	userService := service.NewUserService(users, nil, nil, auditService, &config.Config{})
+	authMiddleware := middleware.NewAuthMiddleware(userService, nil, nil, permissionService, apiKeyService, nil, &config.Config{})
+
+	key, plain, err := apiKeyService.Create(ctx, &model.Principal{User: owner}, &dto.CreateAPIKeyRequest{
+		Name:   "ci",
+		Type:   model.APIKeyService,
+		Scopes: []string{string(utils.PermProductList)},
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, key.Prefix+"_"))
+	assert.True(t, strings.HasPrefix(key.Prefix, utils.APIKeyPrefix+"_"))
+	assert.Equal(t, utils.HashToken(plain), key.Hash)
+	assert.NotContains(t, key.Hash, plain)
+
+	// An API key cannot issue more keys
+	_, _, err = apiKeyService.Create(ctx, &model.Principal{User: owner, APIKey: key}, &dto.CreateAPIKeyRequest{Name: "more"})
+	assert.ErrorIs(t, err, service.ErrAPIKeyNotAllowed)
+
+	router := gin.New()
+	protected := router.Group("", authMiddleware.Protected())
+	protected.GET("/products", authMiddleware.RequirePermission(utils.PermProductList), func(c *gin.Context) { c.Status(http.StatusOK) })
+	protected.GET("/users", authMiddleware.RequirePermission(utils.PermUserList), func(c *gin.Context) { c.Status(http.StatusOK) })
+	protected.GET("/account", authMiddleware.DenyAPIKey(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path string, header, value string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("/products", "X-API-Key", plain))
+	assert.Equal(t, http.StatusOK, request("/products", "Authorization", "ApiKey "+plain))
+	assert.Equal(t, http.StatusForbidden, request("/users", "X-API-Key", plain))
+	assert.Equal(t, http.StatusForbidden, request("/account", "X-API-Key", plain))
+	assert.Equal(t, http.StatusUnauthorized, request("/products", "X-API-Key", plain+"0"))
+	assert.Equal(t, http.StatusUnauthorized, request("/products", "X-API-Key", strings.TrimPrefix(plain, utils.APIKeyPrefix+"_")))
+
+	assert.NoError(t, apiKeyService.Revoke(ctx, owner, key.ID))
+	assert.Equal(t, http.StatusUnauthorized, request("/products", "X-API-Key", plain))
+}
+
+func TestAPIKeyAllowed(t *testing.T) {
+	ctx := context.Background()
+	permissionService := newPermissionService(t)
+
+	admin := &model.User{ID: primitive.NewObjectID(), Roles: []string{"admin"}}
+	demoted := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+
+	serviceKey := &model.APIKey{Type: model.APIKeyService, Scopes: []string{string(utils.PermProductList)}}
+	scopedKey := &model.APIKey{Type: model.APIKeyPersonal, Scopes: []string{string(utils.PermProductList)}}
+	unscopedKey := &model.APIKey{Type: model.APIKeyPersonal, Scopes: []string{}}
+
+	tests := []struct {
+		name    string
+		owner   *model.User
+		key     *model.APIKey
+		perm    utils.Permission
+		allowed bool
+	}{
+		{"service key in scope", admin, serviceKey, utils.PermProductList, true},
+		{"service key out of scope", admin, serviceKey, utils.PermUserList, false},
+		{"service key of demoted owner", demoted, serviceKey, utils.PermProductList, false},
+		{"personal key in scope", admin, scopedKey, utils.PermProductList, true},
+		{"personal key out of scope", admin, scopedKey, utils.PermUserList, false},
+		{"personal key of demoted owner", demoted, scopedKey, utils.PermProductList, false},
+		{"unscoped personal key", admin, unscopedKey, utils.PermUserList, true},
+		{"unscoped personal key of demoted owner", demoted, unscopedKey, utils.PermUserList, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := permissionService.Allowed(ctx, &model.Principal{User: tt.owner, APIKey: tt.key}, tt.perm)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, allowed)
+		})
+	}
+
+	// A service key lapses once its owner loses apikey:manage
+	limited := &model.User{ID: primitive.NewObjectID(), Roles: []string{"lister"}}
+	_, err := permissionService.CreateRole(ctx, admin, "lister", "", []string{string(utils.PermProductList)})
+	assert.NoError(t, err)
+	allowed, err := permissionService.Allowed(ctx, &model.Principal{User: limited, APIKey: serviceKey}, utils.PermProductList)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = permissionService.Allowed(ctx, &model.Principal{User: limited, APIKey: scopedKey}, utils.PermProductList)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	permissionService := newPermissionService(t)
+	authMiddleware := middleware.NewAuthMiddleware(nil, nil, nil, permissionService, nil, nil, &config.Config{})
+
+	admin := &model.User{ID: primitive.NewObjectID(), Roles: []string{"admin"}}
+	serviceKey := &model.APIKey{Type: model.APIKeyService, Scopes: []string{string(utils.PermProductList)}}
+	scopedKey := &model.APIKey{Type: model.APIKeyPersonal, Scopes: []string{string(utils.PermProductList)}}
+	unscopedKey := &model.APIKey{Type: model.APIKeyPersonal, Scopes: []string{}}
+
+	router := gin.New()
+	var principal *model.Principal
+	router.Use(func(c *gin.Context) { c.Set("principal", principal) })
+	router.POST("/orders", authMiddleware.RequireScope(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/products", authMiddleware.RequireScope(utils.PermProductList), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/orders", authMiddleware.RequireScope(utils.PermOrderManage), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		key    *model.APIKey
+		method string
+		path   string
+		status int
+	}{
+		{"session without scope", nil, http.MethodPost, "/orders", http.StatusOK},
+		{"unscoped key without scope", unscopedKey, http.MethodPost, "/orders", http.StatusOK},
+		{"scoped key without scope", scopedKey, http.MethodPost, "/orders", http.StatusForbidden},
+		{"service key without scope", serviceKey, http.MethodPost, "/orders", http.StatusForbidden},
+		{"scoped key in scope", scopedKey, http.MethodGet, "/products", http.StatusOK},
+		{"service key in scope", serviceKey, http.MethodGet, "/products", http.StatusOK},
+		{"scoped key out of scope", scopedKey, http.MethodGet, "/orders", http.StatusForbidden},
+		{"session out of scope", nil, http.MethodGet, "/orders", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal = &model.Principal{User: admin, APIKey: tt.key}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
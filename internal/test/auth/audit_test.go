@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryAuditRepository keeps recorded events, only Create is called
+type memoryAuditRepository struct {
+	repository.AuditRepository
+	events []*model.AuditEvent
+}
+
+func (m *memoryAuditRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func TestAuditDiff(t *testing.T) {
+	before := &model.User{ID: primitive.NewObjectID(), Name: "Old", Email: "a@example.com", Password: "hash-1"}
+	after := *before
+	after.Name = "New"
+	after.Password = "hash-2"
+
+	changedBefore, changedAfter := service.AuditDiff(before, &after)
+	assert.Equal(t, map[string]interface{}{"name": "Old", "password": "[redacted]"}, changedBefore)
+	assert.Equal(t, map[string]interface{}{"name": "New", "password": "[redacted]"}, changedAfter)
+
+	// A deleted document keeps every field on the before side
+	changedBefore, changedAfter = service.AuditDiff(before, nil)
+	assert.Equal(t, "a@example.com", changedBefore["email"])
+	assert.Nil(t, changedAfter)
+}
+
+func TestAuditRecordsRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := &memoryAuditRepository{}
+	auditService := service.NewAuditService(repo)
+	admin := &model.User{ID: primitive.NewObjectID()}
+	target := &model.User{ID: primitive.NewObjectID()}
+
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.DELETE("/user/:id",
+		func(c *gin.Context) {
+			principal := &model.Principal{User: target, Impersonator: admin}
+			c.Request = c.Request.WithContext(service.WithAuditPrincipal(c.Request.Context(), principal))
+		},
+		func(c *gin.Context) {
+			auditService.Record(c.Request.Context(), &model.AuditEvent{Action: model.AuditUserDeleted, TargetID: target.ID})
+			c.Status(http.StatusOK)
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodDelete, "/user/"+target.ID.Hex(), nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	req.Header.Set("User-Agent", "audit-test")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-123", w.Header().Get("X-Request-ID"))
+	assert.Len(t, repo.events, 1)
+	event := repo.events[0]
+	assert.Equal(t, admin.ID, event.ActorID)
+	assert.Equal(t, "req-123", event.RequestID)
+	assert.Equal(t, "audit-test", event.UserAgent)
+	assert.Equal(t, target.ID.Hex(), event.Metadata["impersonated_user_id"])
+
+	// Ids that do not look like ids are replaced
+	req = httptest.NewRequest(http.MethodDelete, "/user/"+target.ID.Hex(), nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad id\n", w.Header().Get("X-Request-ID"))
+	assert.Equal(t, w.Header().Get("X-Request-ID"), repo.events[1].RequestID)
+}
+
+func TestTwoFactorPolicyAudited(t *testing.T) {
+	ctx := context.Background()
+	redisClient := redis.NewClient(&redis.Options{})
+	t.Cleanup(func() { redisClient.Del(ctx, "settings:2fa_required_roles") })
+
+	repo := &memoryAuditRepository{}
+	cfg := &config.Config{TwoFactorRequiredRoles: []string{"auditor"}}
+	twoFactorService := service.NewTwoFactorService(nil, redisClient, nil, nil, service.NewAuditService(repo), cfg)
+
+	assert.NoError(t, twoFactorService.SetRequiredRoles(ctx, []string{"auditor", "support"}))
+	assert.Len(t, repo.events, 1)
+	event := repo.events[0]
+	assert.Equal(t, model.AuditTwoFactorPolicyUpdated, event.Action)
+	assert.Equal(t, []string{"auditor"}, event.Before["roles"])
+	assert.Equal(t, []string{"auditor", "support"}, event.After["roles"])
+}
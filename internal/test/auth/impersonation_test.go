@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestImpersonationToken(t *testing.T) {
+	ctx := context.Background()
+	_, auth := newAuthHandler(t, t.TempDir(), "RS256")
+	tokenService := service.NewTokenService(redis.NewClient(&redis.Options{}), auth, service.NewAuditService(discardAuditRepository{}), &config.Config{})
+
+	admin := &model.User{ID: primitive.NewObjectID(), Roles: []string{"admin"}}
+	customer := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+
+	token, expiresAt, err := tokenService.IssueImpersonationToken(ctx, admin, customer, []string{"pwd", "otp"}, 5*time.Minute)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt, time.Second)
+
+	claims, err := tokenService.ValidateAccessToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, customer.ID.Hex(), claims.UserID)
+	assert.True(t, claims.Impersonated())
+	assert.Equal(t, admin.ID.Hex(), claims.Act.Subject)
+	assert.Empty(t, claims.FamilyID, "an impersonation token must not be refreshable")
+	assert.NoError(t, tokenService.ValidateTokenWithRedis(ctx, token))
+}
+
+func TestImpersonatorMustCoverTarget(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRoleRepository{roles: map[string]*model.RoleDefinition{}}
+	permissionService := service.NewPermissionService(repo, nil, redis.NewClient(&redis.Options{}), service.NewAuditService(discardAuditRepository{}))
+	assert.NoError(t, permissionService.EnsureDefaults(ctx))
+	_, err := permissionService.CreateRole(ctx, &model.User{}, "support", "", []string{"user:list", "user:impersonate"})
+	assert.NoError(t, err)
+
+	covered, err := permissionService.Covers(ctx, []string{"support"}, []string{"user"})
+	assert.NoError(t, err)
+	assert.True(t, covered)
+
+	// Support staff acting as an admin would gain the admin's rights
+	covered, err = permissionService.Covers(ctx, []string{"support"}, []string{"admin"})
+	assert.NoError(t, err)
+	assert.False(t, covered)
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authMiddleware := middleware.NewAuthMiddleware(nil, nil, nil, nil, nil, nil, &config.Config{})
+
+	customer := &model.User{ID: primitive.NewObjectID()}
+	admin := &model.User{ID: primitive.NewObjectID()}
+
+	for name, principal := range map[string]*model.Principal{
+		"user":         {User: customer},
+		"impersonated": {User: customer, Impersonator: admin},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router := gin.New()
+			router.DELETE("/user/:id",
+				func(c *gin.Context) { c.Set("principal", principal) },
+				authMiddleware.DenyImpersonation(),
+				func(c *gin.Context) { c.Status(http.StatusOK) },
+			)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/user/"+primitive.NewObjectID().Hex(), nil))
+			if principal.IsImpersonated() {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+			} else {
+				assert.Equal(t, http.StatusOK, w.Code)
+			}
+		})
+	}
+}
+
+func TestImpersonationReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authMiddleware := middleware.NewAuthMiddleware(nil, nil, nil, nil, nil, nil, &config.Config{})
+
+	customer := &model.User{ID: primitive.NewObjectID()}
+	admin := &model.User{ID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name      string
+		principal *model.Principal
+		method    string
+		status    int
+	}{
+		{"user writes", &model.Principal{User: customer}, http.MethodPost, http.StatusOK},
+		{"impersonated reads", &model.Principal{User: customer, Impersonator: admin}, http.MethodGet, http.StatusOK},
+		{"impersonated posts", &model.Principal{User: customer, Impersonator: admin}, http.MethodPost, http.StatusForbidden},
+		{"impersonated puts", &model.Principal{User: customer, Impersonator: admin}, http.MethodPut, http.StatusForbidden},
+		{"impersonated deletes", &model.Principal{User: customer, Impersonator: admin}, http.MethodDelete, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) { c.Set("principal", tt.principal) }, authMiddleware.ImpersonationReadOnly())
+			router.Handle(tt.method, "/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, "/orders", nil))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
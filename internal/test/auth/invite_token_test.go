@@ -0,0 +1,49 @@
+package test
+
+import (
+	"example-go-project/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteToken(t *testing.T) {
+	secret := []byte("invite-secret")
+	claims := utils.InviteClaims{
+		Email: "new.hire@example.com",
+		Role:  "editor",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "6650f0c2a1b2c3d4e5f60718",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	token, err := utils.SignInviteToken(secret, claims)
+	assert.NoError(t, err)
+
+	parsed, err := utils.ParseInviteToken(secret, token)
+	assert.NoError(t, err)
+	assert.Equal(t, claims.Email, parsed.Email)
+	assert.Equal(t, claims.Role, parsed.Role)
+	assert.Equal(t, claims.ID, parsed.ID)
+
+	_, err = utils.ParseInviteToken([]byte("other-secret"), token)
+	assert.Error(t, err, "a token signed with another secret must be rejected")
+
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired, err := utils.SignInviteToken(secret, claims)
+	assert.NoError(t, err)
+	_, err = utils.ParseInviteToken(secret, expired)
+	assert.Error(t, err)
+
+	// Other HS256 tokens signed with the same secret lack the invite audience
+	refresh, err := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.JWTClaims{
+		UserID:           "user-id",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString(secret)
+	assert.NoError(t, err)
+	_, err = utils.ParseInviteToken(secret, refresh)
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example-go-project/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthHandler(t *testing.T, dir, algorithm string) (*utils.KeyManager, *utils.AuthHandler) {
+	keyManager, err := utils.NewKeyManager(dir, algorithm, time.Hour)
+	assert.NoError(t, err)
+	return keyManager, utils.NewAuthHandler(keyManager, "test-refresh", "1h", "24h")
+}
+
+func TestAccessTokenSigning(t *testing.T) {
+	for _, algorithm := range []string{utils.AlgRS256, utils.AlgEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			keyManager, auth := newAuthHandler(t, t.TempDir(), algorithm)
+
+			token, err := auth.GenerateToken(utils.JWTClaims{UserID: "user-id", Roles: []string{"user"}})
+			assert.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &utils.JWTClaims{})
+			assert.NoError(t, err)
+			assert.Equal(t, algorithm, parsed.Method.Alg())
+			assert.Equal(t, keyManager.Current().ID, parsed.Header["kid"])
+
+			claims, err := auth.ValidateToken(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-id", claims.UserID)
+		})
+	}
+}
+
+func TestKeyRotationKeepsOldTokensValid(t *testing.T) {
+	keyManager, auth := newAuthHandler(t, t.TempDir(), utils.AlgRS256)
+	oldKey := keyManager.Current()
+
+	oldToken, err := auth.GenerateToken(utils.JWTClaims{UserID: "user-id"})
+	assert.NoError(t, err)
+
+	newKey, err := keyManager.Rotate()
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldKey.ID, newKey.ID)
+
+	_, err = auth.ValidateToken(oldToken)
+	assert.NoError(t, err)
+
+	newToken, err := auth.GenerateToken(utils.JWTClaims{UserID: "user-id"})
+	assert.NoError(t, err)
+	_, err = auth.ValidateToken(newToken)
+	assert.NoError(t, err)
+
+	jwks := keyManager.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	for _, key := range jwks.Keys {
+		assert.Equal(t, "RSA", key.Kty)
+		assert.NotEmpty(t, key.N)
+		assert.NotEmpty(t, key.E)
+	}
+}
+
+func TestKeysAreLoadedFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	_, auth := newAuthHandler(t, dir, utils.AlgEdDSA)
+
+	token, err := auth.GenerateToken(utils.JWTClaims{UserID: "user-id"})
+	assert.NoError(t, err)
+
+	// A second instance sharing the directory verifies the same tokens
+	keyManager, other := newAuthHandler(t, dir, utils.AlgEdDSA)
+	_, err = other.ValidateToken(token)
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "OKP", keyManager.JWKS().Keys[0].Kty)
+}
+
+func TestRetiredKeysArePruned(t *testing.T) {
+	dir := t.TempDir()
+	keyManager, err := utils.NewKeyManager(dir, utils.AlgEdDSA, 0)
+	assert.NoError(t, err)
+	oldKey := keyManager.Current()
+
+	_, err = keyManager.Rotate()
+	assert.NoError(t, err)
+
+	_, ok := keyManager.Key(oldKey.ID)
+	assert.False(t, ok)
+	_, err = os.Stat(filepath.Join(dir, oldKey.ID+".pem"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUnknownKeyIsRejected(t *testing.T) {
+	_, auth := newAuthHandler(t, t.TempDir(), utils.AlgRS256)
+	_, other := newAuthHandler(t, t.TempDir(), utils.AlgRS256)
+
+	token, err := other.GenerateToken(utils.JWTClaims{UserID: "user-id"})
+	assert.NoError(t, err)
+
+	_, err = auth.ValidateToken(token)
+	assert.Error(t, err)
+
+	refreshToken, _, err := auth.GenerateRefreshToken(utils.JWTClaims{UserID: "user-id"})
+	assert.NoError(t, err)
+	_, err = auth.ValidateToken(refreshToken)
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"example-go-project/pkg/oidc"
+	"example-go-project/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint
+// that checks the PKCE verifier of the code it handed out
+type mockIdP struct {
+	server    *httptest.Server
+	keys      *utils.KeyManager
+	signer    *utils.KeyManager
+	audience  string
+	nonce     string
+	challenge string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	keys, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+
+	idp := &mockIdP{keys: keys, signer: keys, audience: "test-client"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(idp.keys.JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || oidc.CodeChallenge(r.Form.Get("code_verifier")) != idp.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token":     idp.idToken(t),
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) idToken(t *testing.T) string {
+	key := idp.signer.Current()
+	token := jwt.NewWithClaims(key.Method, jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "idp-user-1",
+		"aud":            idp.audience,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          idp.nonce,
+		"email":          "jane@example.com",
+		"email_verified": true,
+	})
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.Private)
+	assert.NoError(t, err)
+	return signed
+}
+
+func (idp *mockIdP) provider() *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Name:        "mock",
+		Issuer:      idp.server.URL,
+		ClientID:    "test-client",
+		RedirectURL: "http://localhost/api/v1/auth/oidc/mock/callback",
+	}, idp.server.Client())
+}
+
+// authorize plays the browser at the authorization endpoint and remembers
+// the nonce and challenge the provider would bind to the code
+func (idp *mockIdP) authorize(t *testing.T, provider *oidc.Provider, verifier string) {
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce-1", oidc.CodeChallenge(verifier))
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, idp.server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+
+	idp.nonce = query.Get("nonce")
+	idp.challenge = query.Get("code_challenge")
+}
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	idp := newMockIdP(t)
+	provider := idp.provider()
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	assert.NoError(t, err)
+	idp.authorize(t, provider, verifier)
+
+	// The code is useless without the verifier of the started login
+	_, err = provider.Exchange(ctx, "good-code", "another-verifier")
+	assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+
+	token, err := provider.Exchange(ctx, "good-code", verifier)
+	assert.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "idp-user-1", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+}
+
+func TestOIDCRejectsForeignIDTokens(t *testing.T) {
+	ctx := context.Background()
+	idp := newMockIdP(t)
+	provider := idp.provider()
+	idp.nonce = "nonce-1"
+
+	// Replayed into another login
+	_, err := provider.VerifyIDToken(ctx, idp.idToken(t), "nonce-2")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	// Issued to another client of the same provider
+	idp.audience = "other-client"
+	_, err = provider.VerifyIDToken(ctx, idp.idToken(t), "nonce-1")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+	// Signed with a key the provider does not publish
+	idp.audience = "test-client"
+	forger, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	idp.signer = forger
+	_, err = provider.VerifyIDToken(ctx, idp.idToken(t), "nonce-1")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
@@ -0,0 +1,204 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryMembershipRepository keeps memberships in a slice and understands
+// the queries OrgService makes on them
+type memoryMembershipRepository struct {
+	memberships []*model.Membership
+}
+
+func (m *memoryMembershipRepository) matches(membership *model.Membership, query bson.M) bool {
+	for key, value := range query {
+		switch key {
+		case "_id":
+			if membership.ID != value {
+				return false
+			}
+		case "org_id":
+			if membership.OrgID != value {
+				return false
+			}
+		case "user_id":
+			if membership.UserID != value {
+				return false
+			}
+		case "role":
+			if membership.Role != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (m *memoryMembershipRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (m *memoryMembershipRepository) Create(ctx context.Context, membership *model.Membership) error {
+	membership.ID = primitive.NewObjectID()
+	m.memberships = append(m.memberships, membership)
+	return nil
+}
+
+func (m *memoryMembershipRepository) Update(ctx context.Context, query bson.M, payload bson.M) (*model.Membership, error) {
+	membership, err := m.FindOne(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if role, ok := payload["role"].(string); ok {
+		membership.Role = role
+	}
+	return membership, nil
+}
+
+func (m *memoryMembershipRepository) Delete(ctx context.Context, query bson.M) error {
+	for i, membership := range m.memberships {
+		if m.matches(membership, query) {
+			m.memberships = append(m.memberships[:i], m.memberships[i+1:]...)
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (m *memoryMembershipRepository) DeleteMany(ctx context.Context, query bson.M) error {
+	for m.Delete(ctx, query) == nil {
+	}
+	return nil
+}
+
+func (m *memoryMembershipRepository) FindOne(ctx context.Context, query bson.M) (*model.Membership, error) {
+	for _, membership := range m.memberships {
+		if m.matches(membership, query) {
+			return membership, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *memoryMembershipRepository) FindAll(ctx context.Context, query bson.M) ([]*model.Membership, error) {
+	var found []*model.Membership
+	for _, membership := range m.memberships {
+		if m.matches(membership, query) {
+			found = append(found, membership)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryMembershipRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	found, _ := m.FindAll(ctx, query)
+	return int64(len(found)), nil
+}
+
+func newOrgService(memberships *memoryMembershipRepository) *service.OrgService {
+	return service.NewOrgService(nil, memberships, nil, nil, nil, nil, nil, nil, service.NewAuditService(discardAuditRepository{}), &config.Config{})
+}
+
+func TestOrgContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	orgID := primitive.NewObjectID()
+	member := &model.User{ID: primitive.NewObjectID()}
+	outsider := &model.User{ID: primitive.NewObjectID()}
+	memberships := &memoryMembershipRepository{}
+	assert.NoError(t, memberships.Create(context.Background(), &model.Membership{OrgID: orgID, UserID: member.ID, Role: model.OrgRoleMember}))
+	orgMiddleware := middleware.NewOrgMiddleware(newOrgService(memberships))
+
+	tests := []struct {
+		name     string
+		user     *model.User
+		header   string
+		claim    string
+		wantCode int
+		wantOrg  primitive.ObjectID
+	}{
+		{name: "personal space", user: member, wantCode: http.StatusOK},
+		{name: "header", user: member, header: orgID.Hex(), wantCode: http.StatusOK, wantOrg: orgID},
+		{name: "token claim", user: member, claim: orgID.Hex(), wantCode: http.StatusOK, wantOrg: orgID},
+		{name: "not a member", user: outsider, header: orgID.Hex(), wantCode: http.StatusForbidden},
+		{name: "invalid id", user: member, header: "not-an-id", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scopedTo primitive.ObjectID
+			router := gin.New()
+			router.GET("/product",
+				func(c *gin.Context) {
+					c.Set("user", tt.user)
+					c.Set("claims", &utils.JWTClaims{UserID: tt.user.ID.Hex(), OrgID: tt.claim})
+				},
+				orgMiddleware.OrgContext(),
+				func(c *gin.Context) {
+					scopedTo, _ = repository.OrgFromContext(c.Request.Context())
+					c.Status(http.StatusOK)
+				},
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/product", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Org-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantOrg, scopedTo)
+		})
+	}
+}
+
+func TestOrgMemberRules(t *testing.T) {
+	ctx := context.Background()
+	orgID := primitive.NewObjectID()
+	memberships := &memoryMembershipRepository{}
+	orgService := newOrgService(memberships)
+
+	add := func(role string) *model.Membership {
+		membership := &model.Membership{OrgID: orgID, UserID: primitive.NewObjectID(), Role: role}
+		assert.NoError(t, memberships.Create(ctx, membership))
+		return membership
+	}
+	owner := add(model.OrgRoleOwner)
+	admin := add(model.OrgRoleAdmin)
+	member := add(model.OrgRoleMember)
+
+	// Admins manage members but not owners
+	_, err := orgService.UpdateMember(ctx, admin, member.UserID, model.OrgRoleAdmin)
+	assert.NoError(t, err)
+	_, err = orgService.UpdateMember(ctx, admin, member.UserID, model.OrgRoleOwner)
+	assert.ErrorIs(t, err, service.ErrOrgOwnerRequired)
+	assert.ErrorIs(t, orgService.RemoveMember(ctx, admin, owner.UserID), service.ErrOrgOwnerRequired)
+
+	// The last owner can neither step down nor leave
+	_, err = orgService.UpdateMember(ctx, owner, owner.UserID, model.OrgRoleAdmin)
+	assert.ErrorIs(t, err, service.ErrLastOrgOwner)
+	assert.ErrorIs(t, orgService.RemoveMember(ctx, owner, owner.UserID), service.ErrLastOrgOwner)
+
+	_, err = orgService.UpdateMember(ctx, owner, admin.UserID, model.OrgRoleOwner)
+	assert.NoError(t, err)
+	assert.NoError(t, orgService.RemoveMember(ctx, owner, owner.UserID))
+
+	_, err = orgService.Membership(ctx, orgID, owner.UserID)
+	assert.ErrorIs(t, err, service.ErrNotOrgMember)
+}
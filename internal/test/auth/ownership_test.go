@@ -0,0 +1,155 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/handlers"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestOwnerOrPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &memoryRoleRepository{roles: map[string]*model.RoleDefinition{}}
+	permissionService := service.NewPermissionService(repo, nil, redis.NewClient(&redis.Options{}), service.NewAuditService(discardAuditRepository{}))
+	assert.NoError(t, permissionService.EnsureDefaults(context.Background()))
+	authMiddleware := middleware.NewAuthMiddleware(nil, nil, nil, permissionService, nil, nil, &config.Config{})
+
+	owner := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+	other := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+	admin := &model.User{ID: primitive.NewObjectID(), Roles: []string{"admin"}}
+
+	scopedKey := &model.APIKey{Type: model.APIKeyPersonal, Scopes: []string{string(utils.PermProductList)}}
+
+	tests := []struct {
+		name   string
+		caller *model.User
+		apiKey *model.APIKey
+		path   string
+		status int
+	}{
+		{"owner", owner, nil, "/profile/" + owner.ID.Hex(), http.StatusOK},
+		{"other user", other, nil, "/profile/" + owner.ID.Hex(), http.StatusForbidden},
+		{"permission", admin, nil, "/profile/" + owner.ID.Hex(), http.StatusOK},
+		{"invalid id", owner, nil, "/profile/nope", http.StatusBadRequest},
+		{"owner with unscoped key", owner, &model.APIKey{Type: model.APIKeyPersonal}, "/profile/" + owner.ID.Hex(), http.StatusOK},
+		{"owner with scoped key", owner, scopedKey, "/profile/" + owner.ID.Hex(), http.StatusForbidden},
+		{"admin with scoped key", admin, scopedKey, "/profile/" + owner.ID.Hex(), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.PUT("/profile/:id",
+				func(c *gin.Context) {
+					c.Set("user", tt.caller)
+					c.Set("principal", &model.Principal{User: tt.caller, APIKey: tt.apiKey})
+				},
+				authMiddleware.OwnerOrPermission(middleware.PathOwner("id"), utils.PermUserUpdate),
+				func(c *gin.Context) { c.Status(http.StatusOK) },
+			)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+// memoryProductRepository finds products by ID
+type memoryProductRepository struct {
+	repository.ProductRepository
+	products map[primitive.ObjectID]*model.Product
+}
+
+func (m *memoryProductRepository) FindOne(ctx context.Context, query bson.M) (*model.Product, error) {
+	product, ok := m.products[query["_id"].(primitive.ObjectID)]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	found := *product
+	return &found, nil
+}
+
+// memoryCartRepository keeps the carts of signed in users
+type memoryCartRepository struct {
+	repository.CartRepository
+	carts map[primitive.ObjectID]*model.Cart
+}
+
+func (m *memoryCartRepository) FindOne(ctx context.Context, query bson.M) (*model.Cart, error) {
+	cart, ok := m.carts[query["user_id"].(primitive.ObjectID)]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return cart, nil
+}
+
+func (m *memoryCartRepository) Save(ctx context.Context, cart *model.Cart) error {
+	m.carts[cart.UserID] = cart
+	return nil
+}
+
+func TestCartAddsVisibleProducts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	permissionService := newPermissionService(t)
+
+	owner := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+	other := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+	admin := &model.User{ID: primitive.NewObjectID(), Roles: []string{"admin"}}
+	product := &model.Product{ID: primitive.NewObjectID(), UserID: owner.ID, Price: 10, Stock: 5}
+
+	products := &memoryProductRepository{products: map[primitive.ObjectID]*model.Product{product.ID: product}}
+	carts := &memoryCartRepository{carts: map[primitive.ObjectID]*model.Cart{}}
+	cartService := service.NewCartService(carts, products, redis.NewClient(&redis.Options{}), &config.Config{CartTTL: time.Minute})
+	productService := service.NewProductService(products, nil, nil, service.NewAuditService(discardAuditRepository{}))
+	cartHandler := handlers.NewCartHandler(cartService, productService, permissionService)
+
+	tests := []struct {
+		name   string
+		caller *model.User
+		status int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"other user", other, http.StatusNotFound},
+		{"owner", owner, http.StatusOK},
+		{"product:list", admin, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/cart/items",
+				func(c *gin.Context) {
+					if tt.caller != nil {
+						c.Set("principal", &model.Principal{User: tt.caller})
+					}
+				},
+				cartHandler.AddItem,
+			)
+
+			body := `{"product_id":"` + product.ID.Hex() + `","quantity":1}`
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/cart/items", strings.NewReader(body)))
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	assert.NotContains(t, carts.carts, other.ID)
+	assert.Contains(t, carts.carts, owner.ID)
+}
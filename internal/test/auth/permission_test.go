@@ -0,0 +1,97 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/utils"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryRoleRepository keeps role definitions in a map
+type memoryRoleRepository struct {
+	roles map[string]*model.RoleDefinition
+}
+
+func (m *memoryRoleRepository) Create(ctx context.Context, role *model.RoleDefinition) error {
+	m.roles[role.Name] = role
+	return nil
+}
+
+func (m *memoryRoleRepository) Update(ctx context.Context, name string, payload bson.M) (*model.RoleDefinition, error) {
+	role, ok := m.roles[name]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	if perms, ok := payload["permissions"].([]string); ok {
+		role.Permissions = perms
+	}
+	return role, nil
+}
+
+func (m *memoryRoleRepository) Delete(ctx context.Context, name string) error {
+	delete(m.roles, name)
+	return nil
+}
+
+func (m *memoryRoleRepository) FindOne(ctx context.Context, name string) (*model.RoleDefinition, error) {
+	role, ok := m.roles[name]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return role, nil
+}
+
+func (m *memoryRoleRepository) FindAll(ctx context.Context) ([]*model.RoleDefinition, error) {
+	var roles []*model.RoleDefinition
+	for _, role := range m.roles {
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// discardAuditRepository drops recorded events, the services under test
+// only call Create
+type discardAuditRepository struct {
+	repository.AuditRepository
+}
+
+func (discardAuditRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	return nil
+}
+
+func TestRequirePermissionUsesRoleDefinitions(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRoleRepository{roles: map[string]*model.RoleDefinition{}}
+	permissionService := service.NewPermissionService(repo, nil, redis.NewClient(&redis.Options{}), service.NewAuditService(discardAuditRepository{}))
+	assert.NoError(t, permissionService.EnsureDefaults(ctx))
+
+	allowed, err := permissionService.HasPermissions(ctx, []string{"admin"}, utils.PermUserList, utils.PermFileDelete)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = permissionService.HasPermissions(ctx, []string{"user"}, utils.PermProductCreate)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	// A role change is visible right away, the cached map is dropped
+	actor := &model.User{}
+	_, err = permissionService.UpdateRole(ctx, actor, "user", "", []string{string(utils.PermProductCreate)})
+	assert.NoError(t, err)
+
+	allowed, err = permissionService.HasPermissions(ctx, []string{"user"}, utils.PermProductCreate)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	_, err = permissionService.UpdateRole(ctx, actor, "user", "", []string{"product:fly"})
+	assert.ErrorIs(t, err, service.ErrUnknownPermission)
+
+	_, err = permissionService.UpdateRole(ctx, actor, "admin", "", nil)
+	assert.ErrorIs(t, err, service.ErrAdminRoleFixed)
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type storedCarts struct {
+	repository.CartRepository
+	carts map[primitive.ObjectID]*model.Cart
+}
+
+func (r *storedCarts) FindOne(ctx context.Context, query bson.M) (*model.Cart, error) {
+	cart, ok := r.carts[query["user_id"].(primitive.ObjectID)]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return cart, nil
+}
+
+func (r *storedCarts) Save(ctx context.Context, cart *model.Cart) error {
+	r.carts[cart.UserID] = cart
+	return nil
+}
+
+func TestCartRepricesAndMergesOnLogin(t *testing.T) {
+	ctx := context.Background()
+	product := &model.Product{ID: primitive.NewObjectID(), Name: "Chair", Price: 10, Stock: 5}
+	carts := &storedCarts{carts: map[primitive.ObjectID]*model.Cart{}}
+	cartService := service.NewCartService(carts, &stockProducts{product: product}, redis.NewClient(&redis.Options{}), &config.Config{CartTTL: time.Minute})
+
+	cart, err := cartService.AddItem(ctx, service.CartRef{}, product.ID, 2)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cart.CartID)
+	anonymous := service.CartRef{CartID: cart.CartID}
+
+	_, err = cartService.AddItem(ctx, anonymous, product.ID, 4)
+	assert.ErrorIs(t, err, service.ErrInsufficientStock)
+
+	// The price went up and stock went below the quantity after adding
+	product.Price = 12.5
+	product.Stock = 1
+	cart, err = cartService.Get(ctx, anonymous)
+	assert.NoError(t, err)
+	assert.Len(t, cart.Items, 1)
+	line := cart.Items[0]
+	assert.True(t, line.PriceChanged)
+	assert.True(t, line.OutOfStock)
+	assert.Equal(t, 10.0, line.AddedPrice)
+	assert.Equal(t, 12.5, line.Price)
+	assert.Equal(t, 0.0, cart.Total)
+
+	userID := primitive.NewObjectID()
+	product.Stock = 5
+	_, err = cartService.AddItem(ctx, service.CartRef{UserID: userID}, product.ID, 1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cartService.Merge(ctx, userID, anonymous.CartID))
+	cart, err = cartService.Get(ctx, service.CartRef{UserID: userID})
+	assert.NoError(t, err)
+	assert.Empty(t, cart.CartID)
+	assert.Equal(t, 3, cart.Items[0].Quantity)
+	assert.Equal(t, 37.5, cart.Total)
+
+	// The anonymous cart is gone after the merge
+	cart, err = cartService.Get(ctx, anonymous)
+	assert.NoError(t, err)
+	assert.Empty(t, cart.Items)
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type storedCategories struct {
+	repository.CategoryRepository
+	categories map[primitive.ObjectID]*model.Category
+}
+
+func (r *storedCategories) Create(ctx context.Context, category *model.Category) error {
+	r.categories[category.ID] = category
+	return nil
+}
+
+func (r *storedCategories) FindOne(ctx context.Context, query bson.M) (*model.Category, error) {
+	category, ok := r.categories[query["_id"].(primitive.ObjectID)]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	found := *category
+	return &found, nil
+}
+
+// FindAll handles the descendant lookup by ancestor
+func (r *storedCategories) FindAll(ctx context.Context, query bson.D) ([]*model.Category, error) {
+	var categories []*model.Category
+	for _, category := range r.categories {
+		if len(query) == 0 {
+			categories = append(categories, category)
+			continue
+		}
+		for _, ancestor := range category.Ancestors {
+			if ancestor == query[0].Value {
+				categories = append(categories, category)
+			}
+		}
+	}
+	return categories, nil
+}
+
+func (r *storedCategories) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Category, error) {
+	category, ok := r.categories[query["_id"].(primitive.ObjectID)]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	set := update["$set"].(bson.M)
+	category.Ancestors = set["ancestors"].([]primitive.ObjectID)
+	if name, ok := set["name"]; ok {
+		category.Name = name.(string)
+	}
+	if parentID, ok := set["parent_id"]; ok {
+		id := parentID.(primitive.ObjectID)
+		category.ParentID = &id
+	}
+	if _, ok := update["$unset"]; ok {
+		category.ParentID = nil
+	}
+	return r.FindOne(ctx, query)
+}
+
+// Count handles the child lookup by parent
+func (r *storedCategories) Count(ctx context.Context, query bson.D) (int64, error) {
+	var count int64
+	for _, category := range r.categories {
+		if category.ParentID != nil && *category.ParentID == query[0].Value {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *storedCategories) Delete(ctx context.Context, query bson.M) error {
+	delete(r.categories, query["_id"].(primitive.ObjectID))
+	return nil
+}
+
+type categorizedProducts struct {
+	repository.ProductRepository
+	products []*model.Product
+}
+
+func (r *categorizedProducts) Count(ctx context.Context, query bson.D) (int64, error) {
+	var count int64
+	for _, product := range r.products {
+		if product.CategoryID != nil && *product.CategoryID == query[0].Value {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *categorizedProducts) UpdateMany(ctx context.Context, query bson.D, update bson.M) (int64, error) {
+	var updated int64
+	to := update["$set"].(bson.M)["category_id"].(primitive.ObjectID)
+	for _, product := range r.products {
+		if product.CategoryID != nil && *product.CategoryID == query[0].Value {
+			product.CategoryID = &to
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func TestCategoryTreeMoveAndDelete(t *testing.T) {
+	ctx := context.Background()
+	categories := &storedCategories{categories: map[primitive.ObjectID]*model.Category{}}
+	products := &categorizedProducts{}
+	categoryService := service.NewCategoryService(categories, products, service.NewAuditService(discardAuditRepository{}))
+
+	furniture, err := categoryService.Create(ctx, "Furniture", nil)
+	assert.NoError(t, err)
+	chairs, err := categoryService.Create(ctx, "Chairs", &furniture.ID)
+	assert.NoError(t, err)
+	office, err := categoryService.Create(ctx, "Office chairs", &chairs.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{furniture.ID, chairs.ID}, office.Ancestors)
+
+	subtree, err := categoryService.Subtree(ctx, furniture.ID)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []primitive.ObjectID{furniture.ID, chairs.ID, office.ID}, subtree)
+
+	// A category cannot move below itself
+	_, err = categoryService.Update(ctx, chairs.ID, "Chairs", &office.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidCategory)
+	_, err = categoryService.Update(ctx, chairs.ID, "Chairs", &chairs.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidCategory)
+
+	// Moving to the top carries the subcategories along
+	chairs, err = categoryService.Update(ctx, chairs.ID, "Chairs", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, chairs.ParentID)
+	office, err = categoryService.FindByID(ctx, office.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{chairs.ID}, office.Ancestors)
+
+	product := &model.Product{ID: primitive.NewObjectID(), CategoryID: &office.ID}
+	products.products = append(products.products, product)
+
+	assert.ErrorIs(t, categoryService.Delete(ctx, chairs.ID, nil), service.ErrCategoryHasChildren)
+	assert.ErrorIs(t, categoryService.Delete(ctx, office.ID, nil), service.ErrCategoryInUse)
+	assert.ErrorIs(t, categoryService.Delete(ctx, office.ID, &office.ID), service.ErrInvalidCategory)
+
+	assert.NoError(t, categoryService.Delete(ctx, office.ID, &furniture.ID))
+	assert.Equal(t, furniture.ID, *product.CategoryID)
+	_, err = categoryService.FindByID(ctx, office.ID)
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+}
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"oak", "outdoor"}, service.NormalizeTags([]string{" Oak", "outdoor", "", "OAK "}))
+}
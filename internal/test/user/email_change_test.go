@@ -0,0 +1,80 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inboxMailService keeps the last mail sent to each address
+type inboxMailService struct {
+	inbox map[string]string
+}
+
+func (m *inboxMailService) Send(ctx context.Context, to, subject, body string) error {
+	m.inbox[to] = body
+	return nil
+}
+
+func TestEmailChange(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	mockRedis := redis.NewClient(&redis.Options{})
+	mail := &inboxMailService{inbox: map[string]string{}}
+	cfg := &config.Config{
+		JWTExpiresIn:         "1h",
+		JWTRefreshKey:        "test-refresh",
+		JWTRefreshIn:         "24h",
+		EmailVerificationTTL: time.Minute,
+		AppURL:               "http://localhost:3000",
+	}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	auditService := service.NewAuditService(discardAuditRepository{})
+	tokenService := service.NewTokenService(mockRedis, auth, auditService, cfg)
+	emailChangeService := service.NewEmailChangeService(mockRepo, mockRedis, tokenService, auditService, mail, cfg)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Password1!"), bcrypt.MinCost)
+	user := &model.User{
+		ID:       primitive.NewObjectID(),
+		Email:    "old@example.com",
+		Password: string(hashedPassword),
+		Roles:    []string{"user"},
+	}
+	changed := *user
+	changed.Email = "new@example.com"
+	mockRepo.On("FindOne", mock.Anything, bson.M{"email": "new@example.com"}).Return(nil, mongo.ErrNoDocuments)
+	mockRepo.On("FindOneAndUpdate", mock.Anything, bson.M{"_id": user.ID, "email": user.Email}, mock.Anything).Return(&changed, nil).Once()
+
+	session, err := tokenService.StartFamily(ctx, user, model.ClientInfo{IP: "10.0.0.1"}, nil)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, emailChangeService.Request(ctx, user, "new@example.com", "wrong"), service.ErrInvalidCredentials)
+	assert.NoError(t, emailChangeService.Request(ctx, user, "new@example.com", "Password1!"))
+	assert.Contains(t, mail.inbox["old@example.com"], "new@example.com")
+
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(mail.inbox["new@example.com"])
+	assert.Len(t, match, 2)
+	token, _ := url.QueryUnescape(match[1])
+
+	assert.NoError(t, emailChangeService.Confirm(ctx, token))
+	assert.Error(t, tokenService.ValidateTokenWithRedis(ctx, session.AccessToken), "sessions end with the email change")
+
+	// The link is single use
+	assert.ErrorIs(t, emailChangeService.Confirm(ctx, token), service.ErrInvalidEmailChange)
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"example-go-project/internal/handlers"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/middleware"
+	"example-go-project/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestEmailVerificationToken(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	mail := &captureMailService{}
+	cfg := &config.Config{EmailVerificationTTL: time.Hour, AppURL: "http://localhost:3000"}
+	verificationService := service.NewEmailVerificationService(mockRepo, redis.NewClient(&redis.Options{}), mail, cfg)
+
+	user := &model.User{ID: primitive.NewObjectID(), Email: "verify@example.com"}
+	mockRepo.On("Update", mock.Anything, mock.Anything, user.ID).Return(user, nil)
+
+	assert.NoError(t, verificationService.SendVerification(ctx, user))
+	first := mail.token(t)
+
+	// Only the latest link works
+	assert.NoError(t, verificationService.SendVerification(ctx, user))
+	latest := mail.token(t)
+	assert.ErrorIs(t, verificationService.Verify(ctx, first), service.ErrInvalidVerificationToken)
+
+	assert.NoError(t, verificationService.Verify(ctx, latest))
+	mockRepo.AssertCalled(t, "Update", mock.Anything, mock.MatchedBy(func(payload bson.M) bool {
+		return payload["email_verified"] == true
+	}), user.ID)
+
+	// The token is single use
+	assert.ErrorIs(t, verificationService.Verify(ctx, latest), service.ErrInvalidVerificationToken)
+
+	user.EmailVerified = true
+	assert.ErrorIs(t, verificationService.SendVerification(ctx, user), service.ErrEmailAlreadyVerified)
+}
+
+func TestEmailVerificationRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	mockRedis := redis.NewClient(&redis.Options{})
+	mail := &captureMailService{}
+	cfg := &config.Config{
+		JWTExpiresIn:             "1h",
+		JWTRefreshKey:            "test-refresh",
+		JWTRefreshIn:             "24h",
+		EmailVerificationTTL:     time.Hour,
+		AppURL:                   "http://localhost:3000",
+		RequireEmailVerification: true,
+	}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	auditService := service.NewAuditService(discardAuditRepository{})
+	tokenService := service.NewTokenService(mockRedis, auth, auditService, cfg)
+	userService := service.NewUserService(mockRepo, mockRedis, tokenService, auditService, cfg)
+	verificationService := service.NewEmailVerificationService(mockRepo, mockRedis, mail, cfg)
+	authMiddleware := middleware.NewAuthMiddleware(userService, tokenService, nil, nil, nil, nil, cfg)
+	verificationHandler := handlers.NewEmailVerificationHandler(verificationService)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	user := &model.User{
+		ID:       primitive.NewObjectID(),
+		Email:    "unverified@example.com",
+		Password: string(hashedPassword),
+		Roles:    []string{"user"},
+	}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"_id": user.ID}).Return(user, nil)
+	mockRepo.On("Update", mock.Anything, mock.Anything, user.ID).Run(func(args mock.Arguments) {
+		user.EmailVerified = args.Get(1).(bson.M)["email_verified"] == true
+	}).Return(user, nil)
+
+	pair, err := userService.Login(ctx, "password123", user, model.ClientInfo{})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/auth/verify-email", verificationHandler.Verify)
+	router.POST("/auth/verify-email/resend", authMiddleware.ProtectedUnverified(), verificationHandler.Resend)
+	router.GET("/me", authMiddleware.Protected(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Protected routes reject the account until it is verified
+	w := request(http.MethodGet, "/me", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, utils.ErrCodeEmailNotVerified, response["code"])
+
+	// The resend route stays open to the unverified account
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/auth/verify-email/resend", "").Code)
+	body, _ := json.Marshal(map[string]string{"token": mail.token(t)})
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/auth/verify-email", string(body)).Code)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/me", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/auth/verify-email/resend", "").Code)
+}
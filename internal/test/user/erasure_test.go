@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scopeName names the organization scope of ctx, "personal" without one
+func scopeName(ctx context.Context) string {
+	if orgID, ok := repository.OrgFromContext(ctx); ok {
+		return orgID.Hex()
+	}
+	return "personal"
+}
+
+// erasureProducts records the scopes products were deleted from and
+// reassigned in
+type erasureProducts struct {
+	repository.ProductRepository
+	deleted    []string
+	reassigned map[string]primitive.ObjectID
+}
+
+func (r *erasureProducts) DeleteMany(ctx context.Context, query bson.D) (int64, error) {
+	r.deleted = append(r.deleted, scopeName(ctx))
+	return 1, nil
+}
+
+func (r *erasureProducts) UpdateMany(ctx context.Context, query bson.D, update bson.M) (int64, error) {
+	r.reassigned[scopeName(ctx)] = update["$set"].(bson.M)["user_id"].(primitive.ObjectID)
+	return 1, nil
+}
+
+type erasureFiles struct {
+	repository.LocalFileRepository
+}
+
+func (erasureFiles) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]*model.FileStorage, error) {
+	return nil, nil
+}
+
+func (erasureFiles) UpdateMany(ctx context.Context, query bson.D, update bson.M) (int64, error) {
+	return 0, nil
+}
+
+type erasureMemberships struct {
+	repository.MembershipRepository
+	memberships []*model.Membership
+	promoted    []primitive.ObjectID
+}
+
+func (r *erasureMemberships) FindAll(ctx context.Context, query bson.M) ([]*model.Membership, error) {
+	var found []*model.Membership
+	for _, membership := range r.memberships {
+		if membership.UserID == query["user_id"] || membership.OrgID == query["org_id"] {
+			found = append(found, membership)
+		}
+	}
+	return found, nil
+}
+
+func (r *erasureMemberships) Update(ctx context.Context, query bson.M, payload bson.M) (*model.Membership, error) {
+	r.promoted = append(r.promoted, query["_id"].(primitive.ObjectID))
+	return nil, nil
+}
+
+func (r *erasureMemberships) Delete(ctx context.Context, query bson.M) error {
+	for i, membership := range r.memberships {
+		if membership.ID == query["_id"] {
+			r.memberships = append(r.memberships[:i], r.memberships[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+type erasureOrgs struct {
+	repository.OrganizationRepository
+	deleted []primitive.ObjectID
+}
+
+func (r *erasureOrgs) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+// erasureCarts records the users whose cart was deleted
+type erasureCarts struct {
+	repository.CartRepository
+	deleted []interface{}
+}
+
+func (r *erasureCarts) Delete(ctx context.Context, query bson.M) error {
+	r.deleted = append(r.deleted, query["user_id"])
+	return nil
+}
+
+type erasureAPIKeys struct {
+	repository.APIKeyRepository
+}
+
+func (erasureAPIKeys) FindAll(ctx context.Context, query bson.M) ([]*model.APIKey, error) {
+	return nil, nil
+}
+
+func TestEraseHandsOverOrgContent(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{JWTExpiresIn: "1h", JWTRefreshKey: "test-refresh", JWTRefreshIn: "24h"}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	auditService := service.NewAuditService(discardAuditRepository{})
+	tokenService := service.NewTokenService(redis.NewClient(&redis.Options{}), auth, auditService, cfg)
+
+	user := &model.User{ID: primitive.NewObjectID(), Name: "Leaving", Email: "leaving@example.com"}
+	shared, solo := primitive.NewObjectID(), primitive.NewObjectID()
+	admin := &model.Membership{ID: primitive.NewObjectID(), OrgID: shared, UserID: primitive.NewObjectID(), Role: model.OrgRoleAdmin}
+	memberships := &erasureMemberships{memberships: []*model.Membership{
+		{ID: primitive.NewObjectID(), OrgID: shared, UserID: user.ID, Role: model.OrgRoleOwner},
+		admin,
+		{ID: primitive.NewObjectID(), OrgID: solo, UserID: user.ID, Role: model.OrgRoleOwner},
+	}}
+	products := &erasureProducts{reassigned: map[string]primitive.ObjectID{}}
+	orgs := &erasureOrgs{}
+	carts := &erasureCarts{}
+	userRepo := NewMockUserRepository()
+	userRepo.On("FindOneAndUpdate", mock.Anything, bson.M{"_id": user.ID}, mock.Anything).Return(user, nil)
+
+	privacyService := service.NewPrivacyService(userRepo, products, erasureFiles{}, memberships, orgs, erasureAPIKeys{}, nil, carts, tokenService, auditService, &captureMailService{}, cfg)
+	assert.NoError(t, privacyService.Erase(ctx, user))
+
+	// The admin of the shared organization becomes its owner and gets the products
+	assert.Equal(t, []primitive.ObjectID{admin.ID}, memberships.promoted)
+	assert.Equal(t, admin.UserID, products.reassigned[shared.Hex()])
+
+	// The organization the user was alone in goes with them, as do personal products
+	assert.Equal(t, []primitive.ObjectID{solo}, orgs.deleted)
+	assert.ElementsMatch(t, []string{solo.Hex(), "personal"}, products.deleted)
+	assert.Equal(t, []*model.Membership{admin}, memberships.memberships)
+	assert.Equal(t, []interface{}{user.ID}, carts.deleted)
+
+	update := userRepo.Calls[0].Arguments.Get(2).(bson.M)
+	assert.Equal(t, "erased-"+user.ID.Hex()+"@invalid", update["$set"].(bson.M)["email"])
+	assert.Equal(t, "", update["$set"].(bson.M)["password"])
+}
+
+func TestLastAdminCannotBeErased(t *testing.T) {
+	ctx := context.Background()
+	admin := &model.User{ID: primitive.NewObjectID(), Email: "admin@example.com", Roles: []string{"user", "admin"}}
+	userRepo := NewMockUserRepository()
+	userRepo.On("FindOne", mock.Anything, mock.Anything).Return(admin, nil)
+	userRepo.On("Count", mock.Anything, mock.Anything).Return(int64(0), nil)
+	privacyService := service.NewPrivacyService(userRepo, nil, nil, nil, nil, nil, nil, nil, nil, service.NewAuditService(nil), &captureMailService{}, &config.Config{})
+
+	_, err := privacyService.RequestErasure(ctx, admin, "")
+	assert.ErrorIs(t, err, service.ErrLastAdminErasure)
+	assert.ErrorIs(t, privacyService.Purge(ctx, admin.ID), service.ErrLastAdminErasure)
+	userRepo.AssertNotCalled(t, "FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// stockProducts holds one product and applies $inc updates, evaluating the
+// available stock condition the way MongoDB would
+type stockProducts struct {
+	repository.ProductRepository
+	product *model.Product
+}
+
+func (r *stockProducts) FindOne(ctx context.Context, query bson.M) (*model.Product, error) {
+	product := *r.product
+	return &product, nil
+}
+
+func (r *stockProducts) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Product, error) {
+	if expr, ok := query["$expr"]; ok {
+		needed := expr.(bson.M)["$gte"].(bson.A)[1].(int)
+		if r.product.Stock-r.product.Reserved < needed {
+			return nil, mongo.ErrNoDocuments
+		}
+	}
+	inc := update["$inc"].(bson.M)
+	if delta, ok := inc["stock"]; ok {
+		r.product.Stock += delta.(int)
+	}
+	if delta, ok := inc["reserved"]; ok {
+		r.product.Reserved += delta.(int)
+	}
+	return r.FindOne(ctx, query)
+}
+
+type stockLedger struct {
+	repository.StockMovementRepository
+	movements []*model.StockMovement
+}
+
+func (r *stockLedger) Create(ctx context.Context, movement *model.StockMovement) error {
+	r.movements = append(r.movements, movement)
+	return nil
+}
+
+type stockReservations struct {
+	repository.StockReservationRepository
+	reservations map[primitive.ObjectID]*model.StockReservation
+}
+
+func (r *stockReservations) Create(ctx context.Context, reservation *model.StockReservation) error {
+	r.reservations[reservation.ID] = reservation
+	return nil
+}
+
+func (r *stockReservations) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.StockReservation, error) {
+	reservation, ok := r.reservations[query["_id"].(primitive.ObjectID)]
+	if !ok || reservation.ReleasedAt != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	now := update["$set"].(bson.M)["released_at"].(time.Time)
+	reservation.ReleasedAt = &now
+	return reservation, nil
+}
+
+// FindAll finds the active reservations of a user and product, or the
+// expired ones without a user
+func (r *stockReservations) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]*model.StockReservation, error) {
+	var found []*model.StockReservation
+	for _, reservation := range r.reservations {
+		if reservation.ReleasedAt != nil || reservation.ConsumedAt != nil {
+			continue
+		}
+		if userID, ok := query["user_id"]; ok {
+			if reservation.UserID == userID && reservation.ProductID == query["product_id"] {
+				found = append(found, reservation)
+			}
+		} else if !reservation.ExpiresAt.After(time.Now()) {
+			found = append(found, reservation)
+		}
+	}
+	return found, nil
+}
+
+func TestStockNeverCoversReservations(t *testing.T) {
+	ctx := context.Background()
+	product := &model.Product{ID: primitive.NewObjectID(), Name: "Chair"}
+	products := &stockProducts{product: product}
+	ledger := &stockLedger{}
+	reservations := &stockReservations{reservations: map[primitive.ObjectID]*model.StockReservation{}}
+	inventory := service.NewInventoryService(products, ledger, reservations, &config.Config{StockReservationTTL: -time.Second, StockReservationMaxQuantity: 4})
+
+	_, err := inventory.Record(ctx, product.ID, model.StockReceipt, 5, "", nil)
+	assert.NoError(t, err)
+	userID := primitive.NewObjectID()
+	_, err = inventory.Reserve(ctx, product, userID, 3)
+	assert.NoError(t, err)
+
+	// One user cannot hold more than the limit across reservations
+	_, err = inventory.Reserve(ctx, product, userID, 2)
+	assert.ErrorIs(t, err, service.ErrReservationLimit)
+	assert.Equal(t, 3, product.Reserved)
+
+	// Two of the five are free, the reserved three cannot be sold
+	_, err = inventory.Record(ctx, product.ID, model.StockSale, 3, "", nil)
+	assert.ErrorIs(t, err, service.ErrInsufficientStock)
+	_, err = inventory.Record(ctx, product.ID, model.StockSale, 2, "", nil)
+	assert.NoError(t, err)
+	_, err = inventory.Record(ctx, product.ID, model.StockSale, -1, "", nil)
+	assert.ErrorIs(t, err, service.ErrInvalidStockMovement)
+
+	// The reservation was made already expired
+	inventory.ReleaseExpired(ctx)
+	assert.Equal(t, 3, product.Stock)
+	assert.Equal(t, 0, product.Reserved)
+
+	assert.Len(t, ledger.movements, 2)
+	assert.Equal(t, []int{5, 3}, []int{ledger.movements[0].StockAfter, ledger.movements[1].StockAfter})
+	assert.Equal(t, -2, ledger.movements[1].Quantity)
+}
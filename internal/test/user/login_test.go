@@ -7,6 +7,7 @@ import (
 	"example-go-project/internal/model"
 	"example-go-project/internal/service"
 	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -49,12 +50,18 @@ func TestLogin(t *testing.T) {
 	mockRepo := NewMockUserRepository()
 	mockRedis := redis.NewClient(&redis.Options{})
 	cfg := &config.Config{
-		JWTSecretKey:  "test-secret",
 		JWTExpiresIn:  "1h",
 		JWTRefreshKey: "test-refresh",
 		JWTRefreshIn:  "24h",
 	}
-	userService := service.NewUserService(mockRepo, mockRedis, cfg)
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	tokenService := service.NewTokenService(mockRedis, auth, service.NewAuditService(discardAuditRepository{}), cfg)
+	userService := service.NewUserService(mockRepo, mockRedis, tokenService, service.NewAuditService(discardAuditRepository{}), cfg)
+	loginThrottle := service.NewLoginThrottleService(mockRedis, service.NewAuditService(discardAuditRepository{}), cfg)
+	twoFactorService := service.NewTwoFactorService(mockRepo, mockRedis, tokenService, loginThrottle, service.NewAuditService(discardAuditRepository{}), cfg)
+	verificationService := service.NewEmailVerificationService(mockRepo, mockRedis, service.NewMailService(cfg), cfg)
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 
 	tests := []struct {
@@ -100,10 +107,10 @@ func TestLogin(t *testing.T) {
 			setupMock: func(m *MockUserRepository) {
 				m.On("FindOne", mock.Anything, bson.M{"email": "nonexistent@example.com"}).Return(nil, mongo.ErrNoDocuments)
 			},
-			expectedStatus: http.StatusNotFound,
+			expectedStatus: http.StatusUnauthorized,
 			expectedBody: gin.H{
 				"success": false,
-				"error":   "Failed to find user",
+				"error":   "Invalid email or password",
 			},
 		},
 		{
@@ -154,7 +161,7 @@ func TestLogin(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody: gin.H{
 				"success": false,
-				"error":   "Invalid password",
+				"error":   "Invalid email or password",
 			},
 		},
 	}
@@ -165,7 +172,7 @@ func TestLogin(t *testing.T) {
 			tt.setupMock(mockRepo)
 
 			// Create handler with the real service (which uses our mock repository)
-			handler := handlers.NewUserHandler(userService)
+			handler := handlers.NewUserHandler(userService, twoFactorService, verificationService, loginThrottle)
 
 			// Create test context
 			jsonData, _ := json.Marshal(tt.input)
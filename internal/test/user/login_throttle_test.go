@@ -0,0 +1,129 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// discardAuditRepository drops recorded events, the services under test
+// only call Create
+type discardAuditRepository struct {
+	repository.AuditRepository
+}
+
+func (discardAuditRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	return nil
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{
+		LoginMaxFailures:   4,
+		LoginDelayAfter:    2,
+		LoginFailureWindow: time.Minute,
+		LoginLockout:       time.Minute,
+	}
+	redisClient := redis.NewClient(&redis.Options{})
+	throttle := service.NewLoginThrottleService(redisClient, service.NewAuditService(discardAuditRepository{}), cfg)
+
+	user := &model.User{ID: primitive.NewObjectID(), Email: "lockout-" + primitive.NewObjectID().Hex() + "@example.com"}
+	admin := &model.User{ID: primitive.NewObjectID()}
+	ip := "203.0.113.7"
+
+	_, err := throttle.Check(ctx, user.Email, ip)
+	assert.NoError(t, err)
+
+	// The first failure is free, the second one asks the client to wait
+	throttle.RecordFailure(ctx, user.Email, ip, user.ID)
+	_, err = throttle.Check(ctx, user.Email, ip)
+	assert.NoError(t, err)
+
+	throttle.RecordFailure(ctx, user.Email, ip, user.ID)
+	wait, err := throttle.Check(ctx, user.Email, ip)
+	assert.ErrorIs(t, err, service.ErrLoginThrottled)
+	assert.True(t, wait > 0 && wait <= time.Second)
+
+	// Emails are matched without case, so changing it does not reset the count
+	throttle.RecordFailure(ctx, "LOCKOUT"+user.Email[len("lockout"):], ip, user.ID)
+	throttle.RecordFailure(ctx, user.Email, ip, user.ID)
+	wait, err = throttle.Check(ctx, user.Email, ip)
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+	assert.True(t, wait > 30*time.Second)
+
+	// Other accounts from the same address are not affected
+	_, err = throttle.Check(ctx, "other-"+user.Email, ip)
+	assert.NoError(t, err)
+
+	assert.NoError(t, throttle.Unlock(ctx, admin, user, ip))
+	_, err = throttle.Check(ctx, user.Email, ip)
+	assert.NoError(t, err)
+}
+
+func TestTwoFactorFailuresLockAccount(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	redisClient := redis.NewClient(&redis.Options{})
+	cfg := &config.Config{
+		JWTExpiresIn:       "1h",
+		JWTRefreshKey:      "test-refresh",
+		JWTRefreshIn:       "24h",
+		LoginMaxFailures:   3,
+		LoginFailureWindow: time.Minute,
+		LoginLockout:       time.Minute,
+	}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	auditService := service.NewAuditService(discardAuditRepository{})
+	tokenService := service.NewTokenService(redisClient, auth, auditService, cfg)
+	throttle := service.NewLoginThrottleService(redisClient, auditService, cfg)
+	twoFactorService := service.NewTwoFactorService(mockRepo, redisClient, tokenService, throttle, auditService, cfg)
+
+	secret, err := utils.GenerateTOTPSecret()
+	assert.NoError(t, err)
+	user := &model.User{
+		ID:               primitive.NewObjectID(),
+		Email:            "2fa-" + primitive.NewObjectID().Hex() + "@example.com",
+		TwoFactorEnabled: true,
+		TOTPSecret:       secret,
+	}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"_id": user.ID}).Return(user, nil)
+	// Wrong codes are also tried as recovery codes
+	mockRepo.On("FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	ip := "203.0.113.9"
+	complete := func(code string) error {
+		challenge, err := twoFactorService.CreateChallenge(ctx, user, model.ClientInfo{IP: ip}, utils.AMRPassword)
+		assert.NoError(t, err)
+		_, err = twoFactorService.CompleteChallenge(ctx, challenge.ChallengeToken, code, model.ClientInfo{IP: ip})
+		return err
+	}
+
+	// A correct code clears the failures before it
+	assert.ErrorIs(t, complete("wrong-code"), service.ErrInvalidTwoFactorCode)
+	assert.ErrorIs(t, complete("wrong-code"), service.ErrInvalidTwoFactorCode)
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	assert.NoError(t, err)
+	assert.NoError(t, complete(code))
+
+	// Every challenge is new, the failures still add up to a lock
+	for i := 0; i < cfg.LoginMaxFailures; i++ {
+		assert.ErrorIs(t, complete("wrong-code"), service.ErrInvalidTwoFactorCode)
+	}
+	assert.ErrorIs(t, complete(code), service.ErrAccountLocked)
+	_, err = throttle.Check(ctx, user.Email, "198.51.100.1")
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMagicLinkLogin(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	mockRedis := redis.NewClient(&redis.Options{})
+	mail := &captureMailService{}
+	cfg := &config.Config{
+		JWTExpiresIn:        "1h",
+		JWTRefreshKey:       "test-refresh",
+		JWTRefreshIn:        "24h",
+		MagicLinkTTL:        time.Minute,
+		MagicLinkMaxPerHour: 4,
+		AppURL:              "http://localhost:3000",
+	}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	tokenService := service.NewTokenService(mockRedis, auth, service.NewAuditService(discardAuditRepository{}), cfg)
+	magicLinkService := service.NewMagicLinkService(mockRepo, mockRedis, tokenService, nil, mail, cfg)
+	mockRedis.Del(ctx, "magic_link_rate:"+utils.HashToken("magic@example.com"))
+
+	user := &model.User{
+		ID:            primitive.NewObjectID(),
+		Email:         "magic@example.com",
+		Roles:         []string{"user"},
+		EmailVerified: true,
+	}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"email": user.Email}).Return(user, nil)
+	mockRepo.On("FindOne", mock.Anything, bson.M{"_id": user.ID}).Return(user, nil)
+
+	device := model.ClientInfo{IP: "10.0.0.1", DeviceName: "laptop"}
+	request := func() (string, string) {
+		requestID, _, err := magicLinkService.Request(ctx, user.Email, device)
+		assert.NoError(t, err)
+		return mail.token(t), requestID
+	}
+
+	// A link opened from another IP is used up without signing in
+	token, requestID := request()
+	_, err = magicLinkService.Verify(ctx, token, requestID, model.ClientInfo{IP: "10.0.0.2"})
+	assert.ErrorIs(t, err, service.ErrInvalidMagicLink)
+	_, err = magicLinkService.Verify(ctx, token, requestID, device)
+	assert.ErrorIs(t, err, service.ErrInvalidMagicLink)
+
+	token, requestID = request()
+	login, err := magicLinkService.Verify(ctx, token, requestID, device)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenService.ValidateTokenWithRedis(ctx, login.TokenPair.AccessToken))
+
+	// The link is single use
+	_, err = magicLinkService.Verify(ctx, token, requestID, device)
+	assert.ErrorIs(t, err, service.ErrInvalidMagicLink)
+
+	// A newer link replaces the previous one
+	oldToken, oldRequestID := request()
+	token, requestID = request()
+	_, err = magicLinkService.Verify(ctx, oldToken, oldRequestID, device)
+	assert.ErrorIs(t, err, service.ErrInvalidMagicLink)
+	_, err = magicLinkService.Verify(ctx, token, requestID, device)
+	assert.NoError(t, err)
+
+	_, wait, err := magicLinkService.Request(ctx, user.Email, device)
+	assert.ErrorIs(t, err, service.ErrMagicLinkThrottled)
+	assert.Greater(t, wait, time.Duration(0))
+}
@@ -23,6 +23,11 @@ func NewMockUserRepository() *MockUserRepository {
 	return &MockUserRepository{}
 }
 
+func (m *MockUserRepository) EnsureIndexes(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) Create(ctx context.Context, user *model.User) error {
 	args := m.Called(ctx, user)
 	return args.Error(0)
@@ -36,11 +41,29 @@ func (m *MockUserRepository) Update(ctx context.Context, payload bson.M, id prim
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.User, error) {
+	args := m.Called(ctx, query, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
 func (m *MockUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Purge(ctx context.Context, id primitive.ObjectID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) FindOne(ctx context.Context, query bson.M) (*model.User, error) {
 	args := m.Called(ctx, query)
 	if args.Get(0) == nil {
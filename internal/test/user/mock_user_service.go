@@ -54,8 +54,8 @@ func (m *MockUserService) Create(ctx context.Context, req *dto.RegisterRequest)
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
-func (m *MockUserService) Login(ctx context.Context, password string, user *model.User) (*utils.TokenPair, error) {
-	args := m.Called(ctx, password, user)
+func (m *MockUserService) Login(ctx context.Context, password string, user *model.User, client model.ClientInfo) (*utils.TokenPair, error) {
+	args := m.Called(ctx, password, user, client)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -80,19 +80,14 @@ func (m *MockUserService) FindAll(ctx context.Context, filter dto.UserFilter, pa
 	return args.Get(0).([]model.User), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockUserService) RefreshToken(ctx context.Context, refreshToken string) (*utils.TokenPair, error) {
-	args := m.Called(ctx, refreshToken)
+func (m *MockUserService) RefreshToken(ctx context.Context, refreshToken string, client model.ClientInfo) (*utils.TokenPair, error) {
+	args := m.Called(ctx, refreshToken, client)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*utils.TokenPair), args.Error(1)
 }
 
-func (m *MockUserService) ValidateTokenWithRedis(ctx context.Context, token string) error {
-	args := m.Called(ctx, token)
-	return args.Error(0)
-}
-
 func (m *MockUserService) Logout(ctx context.Context, accessToken, refreshToken string) error {
 	args := m.Called(ctx, accessToken, refreshToken)
 	return args.Error(0)
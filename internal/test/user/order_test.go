@@ -0,0 +1,191 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// inlineTransactor runs the function without a transaction, the fakes have
+// nothing to roll back
+type inlineTransactor struct{}
+
+func (inlineTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// retryingTransactor runs the function, rolls back its effects on the fakes
+// and runs it again, as MongoDB does after a transient error
+type retryingTransactor struct {
+	snapshot func() (rollback func())
+}
+
+func (r retryingTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	rollback := r.snapshot()
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	rollback()
+	return fn(ctx)
+}
+
+// countingPayments counts the refunds that reach the provider
+type countingPayments struct {
+	*service.FakePaymentProvider
+	refunds int
+}
+
+func (p *countingPayments) Refund(ctx context.Context, order *model.Order) error {
+	p.refunds++
+	return p.FakePaymentProvider.Refund(ctx, order)
+}
+
+// storedOrders applies the status updates of OrderService to one order
+type storedOrders struct {
+	repository.OrderRepository
+	order *model.Order
+}
+
+func (r *storedOrders) Create(ctx context.Context, order *model.Order) error {
+	stored := *order
+	r.order = &stored
+	return nil
+}
+
+func (r *storedOrders) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Order, error) {
+	if r.order == nil || r.order.Status != query["status"] {
+		return nil, mongo.ErrNoDocuments
+	}
+	set := update["$set"].(bson.M)
+	r.order.Status = set["status"].(string)
+	if paymentID, ok := set["payment_id"]; ok {
+		r.order.PaymentID = paymentID.(string)
+	}
+	r.order.History = append(r.order.History, update["$push"].(bson.M)["history"].(model.OrderStatusChange))
+	order := *r.order
+	return &order, nil
+}
+
+func TestOrderCheckoutPaymentAndRefund(t *testing.T) {
+	ctx := context.Background()
+	product := &model.Product{ID: primitive.NewObjectID(), Name: "Chair", Price: 19.99, Stock: 5}
+	products := &stockProducts{product: product}
+	reservations := &stockReservations{reservations: map[primitive.ObjectID]*model.StockReservation{}}
+	inventory := service.NewInventoryService(products, &stockLedger{}, reservations, &config.Config{StockReservationTTL: time.Minute})
+	orders := &storedOrders{}
+	payments := service.NewFakePaymentProvider()
+	orderService := service.NewOrderService(orders, products, inventory, inlineTransactor{}, payments, service.NewAuditService(discardAuditRepository{}))
+
+	items := []dto.OrderItemRequest{
+		{ProductID: product.ID.Hex(), Quantity: 2},
+		{ProductID: product.ID.Hex(), Quantity: 1},
+	}
+	order, err := orderService.Create(ctx, primitive.NewObjectID(), items)
+	assert.NoError(t, err)
+	assert.Len(t, order.Items, 1)
+	assert.Equal(t, 59.97, order.Total)
+	assert.Equal(t, 2, product.Stock)
+
+	// The price at checkout stays on the order
+	product.Price = 25
+	_, err = orderService.Create(ctx, primitive.NewObjectID(), []dto.OrderItemRequest{{ProductID: product.ID.Hex(), Quantity: 3}})
+	assert.ErrorIs(t, err, service.ErrInsufficientStock)
+	assert.Equal(t, 2, product.Stock)
+
+	_, err = orderService.Pay(ctx, order, service.FakeDeclinedSource)
+	assert.ErrorIs(t, err, service.ErrPaymentDeclined)
+	assert.Equal(t, model.OrderPending, orders.order.Status)
+
+	paid, err := orderService.Pay(ctx, order, "tok_visa")
+	assert.NoError(t, err)
+	assert.Equal(t, model.OrderPaid, paid.Status)
+	charged, ok := payments.Charged(paid.PaymentID)
+	assert.True(t, ok)
+	assert.Equal(t, 59.97, charged)
+
+	// Paying again works against the stale copy and must not charge twice
+	_, err = orderService.Pay(ctx, paid, "tok_visa")
+	assert.ErrorIs(t, err, service.ErrInvalidOrderTransition)
+	_, err = orderService.Pay(ctx, order, "tok_visa")
+	assert.ErrorIs(t, err, service.ErrOrderChanged)
+
+	_, err = orderService.Transition(ctx, paid, model.OrderCancelled)
+	assert.ErrorIs(t, err, service.ErrInvalidOrderTransition)
+
+	refunded, err := orderService.Transition(ctx, paid, model.OrderRefunded)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OrderRefunded, refunded.Status)
+	_, ok = payments.Charged(paid.PaymentID)
+	assert.False(t, ok)
+	assert.Equal(t, 5, product.Stock)
+	assert.Equal(t, 19.99, refunded.Items[0].Price)
+
+	var statuses []string
+	for _, change := range refunded.History {
+		statuses = append(statuses, change.Status)
+	}
+	assert.Equal(t, []string{model.OrderPending, model.OrderPaid, model.OrderRefunding, model.OrderRefunded}, statuses)
+}
+
+func TestOrderRefundOutsideRetriedTransaction(t *testing.T) {
+	ctx := context.Background()
+	product := &model.Product{ID: primitive.NewObjectID(), Name: "Chair", Price: 10, Stock: 5}
+	products := &stockProducts{product: product}
+	reservations := &stockReservations{reservations: map[primitive.ObjectID]*model.StockReservation{}}
+	inventory := service.NewInventoryService(products, &stockLedger{}, reservations, &config.Config{StockReservationTTL: time.Minute})
+	orders := &storedOrders{}
+	payments := &countingPayments{FakePaymentProvider: service.NewFakePaymentProvider()}
+	auditService := service.NewAuditService(discardAuditRepository{})
+
+	order, err := service.NewOrderService(orders, products, inventory, inlineTransactor{}, payments, auditService).
+		Create(ctx, primitive.NewObjectID(), []dto.OrderItemRequest{{ProductID: product.ID.Hex(), Quantity: 2}})
+	assert.NoError(t, err)
+
+	transactor := retryingTransactor{snapshot: func() func() {
+		order := *orders.order
+		order.History = append([]model.OrderStatusChange{}, order.History...)
+		stock := product.Stock
+		return func() {
+			*orders.order = order
+			product.Stock = stock
+		}
+	}}
+	orderService := service.NewOrderService(orders, products, inventory, transactor, payments, auditService)
+
+	paid, err := orderService.Pay(ctx, order, "tok_visa")
+	assert.NoError(t, err)
+
+	refunded, err := orderService.Transition(ctx, paid, model.OrderRefunded)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OrderRefunded, refunded.Status)
+	assert.Equal(t, 1, payments.refunds)
+	assert.Equal(t, 5, product.Stock)
+	_, ok := payments.Charged(paid.PaymentID)
+	assert.False(t, ok)
+
+	var statuses []string
+	for _, change := range refunded.History {
+		statuses = append(statuses, change.Status)
+	}
+	assert.Equal(t, []string{model.OrderPending, model.OrderPaid, model.OrderRefunding, model.OrderRefunded}, statuses)
+
+	// A refund left unfinished is completed by refunding again
+	orders.order.Status = model.OrderRefunding
+	orders.order.History = orders.order.History[:3]
+	product.Stock = 3
+	stuck := *orders.order
+	refunded, err = orderService.Transition(ctx, &stuck, model.OrderRefunded)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OrderRefunded, refunded.Status)
+	assert.Equal(t, 5, product.Stock)
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type invitingOrgs struct {
+	repository.OrganizationRepository
+	org *model.Organization
+}
+
+func (r invitingOrgs) FindOne(ctx context.Context, query bson.M) (*model.Organization, error) {
+	return r.org, nil
+}
+
+// storedInvitations keeps the invitations that were created and not deleted
+type storedInvitations struct {
+	repository.OrgInvitationRepository
+	invitations map[primitive.ObjectID]*model.OrgInvitation
+}
+
+func (r *storedInvitations) Create(ctx context.Context, invitation *model.OrgInvitation) error {
+	r.invitations[invitation.ID] = invitation
+	return nil
+}
+
+func (r *storedInvitations) Delete(ctx context.Context, query bson.M) error {
+	delete(r.invitations, query["_id"].(primitive.ObjectID))
+	return nil
+}
+
+func TestInviteDeletesUnsentInvitation(t *testing.T) {
+	ctx := context.Background()
+	org := &model.Organization{ID: primitive.NewObjectID(), Name: "Acme"}
+	owner := &model.Membership{OrgID: org.ID, UserID: primitive.NewObjectID(), Role: model.OrgRoleOwner}
+	userRepo := NewMockUserRepository()
+	userRepo.On("FindOne", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+	invitations := &storedInvitations{invitations: map[primitive.ObjectID]*model.OrgInvitation{}}
+	orgService := service.NewOrgService(invitingOrgs{org: org}, nil, invitations, userRepo, nil, nil, nil, failingMailService{}, service.NewAuditService(discardAuditRepository{}), &config.Config{})
+
+	_, err := orgService.Invite(ctx, owner, &model.User{ID: owner.UserID, Name: "Owner"}, "new@example.com", model.OrgRoleMember)
+	assert.Error(t, err)
+	assert.Empty(t, invitations.invitations)
+}
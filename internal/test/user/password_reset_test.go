@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// captureMailService keeps the last mail, which may be sent in the background
+type captureMailService struct {
+	mu   sync.Mutex
+	body string
+}
+
+func (m *captureMailService) Send(ctx context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.body = body
+	return nil
+}
+
+// token waits for a mail and returns the token of its link
+func (m *captureMailService) token(t *testing.T) string {
+	var match []string
+	assert.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		match = regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(m.body)
+		m.body = ""
+		return match != nil
+	}, time.Second, time.Millisecond)
+	if match == nil {
+		return ""
+	}
+	token, _ := url.QueryUnescape(match[1])
+	return token
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	mockRedis := redis.NewClient(&redis.Options{})
+	mail := &captureMailService{}
+	cfg := &config.Config{
+		JWTExpiresIn:     "1h",
+		JWTRefreshKey:    "test-refresh",
+		JWTRefreshIn:     "24h",
+		PasswordResetTTL: time.Hour,
+		AppURL:           "http://localhost:3000",
+	}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	tokenService := service.NewTokenService(mockRedis, auth, service.NewAuditService(discardAuditRepository{}), cfg)
+	userService := service.NewUserService(mockRepo, mockRedis, tokenService, service.NewAuditService(discardAuditRepository{}), cfg)
+	passwordService := service.NewPasswordService(mockRepo, mockRedis, tokenService, mail, service.NewAuditService(discardAuditRepository{}), cfg)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	user := &model.User{
+		ID:       primitive.NewObjectID(),
+		Email:    "reset@example.com",
+		Password: string(hashedPassword),
+		Roles:    []string{"user"},
+	}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"email": user.Email}).Return(user, nil)
+	mockRepo.On("Update", mock.Anything, mock.Anything, user.ID).Return(user, nil)
+
+	loginPair, err := userService.Login(ctx, "password123", user, model.ClientInfo{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, passwordService.ForgotPassword(ctx, user.Email))
+	token := mail.token(t)
+
+	assert.NoError(t, passwordService.ResetPassword(ctx, token, "Newpassword1!"))
+	assert.Error(t, tokenService.ValidateTokenWithRedis(ctx, loginPair.AccessToken))
+
+	// The token is single use
+	assert.ErrorIs(t, passwordService.ResetPassword(ctx, token, "Newpassword1!"), service.ErrInvalidResetToken)
+}
+
+type failingMailService struct{}
+
+func (failingMailService) Send(ctx context.Context, to, subject, body string) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestForgotPasswordHidesMailFailures(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	cfg := &config.Config{PasswordResetTTL: time.Hour}
+	passwordService := service.NewPasswordService(mockRepo, redis.NewClient(&redis.Options{}), nil, failingMailService{}, service.NewAuditService(discardAuditRepository{}), cfg)
+
+	user := &model.User{ID: primitive.NewObjectID(), Email: "known@example.com"}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"email": user.Email}).Return(user, nil)
+	mockRepo.On("FindOne", mock.Anything, bson.M{"email": "unknown@example.com"}).Return(nil, mongo.ErrNoDocuments)
+
+	assert.NoError(t, passwordService.ForgotPassword(ctx, user.Email))
+	assert.NoError(t, passwordService.ForgotPassword(ctx, "unknown@example.com"))
+}
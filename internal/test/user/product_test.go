@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/dto"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// versionedProducts holds one product and applies updates only to the
+// version named in the query, like the conditional update in MongoDB
+type versionedProducts struct {
+	repository.ProductRepository
+	product *model.Product
+}
+
+func (r *versionedProducts) FindOne(ctx context.Context, query bson.M) (*model.Product, error) {
+	product := *r.product
+	return &product, nil
+}
+
+func (r *versionedProducts) FindOneAndUpdate(ctx context.Context, query bson.M, update bson.M) (*model.Product, error) {
+	if versions, ok := query["version"]; ok {
+		matched := false
+		for _, version := range versions.(bson.M)["$in"].(bson.A) {
+			matched = matched || version == r.product.Version
+		}
+		if !matched {
+			return nil, mongo.ErrNoDocuments
+		}
+	}
+	r.product.Name = update["$set"].(bson.M)["name"].(string)
+	r.product.Version++
+	return r.FindOne(ctx, query)
+}
+
+func TestProductUpdateStaleVersion(t *testing.T) {
+	ctx := context.Background()
+	products := &versionedProducts{product: &model.Product{ID: primitive.NewObjectID(), Name: "Chair", Version: 1}}
+	productService := service.NewProductService(products, nil, nil, service.NewAuditService(discardAuditRepository{}))
+
+	price := 10.0
+	payload := &dto.UpdateProductRequest{Name: "Table", Price: &price}
+
+	updated, err := productService.Update(ctx, products.product.ID, payload, []int64{1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+
+	// Another admin read version 1 before the update above
+	payload.Name = "Desk"
+	_, err = productService.Update(ctx, products.product.ID, payload, []int64{1})
+	assert.ErrorIs(t, err, service.ErrProductVersionMismatch)
+	assert.Equal(t, "Table", products.product.Name)
+}
+
+func TestMergePatch(t *testing.T) {
+	patched, err := utils.MergePatch(
+		[]byte(`{"name":"Chair","price":10,"stock":5,"tags":{"color":"red","size":"L"}}`),
+		[]byte(`{"price":12,"stock":null,"tags":{"size":null,"material":"oak"}}`),
+	)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Chair","price":12,"tags":{"color":"red","material":"oak"}}`, string(patched))
+}
+
+func TestCreateProductRequestStock(t *testing.T) {
+	var req dto.CreateProductRequest
+	assert.NoError(t, binding.JSON.BindBody([]byte(`{"name":"Chair","price":10,"stock":0}`), &req))
+	assert.Equal(t, 0, *req.Stock)
+
+	// Stock must be given, and cannot be negative
+	assert.Error(t, binding.JSON.BindBody([]byte(`{"name":"Chair","price":10}`), &dto.CreateProductRequest{}))
+	assert.Error(t, binding.JSON.BindBody([]byte(`{"name":"Chair","price":10,"stock":-1}`), &dto.CreateProductRequest{}))
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	mockRedis := redis.NewClient(&redis.Options{})
+	cfg := &config.Config{
+		JWTExpiresIn:  "1h",
+		JWTRefreshKey: "test-refresh",
+		JWTRefreshIn:  "24h",
+	}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	tokenService := service.NewTokenService(mockRedis, auth, service.NewAuditService(discardAuditRepository{}), cfg)
+	userService := service.NewUserService(mockRepo, mockRedis, tokenService, service.NewAuditService(discardAuditRepository{}), cfg)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	user := &model.User{
+		ID:       primitive.NewObjectID(),
+		Email:    "test@example.com",
+		Password: string(hashedPassword),
+		Roles:    []string{"user"},
+	}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"_id": user.ID}).Return(user, nil)
+
+	loginPair, err := userService.Login(ctx, "password123", user, model.ClientInfo{DeviceName: "test"})
+	assert.NoError(t, err)
+
+	refreshedPair, err := userService.RefreshToken(ctx, loginPair.RefreshToken, model.ClientInfo{})
+	assert.NoError(t, err)
+	assert.NoError(t, tokenService.ValidateTokenWithRedis(ctx, refreshedPair.AccessToken))
+
+	// Replaying the rotated refresh token revokes the whole family
+	_, err = userService.RefreshToken(ctx, loginPair.RefreshToken, model.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrRefreshTokenReused)
+
+	assert.Error(t, tokenService.ValidateTokenWithRedis(ctx, loginPair.AccessToken))
+	assert.Error(t, tokenService.ValidateTokenWithRedis(ctx, refreshedPair.AccessToken))
+
+	_, err = userService.RefreshToken(ctx, refreshedPair.RefreshToken, model.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+}
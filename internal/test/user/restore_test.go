@@ -0,0 +1,31 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRestoreTakenEmail(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	userService := service.NewUserService(mockRepo, nil, nil, service.NewAuditService(discardAuditRepository{}), &config.Config{})
+
+	deletedAt := time.Now()
+	deleted := &model.User{ID: primitive.NewObjectID(), Email: "test@example.com", DeletedAt: &deletedAt}
+	registered := &model.User{ID: primitive.NewObjectID(), Email: "test@example.com"}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"_id": deleted.ID, "deleted_at": bson.M{"$exists": true}}).Return(deleted, nil)
+	mockRepo.On("FindOne", mock.Anything, bson.M{"email": deleted.Email}).Return(registered, nil)
+
+	_, err := userService.Restore(ctx, deleted.ID)
+	assert.ErrorIs(t, err, service.ErrEmailExists)
+	mockRepo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+}
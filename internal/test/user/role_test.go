@@ -0,0 +1,31 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRevokeLastAdmin(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	roleService := service.NewRoleService(mockRepo, nil, service.NewAuditService(nil))
+
+	admin := &model.User{
+		ID:    primitive.NewObjectID(),
+		Email: "admin@example.com",
+		Roles: []string{"user", "admin"},
+	}
+	mockRepo.On("FindOne", mock.Anything, bson.M{"_id": admin.ID}).Return(admin, nil)
+	mockRepo.On("Count", mock.Anything, bson.D{{Key: "roles", Value: "admin"}}).Return(int64(1), nil)
+
+	_, err := roleService.RevokeRole(ctx, admin, admin.ID, "admin", "127.0.0.1")
+	assert.ErrorIs(t, err, service.ErrLastAdmin)
+	mockRepo.AssertNotCalled(t, "FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"example-go-project/internal/model"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/config"
+	"example-go-project/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSessionsListRevokeAndTouch(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := NewMockUserRepository()
+	mockRedis := redis.NewClient(&redis.Options{})
+	cfg := &config.Config{
+		JWTExpiresIn:  "1h",
+		JWTRefreshKey: "test-refresh",
+		JWTRefreshIn:  "24h",
+	}
+	keyManager, err := utils.NewKeyManager(t.TempDir(), utils.AlgRS256, time.Hour)
+	assert.NoError(t, err)
+	auth := utils.NewAuthHandler(keyManager, cfg.JWTRefreshKey, cfg.JWTExpiresIn, cfg.JWTRefreshIn)
+	tokenService := service.NewTokenService(mockRedis, auth, service.NewAuditService(discardAuditRepository{}), cfg)
+	userService := service.NewUserService(mockRepo, mockRedis, tokenService, service.NewAuditService(discardAuditRepository{}), cfg)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	user := &model.User{
+		ID:       primitive.NewObjectID(),
+		Email:    "sessions@example.com",
+		Password: string(hashedPassword),
+		Roles:    []string{"user"},
+	}
+	userID := user.ID.Hex()
+	mockRepo.On("FindOne", mock.Anything, bson.M{"_id": user.ID}).Return(user, nil)
+
+	login := func(device string) (*utils.TokenPair, string) {
+		pair, err := userService.Login(ctx, "password123", user, model.ClientInfo{DeviceName: device})
+		assert.NoError(t, err)
+		claims, err := tokenService.ValidateAccessToken(pair.AccessToken)
+		assert.NoError(t, err)
+		return pair, claims.FamilyID
+	}
+	laptop, laptopID := login("laptop")
+	phone, phoneID := login("phone")
+
+	sessions, err := tokenService.ListSessions(ctx, userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	devices := []string{}
+	for _, session := range sessions {
+		assert.Equal(t, userID, session.UserID)
+		devices = append(devices, session.DeviceName)
+	}
+	assert.ElementsMatch(t, []string{"laptop", "phone"}, devices)
+
+	// Activity moves last_seen_at
+	mockRedis.HSet(ctx, "token_family:"+laptopID, "last_seen_at", "1")
+	assert.NoError(t, tokenService.TouchSession(ctx, laptopID))
+	session, err := tokenService.FindSession(ctx, laptopID)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), session.LastSeenAt, time.Minute)
+
+	// Sessions of other users are not found
+	assert.ErrorIs(t, tokenService.RevokeSession(ctx, primitive.NewObjectID().Hex(), phoneID), service.ErrSessionNotFound)
+
+	// Revoking one session ends its family and leaves the others alone
+	assert.NoError(t, tokenService.RevokeSession(ctx, userID, phoneID))
+	assert.Error(t, tokenService.ValidateTokenWithRedis(ctx, phone.AccessToken))
+	_, err = userService.RefreshToken(ctx, phone.RefreshToken, model.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	// Touching a revoked session does not bring it back
+	assert.NoError(t, tokenService.TouchSession(ctx, phoneID))
+	_, err = tokenService.FindSession(ctx, phoneID)
+	assert.ErrorIs(t, err, service.ErrSessionNotFound)
+
+	sessions, err = tokenService.ListSessions(ctx, userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, laptopID, sessions[0].ID)
+
+	laptop, err = userService.RefreshToken(ctx, laptop.RefreshToken, model.ClientInfo{})
+	assert.NoError(t, err)
+
+	// Logging out everywhere ends the remaining session
+	assert.NoError(t, tokenService.RevokeAllSessions(ctx, userID))
+	assert.Error(t, tokenService.ValidateTokenWithRedis(ctx, laptop.AccessToken))
+	_, err = userService.RefreshToken(ctx, laptop.RefreshToken, model.ClientInfo{})
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	sessions, err = tokenService.ListSessions(ctx, userID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -3,11 +3,15 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	AppName     string
 	ServerPort  string
 	ServerHost  string
 	ServerState string
@@ -15,14 +19,73 @@ type Config struct {
 	MongoDBURI      string
 	MongoDBDatabase string
 
-	JWTSecretKey  string
 	JWTExpiresIn  string
 	JWTRefreshKey string
 	JWTRefreshIn  string
 
+	JWTKeysDir     string
+	JWTSigningAlg  string
+	JWTKeyRotation time.Duration
+
+	TwoFactorRequiredRoles []string
+
+	AdminEmail string
+
+	ImpersonationTTL time.Duration
+
+	AuditRetention time.Duration
+
+	ErasureGracePeriod time.Duration
+	UserPurgeAfter     time.Duration
+
+	StockReservationTTL         time.Duration
+	StockReservationMaxQuantity int
+	PaymentProvider             string
+	CartTTL                     time.Duration
+
+	PasswordResetTTL time.Duration
+
+	MagicLinkTTL        time.Duration
+	MagicLinkMaxPerHour int
+
+	OrgInvitationTTL time.Duration
+
+	InviteOnly    bool
+	InvitationTTL time.Duration
+	InviteSecret  string
+
+	LoginMaxFailures   int
+	LoginIPMaxFailures int
+	LoginDelayAfter    int
+	LoginFailureWindow time.Duration
+	LoginLockout       time.Duration
+
+	RequireEmailVerification bool
+	EmailVerificationTTL     time.Duration
+
 	BaseUrl string
+	AppURL  string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
 
 	RedisURL string
+
+	OIDCProviders []OIDCProvider
+}
+
+// OIDCProvider is an external identity provider users can sign in with,
+// read from OIDC_<NAME>_* for every name listed in OIDC_PROVIDERS.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
 func LoadConfig() *Config {
@@ -32,6 +95,7 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
+		AppName:     getEnv("APP_NAME", "example-go-gin"),
 		ServerPort:  os.Getenv("PORT"),
 		ServerHost:  os.Getenv("HOST"),
 		ServerState: os.Getenv("ENV"),
@@ -39,13 +103,148 @@ func LoadConfig() *Config {
 		MongoDBURI:      os.Getenv("MONGO_URI"),
 		MongoDBDatabase: os.Getenv("MONGO_DB_NAME"),
 
-		JWTSecretKey:  os.Getenv("JWT_SECRET"),
 		JWTExpiresIn:  os.Getenv("JWT_EXPIRY"),
 		JWTRefreshKey: os.Getenv("JWT_REFRESH_SECRET"),
 		JWTRefreshIn:  os.Getenv("JWT_REFRESH_EXPIRY"),
 
+		JWTKeysDir:     getEnv("JWT_KEYS_DIR", "./keys"),
+		JWTSigningAlg:  getEnv("JWT_SIGNING_ALG", "RS256"),
+		JWTKeyRotation: getEnvDuration("JWT_KEY_ROTATION", 30*24*time.Hour),
+
+		TwoFactorRequiredRoles: getEnvList("TWO_FACTOR_REQUIRED_ROLES", nil),
+
+		AdminEmail: os.Getenv("ADMIN_EMAIL"),
+
+		ImpersonationTTL: getEnvDuration("IMPERSONATION_TTL", 15*time.Minute),
+
+		AuditRetention: getEnvDuration("AUDIT_RETENTION", 365*24*time.Hour),
+
+		ErasureGracePeriod: getEnvDuration("ERASURE_GRACE_PERIOD", 30*24*time.Hour),
+		UserPurgeAfter:     getEnvDuration("USER_PURGE_AFTER", 30*24*time.Hour),
+
+		StockReservationTTL:         getEnvDuration("STOCK_RESERVATION_TTL", 15*time.Minute),
+		StockReservationMaxQuantity: getEnvInt("STOCK_RESERVATION_MAX_QUANTITY", 10),
+		PaymentProvider:             getEnv("PAYMENT_PROVIDER", "fake"),
+		CartTTL:                     getEnvDuration("CART_TTL", 7*24*time.Hour),
+
+		PasswordResetTTL: getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		MagicLinkTTL:        getEnvDuration("MAGIC_LINK_TTL", 15*time.Minute),
+		MagicLinkMaxPerHour: getEnvInt("MAGIC_LINK_MAX_PER_HOUR", 5),
+
+		OrgInvitationTTL: getEnvDuration("ORG_INVITATION_TTL", 7*24*time.Hour),
+
+		InviteOnly:    getEnvBool("INVITE_ONLY", false),
+		InvitationTTL: getEnvDuration("INVITATION_TTL", 72*time.Hour),
+		InviteSecret:  os.Getenv("INVITE_SECRET"),
+
+		LoginMaxFailures:   getEnvInt("LOGIN_MAX_FAILURES", 10),
+		LoginIPMaxFailures: getEnvInt("LOGIN_IP_MAX_FAILURES", 100),
+		LoginDelayAfter:    getEnvInt("LOGIN_DELAY_AFTER", 3),
+		LoginFailureWindow: getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockout:       getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		EmailVerificationTTL:     getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+
 		BaseUrl: os.Getenv("DOMAIN"),
+		AppURL:  getEnv("APP_URL", "http://localhost:3000"),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@example.com"),
 
 		RedisURL: os.Getenv("REDIS_URL"),
+
+		OIDCProviders: getOIDCProviders(),
+	}
+}
+
+func getOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range getEnvList("OIDC_PROVIDERS", nil) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       getEnvList(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			log.Printf("Skipping OIDC provider %s, %sISSUER, %sCLIENT_ID and %sREDIRECT_URL are required", name, prefix, prefix, prefix)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvList(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %v, using %t", key, err, fallback)
+		return fallback
+	}
+	return enabled
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid number for %s: %v, using %d", key, err, fallback)
+		return fallback
+	}
+	return number
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %v, using %s", key, err, fallback)
+		return fallback
 	}
+	return duration
 }
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"example-go-project/internal/model"
+	"example-go-project/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// impersonatorFromClaims loads the admin named in the act claim. The admin
+// must still be allowed to impersonate, so removing the permission ends
+// every impersonation at once.
+func (m *AuthMiddleware) impersonatorFromClaims(c *gin.Context, claims *utils.JWTClaims) (*model.User, bool) {
+	impersonator, err := m.userService.FindByID(c, claims.Act.Subject)
+	if err != nil {
+		utils.SendError(c, http.StatusUnauthorized, "Impersonation has ended")
+		c.Abort()
+		return nil, false
+	}
+
+	allowed, err := m.permissionService.HasPermissions(c, impersonator.Roles, utils.PermUserImpersonate)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		c.Abort()
+		return nil, false
+	}
+	if !allowed {
+		utils.SendError(c, http.StatusUnauthorized, "Impersonation has ended")
+		c.Abort()
+		return nil, false
+	}
+
+	return impersonator, true
+}
+
+// ImpersonationReadOnly lets impersonation tokens only read: support staff
+// may look at what the user sees but not change anything on their behalf
+func (m *AuthMiddleware) ImpersonationReadOnly() gin.HandlerFunc {
+	deny := m.DenyImpersonation()
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		deny(c)
+	}
+}
+
+// DenyImpersonation keeps impersonation tokens away from routes they may not
+// even read, like account security and API keys
+func (m *AuthMiddleware) DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := GetPrincipalFromContext(c); ok && principal.IsImpersonated() {
+			utils.SendError(c, http.StatusForbidden, "This route cannot be used while impersonating")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetImpersonatorFromContext retrieves the admin acting as the user, if any
+func GetImpersonatorFromContext(c *gin.Context) (*model.User, bool) {
+	impersonator, exists := c.Get("impersonator")
+	if !exists {
+		return nil, false
+	}
+
+	impersonatorObj, ok := impersonator.(*model.User)
+	return impersonatorObj, ok
+}
@@ -5,6 +5,7 @@ import (
 	"example-go-project/internal/service"
 	"example-go-project/pkg/config"
 	"example-go-project/pkg/utils"
+	"log"
 	"net/http"
 	"strings"
 
@@ -12,20 +13,61 @@ import (
 )
 
 type AuthMiddleware struct {
-	userService *service.UserService
-	config      *config.Config
+	userService       *service.UserService
+	tokenService      *service.TokenService
+	twoFactorService  *service.TwoFactorService
+	permissionService *service.PermissionService
+	apiKeyService     *service.APIKeyService
+	impersonation     *service.ImpersonationService
+	config            *config.Config
 }
 
-func NewAuthMiddleware(userService *service.UserService, config *config.Config) *AuthMiddleware {
+func NewAuthMiddleware(userService *service.UserService, tokenService *service.TokenService, twoFactorService *service.TwoFactorService, permissionService *service.PermissionService, apiKeyService *service.APIKeyService, impersonation *service.ImpersonationService, config *config.Config) *AuthMiddleware {
 	return &AuthMiddleware{
-		userService: userService,
-		config:      config,
+		userService:       userService,
+		tokenService:      tokenService,
+		twoFactorService:  twoFactorService,
+		permissionService: permissionService,
+		apiKeyService:     apiKeyService,
+		impersonation:     impersonation,
+		config:            config,
 	}
 }
 
-// Protected validates JWT token and adds user to context
+// Protected validates JWT token or API key and adds user and principal to
+// context. With REQUIRE_EMAIL_VERIFICATION set, unverified accounts are rejected.
+// An impersonation token also adds the impersonator, and each of its requests
+// is audited
 func (m *AuthMiddleware) Protected() gin.HandlerFunc {
+	return m.protected(m.config.RequireEmailVerification)
+}
+
+// ProtectedUnverified is Protected without the email verification check,
+// for the routes an unverified account uses to verify itself
+func (m *AuthMiddleware) ProtectedUnverified() gin.HandlerFunc {
+	return m.protected(false)
+}
+
+// Optional is Protected for requests with a token or API key and lets
+// anonymous requests through, for routes that serve both
+func (m *AuthMiddleware) Optional() gin.HandlerFunc {
+	protected := m.Protected()
+	return func(c *gin.Context) {
+		if apiKeyFromRequest(c) == "" && c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		protected(c)
+	}
+}
+
+func (m *AuthMiddleware) protected(requireVerified bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			m.authenticateAPIKey(c, key, requireVerified)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			utils.SendError(c, http.StatusUnauthorized, "Authorization header is required")
@@ -41,16 +83,17 @@ func (m *AuthMiddleware) Protected() gin.HandlerFunc {
 		}
 
 		token := bearerToken[1]
-		auth := utils.NewAuthHandler(m.config.JWTSecretKey, m.config.JWTRefreshKey, m.config.JWTExpiresIn, m.config.JWTRefreshIn)
 
-		claims, err := auth.ValidateToken(token)
+		// The kid header selects the verification key, so tokens signed
+		// before a key rotation stay valid until they expire
+		claims, err := m.tokenService.ValidateAccessToken(token)
 		if err != nil {
 			utils.SendError(c, http.StatusUnauthorized, "Invalid token")
 			c.Abort()
 			return
 		}
 
-		if err := m.userService.ValidateTokenWithRedis(c, token); err != nil {
+		if err := m.tokenService.ValidateTokenWithRedis(c, token); err != nil {
 			utils.SendError(c, http.StatusUnauthorized, "Token is invalid or has been revoked")
 			c.Abort()
 			return
@@ -63,14 +106,41 @@ func (m *AuthMiddleware) Protected() gin.HandlerFunc {
 			return
 		}
 
+		if requireVerified && !user.EmailVerified {
+			utils.SendErrorCode(c, http.StatusForbidden, utils.ErrCodeEmailNotVerified, "Email address is not verified")
+			c.Abort()
+			return
+		}
+
+		principal := &model.Principal{User: user}
+		if claims.Impersonated() {
+			impersonator, ok := m.impersonatorFromClaims(c, claims)
+			if !ok {
+				return
+			}
+			principal.Impersonator = impersonator
+			c.Set("impersonator", impersonator)
+		}
+
+		if err := m.tokenService.TouchSession(c, claims.FamilyID); err != nil {
+			log.Printf("Failed to update session %s: %v", claims.FamilyID, err)
+		}
+
 		c.Set("user", user)
+		c.Set("principal", principal)
 		c.Set("token", token)
 		c.Set("claims", claims)
+		c.Request = c.Request.WithContext(service.WithAuditPrincipal(c.Request.Context(), principal))
 		c.Next()
+
+		if principal.IsImpersonated() {
+			m.impersonation.RecordRequest(c.Request.Context(), principal, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP())
+		}
 	}
 }
 
-// RequireRoles checks if user has required roles
+// RequireRoles checks if user has required roles. The roles of the stored
+// user are used, not the ones in the token, so role changes apply at once
 func (m *AuthMiddleware) RequireRoles(roles ...utils.Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, exists := c.Get("user")
@@ -102,6 +172,71 @@ func (m *AuthMiddleware) RequireRoles(roles ...utils.Role) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission checks that the user's roles grant every given
+// permission, using the role definitions stored in MongoDB. API keys are
+// also limited to their scopes
+func (m *AuthMiddleware) RequirePermission(perms ...utils.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipalFromContext(c)
+		if !ok {
+			utils.SendError(c, http.StatusUnauthorized, "User not found in context")
+			c.Abort()
+			return
+		}
+
+		allowed, err := m.permissionService.Allowed(c, principal, perms...)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			utils.SendError(c, http.StatusForbidden, "Insufficient permissions")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireTwoFactor rejects users whose role must use two-factor
+// authentication unless the token was issued after a second factor
+func (m *AuthMiddleware) RequireTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// API keys are created behind this check and cannot create keys,
+		// so a key of a user who must use two-factor came from such a session
+		if principal, ok := GetPrincipalFromContext(c); ok && principal.IsAPIKey() {
+			c.Next()
+			return
+		}
+
+		user, ok := GetUserFromContext(c)
+		if !ok {
+			utils.SendError(c, http.StatusUnauthorized, "User not found in context")
+			c.Abort()
+			return
+		}
+
+		required, err := m.twoFactorService.IsRequired(c, user)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			c.Abort()
+			return
+		}
+
+		claims, _ := GetClaimsFromContext(c)
+		if required && (claims == nil || !claims.HasAMR(utils.AMROTP)) {
+			utils.SendError(c, http.StatusForbidden, "Two-factor authentication is required for your role, enable it and sign in again")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetUserFromContext retrieves user from context
 func GetUserFromContext(c *gin.Context) (*model.User, bool) {
 	user, exists := c.Get("user")
@@ -112,3 +247,14 @@ func GetUserFromContext(c *gin.Context) (*model.User, bool) {
 	userObj, ok := user.(*model.User)
 	return userObj, ok
 }
+
+// GetClaimsFromContext retrieves the access token claims from context
+func GetClaimsFromContext(c *gin.Context) (*utils.JWTClaims, bool) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+
+	claimsObj, ok := claims.(*utils.JWTClaims)
+	return claimsObj, ok
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"errors"
+	"example-go-project/internal/model"
+	"example-go-project/internal/repository"
+	"example-go-project/internal/service"
+	"example-go-project/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrgMiddleware struct {
+	orgService *service.OrgService
+}
+
+func NewOrgMiddleware(orgService *service.OrgService) *OrgMiddleware {
+	return &OrgMiddleware{
+		orgService: orgService,
+	}
+}
+
+// OrgContext selects the active organization from the X-Org-ID header or,
+// without one, from the org claim of the access token. The membership is
+// checked on every request, and the request context is scoped with
+// repository.WithOrg so product and file queries only see that organization.
+// Without an organization requests work in the personal space.
+func (m *OrgMiddleware) OrgContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID := c.GetHeader("X-Org-ID")
+		if orgID == "" {
+			if claims, ok := GetClaimsFromContext(c); ok {
+				orgID = claims.OrgID
+			}
+		}
+		if orgID == "" {
+			c.Next()
+			return
+		}
+
+		objectID, err := primitive.ObjectIDFromHex(orgID)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid organization ID")
+			c.Abort()
+			return
+		}
+
+		membership, ok := m.membership(c, objectID, http.StatusForbidden)
+		if !ok {
+			return
+		}
+
+		c.Set("membership", membership)
+		c.Request = c.Request.WithContext(repository.WithOrg(c.Request.Context(), objectID))
+		c.Next()
+	}
+}
+
+// RequireOrgRole checks that the user has at least the given role in the
+// organization of the :orgId path parameter. Non members get a 404 so the
+// routes do not reveal which organizations exist.
+func (m *OrgMiddleware) RequireOrgRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		objectID, err := primitive.ObjectIDFromHex(c.Param("orgId"))
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid organization ID")
+			c.Abort()
+			return
+		}
+
+		membership, ok := m.membership(c, objectID, http.StatusNotFound)
+		if !ok {
+			return
+		}
+
+		if !service.HasOrgRole(membership, role) {
+			utils.SendError(c, http.StatusForbidden, "Insufficient organization role")
+			c.Abort()
+			return
+		}
+
+		c.Set("membership", membership)
+		c.Next()
+	}
+}
+
+func (m *OrgMiddleware) membership(c *gin.Context, orgID primitive.ObjectID, notMemberStatus int) (*model.Membership, bool) {
+	user, ok := GetUserFromContext(c)
+	if !ok {
+		utils.SendError(c, http.StatusUnauthorized, "User not found in context")
+		c.Abort()
+		return nil, false
+	}
+
+	membership, err := m.orgService.Membership(c, orgID, user.ID)
+	if errors.Is(err, service.ErrNotOrgMember) {
+		if notMemberStatus == http.StatusNotFound {
+			utils.SendError(c, notMemberStatus, "Organization not found")
+		} else {
+			utils.SendError(c, notMemberStatus, err.Error())
+		}
+		c.Abort()
+		return nil, false
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		c.Abort()
+		return nil, false
+	}
+	return membership, true
+}
+
+// GetMembershipFromContext retrieves the membership in the active
+// organization, or in the organization of the :orgId routes
+func GetMembershipFromContext(c *gin.Context) (*model.Membership, bool) {
+	membership, exists := c.Get("membership")
+	if !exists {
+		return nil, false
+	}
+
+	membershipObj, ok := membership.(*model.Membership)
+	return membershipObj, ok
+}
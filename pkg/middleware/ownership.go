@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"errors"
+	"example-go-project/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OwnerResolver returns the id of the user owning the resource a request
+// targets. mongo.ErrNoDocuments means the resource does not exist and
+// primitive.ErrInvalidHex that the request carries a malformed id.
+type OwnerResolver func(c *gin.Context) (primitive.ObjectID, error)
+
+// PathOwner resolves the owner from a path parameter holding a user id, for
+// routes that act on a user directly such as /user/profile/:id.
+func PathOwner(param string) OwnerResolver {
+	return func(c *gin.Context) (primitive.ObjectID, error) {
+		return primitive.ObjectIDFromHex(c.Param(param))
+	}
+}
+
+// OwnerOrPermission lets the owner of the resource through, and anyone else
+// only with every given permission
+func (m *AuthMiddleware) OwnerOrPermission(resolve OwnerResolver, perms ...utils.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := GetPrincipalFromContext(c)
+		if !ok {
+			utils.SendError(c, http.StatusUnauthorized, "User not found in context")
+			c.Abort()
+			return
+		}
+
+		ownerID, err := resolve(c)
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+			c.Abort()
+			return
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			utils.SendError(c, http.StatusNotFound, "Resource not found")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			c.Abort()
+			return
+		}
+
+		allowed, err := m.permissionService.IsOwnerOrPermitted(c, principal, ownerID, perms...)
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			utils.SendError(c, http.StatusForbidden, "Insufficient permissions")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"example-go-project/internal/service"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Incoming ids are kept when they look like ids, anything else is replaced
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID takes the X-Request-ID of the request, or generates one, and
+// echoes it in the response. It also puts the id, IP and user agent in the
+// request context, where AuditService.Record picks them up.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.New().String()
+		}
+
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(service.WithAuditRequest(c.Request.Context(), service.AuditRequest{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: id,
+		}))
+		c.Next()
+	}
+}
@@ -8,6 +8,11 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// Error codes sent with SendErrorCode
+const (
+	ErrCodeEmailNotVerified = "email_not_verified"
+)
+
 func SendSuccess(c *gin.Context, status int, data interface{}, message ...string) {
 	response := gin.H{
 		"success": true,
@@ -27,6 +32,16 @@ func SendError(c *gin.Context, status int, message string) {
 	})
 }
 
+// SendErrorCode adds a machine readable code for errors clients must handle
+// differently from a plain failure
+func SendErrorCode(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   message,
+		"code":    code,
+	})
+}
+
 func FormatValidationError(err error) []string {
 	var validationErrors validator.ValidationErrors
 	errorMessages := make([]string, 0)
@@ -42,6 +57,12 @@ func FormatValidationError(err error) []string {
 				errorMessages = append(errorMessages, fmt.Sprintf("%s must be at least %s characters", e.Field(), e.Param()))
 			case "max":
 				errorMessages = append(errorMessages, fmt.Sprintf("%s must not exceed %s characters", e.Field(), e.Param()))
+			case "gte":
+				errorMessages = append(errorMessages, fmt.Sprintf("%s must be at least %s", e.Field(), e.Param()))
+			case "lte":
+				errorMessages = append(errorMessages, fmt.Sprintf("%s must be at most %s", e.Field(), e.Param()))
+			case "oneof":
+				errorMessages = append(errorMessages, fmt.Sprintf("%s must be one of: %s", e.Field(), e.Param()))
 			case "eqfield":
 				errorMessages = append(errorMessages, fmt.Sprintf("%s must be equal to %s", e.Field(), e.Param()))
 			case "password_validator":
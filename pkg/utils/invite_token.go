@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// inviteAudience keeps invite tokens from being accepted as any other token
+const inviteAudience = "invite"
+
+// InviteClaims are the claims of a signed invitation link. The jti is the id
+// of the stored invitation, so a token can be used once and revoked.
+type InviteClaims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// SignInviteToken signs an invitation with HS256. The claims must carry the
+// invitation id and expiry.
+func SignInviteToken(secret []byte, claims InviteClaims) (string, error) {
+	claims.Audience = jwt.ClaimStrings{inviteAudience}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseInviteToken checks the signature, audience and expiry of an invite token.
+func ParseInviteToken(secret []byte, tokenString string) (*InviteClaims, error) {
+	claims := &InviteClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || !claims.VerifyAudience(inviteAudience, true) || claims.ExpiresAt == nil {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
@@ -1,92 +1,151 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
-	secretKey        string
+	keys             *KeyManager
 	refreshSecretKey string
 	expiresIn        string
 	refreshExpiresIn string
 }
 
 type JWTClaims struct {
-	UserID string   `json:"user_id"`
-	Roles  []string `json:"roles"`
+	UserID   string   `json:"user_id"`
+	Roles    []string `json:"roles"`
+	FamilyID string   `json:"fid,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+	Act      *Actor   `json:"act,omitempty"`
+	OrgID    string   `json:"org,omitempty"` // active organization, switched on refresh
 	jwt.RegisteredClaims
 }
 
+// Actor is the act claim (RFC 8693) of an impersonation token: the token
+// acts for UserID, but the person behind the requests is Actor.Subject.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// Impersonated reports whether the token was issued to someone acting as the user.
+func (c *JWTClaims) Impersonated() bool {
+	return c.Act != nil && c.Act.Subject != ""
+}
+
+// Authentication methods (RFC 8176) recorded in the amr claim
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMROIDC     = "oidc"  // signed in through an external OpenID Connect provider
+	AMREmail    = "email" // signed in with a one-time link mailed to the account
+)
+
+// HasAMR reports whether the token was issued after the given method.
+func (c *JWTClaims) HasAMR(method string) bool {
+	for _, m := range c.AMR {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+
+	// RefreshTokenID is the jti of the refresh token, tracked by its family
+	RefreshTokenID string `json:"-"`
 }
 
-func NewAuthHandler(secretKey, refreshSecretKey, expiresIn, refreshExpiresIn string) *AuthHandler {
+func NewAuthHandler(keys *KeyManager, refreshSecretKey, expiresIn, refreshExpiresIn string) *AuthHandler {
 	return &AuthHandler{
-		secretKey:        secretKey,
+		keys:             keys,
 		refreshSecretKey: refreshSecretKey,
 		expiresIn:        expiresIn,
 		refreshExpiresIn: refreshExpiresIn,
 	}
 }
 
-func (s *AuthHandler) GenerateToken(userID string, roles []string) (string, error) {
+func (s *AuthHandler) AccessExpiry() time.Duration {
 	expDuration, _ := time.ParseDuration(s.expiresIn)
-	claims := JWTClaims{
-		UserID: userID,
-		Roles:  roles,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expDuration)),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+	return expDuration
 }
 
-func (s *AuthHandler) GenerateRefreshToken(userID string, roles []string) (string, error) {
+func (s *AuthHandler) RefreshExpiry() time.Duration {
 	expDuration, _ := time.ParseDuration(s.refreshExpiresIn)
-	claims := JWTClaims{
-		UserID: userID,
-		Roles:  roles,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expDuration)),
-		},
+	return expDuration
+}
+
+// GenerateToken signs an access token. A unique jti is set and, unless the
+// claims carry their own expiry, the configured access token lifetime is used.
+func (s *AuthHandler) GenerateToken(claims JWTClaims) (string, error) {
+	claims.ID = uuid.New().String()
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(s.AccessExpiry()))
 	}
 
+	key := s.keys.Current()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+func (s *AuthHandler) GenerateRefreshToken(claims JWTClaims) (string, string, error) {
+	claims.ID = uuid.New().String()
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(s.RefreshExpiry()))
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.refreshSecretKey))
+	signed, err := token.SignedString([]byte(s.refreshSecretKey))
+	if err != nil {
+		return "", "", err
+	}
+	return signed, claims.ID, nil
 }
 
-func (s *AuthHandler) GenerateTokenPair(userID string, roles []string) (*TokenPair, error) {
-	accessToken, err := s.GenerateToken(userID, roles)
+func (s *AuthHandler) GenerateTokenPair(claims JWTClaims) (*TokenPair, error) {
+	accessToken, err := s.GenerateToken(claims)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.GenerateRefreshToken(userID, roles)
+	refreshToken, refreshTokenID, err := s.GenerateRefreshToken(claims)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:    accessToken,
+		RefreshToken:   refreshToken,
+		RefreshTokenID: refreshTokenID,
 	}, nil
 }
 
 func (s *AuthHandler) ValidateToken(tokenString string) (*JWTClaims, error) {
 	claims := &JWTClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.secretKey), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}))
 
-	if err != nil || !token.Valid {
+	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
 
 	return claims, nil
 }
@@ -95,11 +154,14 @@ func (s *AuthHandler) ValidateRefreshToken(tokenString string) (*JWTClaims, erro
 	claims := &JWTClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(s.refreshSecretKey), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
-	if err != nil || !token.Valid {
+	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
 
 	return claims, nil
 }
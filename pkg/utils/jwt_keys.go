@@ -0,0 +1,413 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey is one asymmetric key of the key set, identified by its kid.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+}
+
+// JWK is the public part of a signing key in RFC 7517 format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyManager keeps the access token signing keys. Every PEM file in dir is an
+// active key named after its kid; the newest one signs, all of them verify.
+type KeyManager struct {
+	mu        sync.RWMutex
+	dir       string
+	algorithm string
+	retention time.Duration
+	keys      map[string]*SigningKey
+	current   *SigningKey
+}
+
+// NewKeyManager loads the keys from dir and creates a first key when the
+// directory is empty. retention is how long a replaced key keeps verifying,
+// which should be at least the access token lifetime.
+func NewKeyManager(dir, algorithm string, retention time.Duration) (*KeyManager, error) {
+	if algorithm != AlgRS256 && algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	m := &KeyManager{
+		dir:       dir,
+		algorithm: algorithm,
+		retention: retention,
+		keys:      make(map[string]*SigningKey),
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	if err := m.Load(); err != nil {
+		return nil, err
+	}
+
+	if current := m.Current(); current == nil || current.Method.Alg() != algorithm {
+		if _, err := m.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Load reads every key from disk, so keys rotated by another instance sharing
+// the directory are picked up as well.
+func (m *KeyManager) Load() error {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*SigningKey)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+
+		key, err := readSigningKey(filepath.Join(m.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to load signing key %s: %w", entry.Name(), err)
+		}
+		keys[key.ID] = key
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys = keys
+	m.current = newestKey(keys)
+	m.prune()
+	return nil
+}
+
+// Rotate generates a new signing key, stores it on disk and makes it current.
+// Keys replaced longer than the retention period ago are removed.
+func (m *KeyManager) Rotate() (*SigningKey, error) {
+	key, err := generateSigningKey(m.algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(m.dir, key.ID+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys[key.ID] = key
+	m.current = key
+	m.prune()
+
+	log.Printf("Rotated JWT signing key, new kid %s", key.ID)
+	return key, nil
+}
+
+// StartRotation rotates the signing key every interval until ctx is done.
+func (m *KeyManager) StartRotation(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Load(); err != nil {
+				log.Printf("Failed to reload JWT signing keys: %v", err)
+				continue
+			}
+
+			current := m.Current()
+			if current != nil && time.Since(current.CreatedAt) < interval {
+				continue
+			}
+
+			if _, err := m.Rotate(); err != nil {
+				log.Printf("Failed to rotate JWT signing key: %v", err)
+			}
+		}
+	}
+}
+
+// Current returns the key new tokens are signed with.
+func (m *KeyManager) Current() *SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current
+}
+
+// Key returns the active key with the given kid.
+func (m *KeyManager) Key(kid string) (*SigningKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.keys[kid]
+	return key, ok
+}
+
+// JWKS returns the public keys of every active key.
+func (m *KeyManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(m.keys))}
+	for _, key := range sortedKeys(m.keys) {
+		jwk := JWK{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// PublicKey decodes the key for signature checks. RSA, EC and Ed25519 keys
+// are supported, which covers the keys published by common OIDC providers.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// prune drops keys whose successor has been signing for longer than the
+// retention period, so no unexpired token can still reference them.
+func (m *KeyManager) prune() {
+	keys := sortedKeys(m.keys)
+	now := time.Now()
+
+	for i := 0; i < len(keys)-1; i++ {
+		if keys[i+1].CreatedAt.Add(m.retention).After(now) {
+			continue
+		}
+
+		delete(m.keys, keys[i].ID)
+		if err := os.Remove(filepath.Join(m.dir, keys[i].ID+".pem")); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove retired JWT signing key %s: %v", keys[i].ID, err)
+		}
+	}
+}
+
+func generateSigningKey(algorithm string) (*SigningKey, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	now := time.Now()
+	return newSigningKey(
+		fmt.Sprintf("%s-%s", now.UTC().Format("20060102T150405"), uuid.New().String()[:8]),
+		private,
+		now,
+	)
+}
+
+func readSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return newSigningKey(kid, private, info.ModTime())
+}
+
+func newSigningKey(kid string, private crypto.Signer, createdAt time.Time) (*SigningKey, error) {
+	key := &SigningKey{
+		ID:        kid,
+		Private:   private,
+		Public:    private.Public(),
+		CreatedAt: createdAt,
+	}
+
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	return key, nil
+}
+
+func newestKey(keys map[string]*SigningKey) *SigningKey {
+	sorted := sortedKeys(keys)
+	if len(sorted) == 0 {
+		return nil
+	}
+	return sorted[len(sorted)-1]
+}
+
+func sortedKeys(keys map[string]*SigningKey) []*SigningKey {
+	sorted := make([]*SigningKey, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	return sorted
+}
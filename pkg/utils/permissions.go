@@ -0,0 +1,61 @@
+package utils
+
+type Permission string
+
+const (
+	PermProductCreate   Permission = "product:create"
+	PermProductList     Permission = "product:list"
+	PermProductUpdate   Permission = "product:update"
+	PermProductDelete   Permission = "product:delete"
+	PermFileUpload      Permission = "file:upload"
+	PermFileList        Permission = "file:list"
+	PermFileDelete      Permission = "file:delete"
+	PermUserList        Permission = "user:list"
+	PermUserUpdate      Permission = "user:update"
+	PermUserDelete      Permission = "user:delete"
+	PermUserRoles       Permission = "user:roles"
+	PermUserSessions    Permission = "user:sessions"
+	PermUserUnlock      Permission = "user:unlock"
+	PermUserImpersonate Permission = "user:impersonate"
+	PermUserInvite      Permission = "user:invite"
+	PermTwoFactorPolicy Permission = "settings:2fa"
+	PermRoleManage      Permission = "role:manage"
+	PermAPIKeyManage    Permission = "apikey:manage"
+	PermAuditRead       Permission = "audit:read"
+	PermOrderManage     Permission = "order:manage"
+	PermCategoryManage  Permission = "category:manage"
+)
+
+// AllPermissions lists every permission checked by a route
+var AllPermissions = []Permission{
+	PermProductCreate,
+	PermProductList,
+	PermProductUpdate,
+	PermProductDelete,
+	PermFileUpload,
+	PermFileList,
+	PermFileDelete,
+	PermUserList,
+	PermUserUpdate,
+	PermUserDelete,
+	PermUserRoles,
+	PermUserSessions,
+	PermUserUnlock,
+	PermUserImpersonate,
+	PermUserInvite,
+	PermTwoFactorPolicy,
+	PermRoleManage,
+	PermAPIKeyManage,
+	PermAuditRead,
+	PermOrderManage,
+	PermCategoryManage,
+}
+
+func IsKnownPermission(p string) bool {
+	for _, known := range AllPermissions {
+		if string(known) == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateRandomToken returns n random bytes encoded for use in URLs.
+func GenerateRandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken hashes a random token for storage, so a leaked store does not
+// leak usable tokens. Random tokens need no salt or slow hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to spot
+const APIKeyPrefix = "egp"
+
+// GenerateAPIKey returns a new API key and its visible prefix. The key looks
+// like egp_<8 hex>_<64 hex>; only the part before the secret is shown again.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	buf := make([]byte, 36)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	prefix = APIKeyPrefix + "_" + hex.EncodeToString(buf[:4])
+	return prefix + "_" + hex.EncodeToString(buf[4:]), prefix, nil
+}